@@ -20,6 +20,7 @@ const (
 	headerOperationState = "Nexus-Operation-State"
 	headerOperationID    = "Nexus-Operation-Id"
 	headerRequestID      = "Nexus-Request-Id"
+	headerRetryable      = "Nexus-Request-Retryable"
 )
 
 const contentTypeJSON = "application/json"
@@ -44,12 +45,85 @@ type Failure struct {
 	Metadata map[string]string `json:"metadata,omitempty"`
 	// Additional JSON serializable structured data.
 	Details json.RawMessage `json:"details,omitempty"`
+	// Failures that caused this failure. Typically populated from the chain of a Go error with
+	// [NewFailureFromError], and converted back to an error chain with [ErrorFromFailure].
+	//
+	// A single cause is encoded as a nested "cause" object, so that linear chains are encoded as a chain of "cause"
+	// fields. Failures with several causes, e.g. converted from errors joined with [errors.Join], encode them in a
+	// "causes" array instead.
+	Causes []Failure `json:"causes,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface, encoding a single cause in the "cause" field.
+func (f Failure) MarshalJSON() ([]byte, error) {
+	type failure Failure
+	v := struct {
+		failure
+		Cause *Failure `json:"cause,omitempty"`
+	}{failure: failure(f)}
+	if len(f.Causes) == 1 {
+		v.Cause = &f.Causes[0]
+		v.failure.Causes = nil
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, accepting both the "cause" and "causes" fields.
+func (f *Failure) UnmarshalJSON(b []byte) error {
+	type failure Failure
+	var v struct {
+		failure
+		Cause *Failure `json:"cause"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*f = Failure(v.failure)
+	if len(f.Causes) == 0 && v.Cause != nil {
+		f.Causes = []Failure{*v.Cause}
+	}
+	return nil
+}
+
+// NewFailure constructs a [Failure] with the given message and metadata, marshaling details to JSON using
+// [json.Marshal]. Details are omitted if nil.
+func NewFailure(message string, details any, metadata map[string]string) (Failure, error) {
+	failure := Failure{Message: message, Metadata: metadata}
+	if details != nil {
+		b, err := json.Marshal(details)
+		if err != nil {
+			return Failure{}, err
+		}
+		failure.Details = b
+	}
+	return failure, nil
+}
+
+var errFailureHasNoDetails = errors.New("failure has no details")
+
+// DecodeFailureDetails unmarshals the details of a [Failure] into a value of type T using [json.Unmarshal].
+// Fails if the failure has no details.
+func DecodeFailureDetails[T any](failure Failure) (T, error) {
+	var details T
+	if len(failure.Details) == 0 {
+		return details, errFailureHasNoDetails
+	}
+	err := json.Unmarshal(failure.Details, &details)
+	return details, err
 }
 
 // UnsuccessfulOperationError represents "failed" and "canceled" operation results.
 type UnsuccessfulOperationError struct {
 	State   OperationState
 	Failure Failure
+	// Error converted from Failure with the client's [ErrorRegistry], if set.
+	cause error
+}
+
+// Unwrap returns the error converted from the failure with [ClientOptions.ErrorRegistry], allowing [errors.Is] and
+// [errors.As] to match registered errors. Returns nil if the client has no error registry.
+func (e *UnsuccessfulOperationError) Unwrap() error {
+	return e.cause
 }
 
 // Error implements the error interface.
@@ -55,7 +55,7 @@ func TestFailure_JSONMarshalling(t *testing.T) {
 		t.Run(tc.message, func(t *testing.T) {
 			serializedDetails, err := json.MarshalIndent(tc.details, "", "\t")
 			require.NoError(t, err)
-			source, err := json.MarshalIndent(Failure{tc.message, tc.metadata, serializedDetails}, "", "\t")
+			source, err := json.MarshalIndent(Failure{Message: tc.message, Metadata: tc.metadata, Details: serializedDetails}, "", "\t")
 			require.NoError(t, err)
 			require.Equal(t, tc.serialized, string(source))
 
@@ -74,3 +74,29 @@ func TestFailure_JSONMarshalling(t *testing.T) {
 		})
 	}
 }
+
+func TestNewFailure(t *testing.T) {
+	type details struct {
+		Reason string `json:"reason"`
+	}
+	failure, err := NewFailure("failed", details{Reason: "because"}, map[string]string{"type": "details"})
+	require.NoError(t, err)
+	require.Equal(t, Failure{
+		Message:  "failed",
+		Metadata: map[string]string{"type": "details"},
+		Details:  json.RawMessage(`{"reason":"because"}`),
+	}, failure)
+
+	decoded, err := DecodeFailureDetails[details](failure)
+	require.NoError(t, err)
+	require.Equal(t, details{Reason: "because"}, decoded)
+
+	failure, err = NewFailure("no details", nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, Failure{Message: "no details"}, failure)
+	_, err = DecodeFailureDetails[details](failure)
+	require.ErrorIs(t, err, errFailureHasNoDetails)
+
+	_, err = NewFailure("invalid", make(chan int), nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,193 @@
+package nexus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrForbidden is returned, or wrapped, by an [Authorizer] to deny a request. Denied requests are rejected with a 403
+// status.
+var ErrForbidden = errors.New("forbidden")
+
+// An AuthorizationAction is an action a caller performs on an operation.
+type AuthorizationAction string
+
+const (
+	// Describe the service. Requests for this action have an empty Operation.
+	AuthorizationActionDescribeService = AuthorizationAction("describe")
+	// Start an operation. Requests for this action have an empty OperationID.
+	AuthorizationActionStartOperation = AuthorizationAction("start")
+	// Get the result of an operation.
+	AuthorizationActionGetOperationResult = AuthorizationAction("get-result")
+	// Get information about an operation.
+	AuthorizationActionGetOperationInfo = AuthorizationAction("get-info")
+	// Cancel an operation.
+	AuthorizationActionCancelOperation = AuthorizationAction("cancel")
+)
+
+// AuthorizationRequest is input for [Authorizer.Authorize].
+type AuthorizationRequest struct {
+	// Principal the request was made by, as returned by [HandlerOptions.Principal]. Empty for anonymous requests.
+	Principal string
+	// Tenant the request was made for. Empty unless [HandlerOptions.Tenancy] is set.
+	Tenant string
+	// Action the principal performs.
+	Action AuthorizationAction
+	// Operation name.
+	Operation string
+	// Operation ID. Empty when starting an operation. The internal ID if [HandlerOptions.OperationIDCodec] is set.
+	OperationID string
+	// The original HTTP request.
+	HTTPRequest *http.Request
+}
+
+// An Authorizer decides whether principals may perform actions on operations. Set [HandlerOptions.Authorizer] to
+// authorize every request before it reaches the [Handler].
+//
+// Authorize returns nil to allow a request. Requests are rejected with a 403 status if the returned error wraps
+// [ErrForbidden], responded to as-is if it is a [HandlerError], and failed with a 500 status otherwise.
+type Authorizer interface {
+	Authorize(ctx context.Context, request *AuthorizationRequest) error
+}
+
+// AuthorizationEffect is the effect of a matching [AuthorizationRule].
+type AuthorizationEffect int
+
+const (
+	// Allow matching requests.
+	AuthorizationEffectAllow = AuthorizationEffect(iota)
+	// Deny matching requests, taking precedence over rules that allow them.
+	AuthorizationEffectDeny
+)
+
+// An AuthorizationRule allows or denies principals to perform actions on operations.
+//
+// Principal and operation patterns are exact names, * to match all, or prefixes followed by *, e.g.
+// spiffe://example.org/ns/prod/*.
+type AuthorizationRule struct {
+	// Whether to allow or deny matching requests. Defaults to allow.
+	Effect AuthorizationEffect
+	// Principals the rule applies to. Applies to all principals, including anonymous ones, if empty.
+	Principals []string
+	// Actions the rule applies to. Applies to all actions if empty.
+	Actions []AuthorizationAction
+	// Operations the rule applies to. Applies to all operations if empty.
+	Operations []string
+}
+
+func matchesPattern(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(value, prefix) {
+				return true
+			}
+		} else if pattern == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (r AuthorizationRule) matches(request *AuthorizationRequest) bool {
+	if len(r.Principals) > 0 && !matchesPattern(r.Principals, request.Principal) {
+		return false
+	}
+	if len(r.Actions) > 0 {
+		found := false
+		for _, action := range r.Actions {
+			if action == request.Action {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return len(r.Operations) == 0 || matchesPattern(r.Operations, request.Operation)
+}
+
+// RuleAuthorizer is an [Authorizer] that evaluates a list of declarative rules. A request is allowed if a rule allows
+// it and no rule denies it. Requests no rule applies to are denied.
+//
+//	authorizer := &nexus.RuleAuthorizer{Rules: []nexus.AuthorizationRule{
+//		{Principals: []string{"spiffe://example.org/billing"}, Operations: []string{"charge"}},
+//		{Principals: []string{"*"}, Actions: []nexus.AuthorizationAction{nexus.AuthorizationActionGetOperationInfo}},
+//		{Effect: nexus.AuthorizationEffectDeny, Principals: []string{"*"}, Actions: []nexus.AuthorizationAction{nexus.AuthorizationActionCancelOperation}, Operations: []string{"refund"}},
+//	}}
+type RuleAuthorizer struct {
+	// Rules to evaluate.
+	Rules []AuthorizationRule
+}
+
+// Authorize implements the Authorizer interface.
+func (a *RuleAuthorizer) Authorize(ctx context.Context, request *AuthorizationRequest) error {
+	allowed := false
+	for _, rule := range a.Rules {
+		if !rule.matches(request) {
+			continue
+		}
+		if rule.Effect == AuthorizationEffectDeny {
+			allowed = false
+			break
+		}
+		allowed = true
+	}
+	if allowed {
+		return nil
+	}
+	principal := request.Principal
+	if principal == "" {
+		principal = "anonymous"
+	}
+	if request.Operation == "" {
+		return fmt.Errorf("%w: %s may not %s", ErrForbidden, principal, request.Action)
+	}
+	return fmt.Errorf("%w: %s may not %s operation %q", ErrForbidden, principal, request.Action, request.Operation)
+}
+
+// principalFromPeerIdentity returns the SPIFFE ID of a mutual TLS peer, falling back to its first DNS name prefixed with
+// dns: and subject common name prefixed with cn:, so that fallbacks cannot be mistaken for SPIFFE IDs or each other.
+func principalFromPeerIdentity(request *http.Request) string {
+	identity, ok := PeerIdentityFromContext(request.Context())
+	if !ok {
+		return ""
+	}
+	if identity.SPIFFEID != "" {
+		return identity.SPIFFEID
+	}
+	if len(identity.DNSNames) > 0 {
+		return "dns:" + identity.DNSNames[0]
+	}
+	if identity.Subject.CommonName != "" {
+		return "cn:" + identity.Subject.CommonName
+	}
+	return ""
+}
+
+// authorize authorizes a request with the handler's Authorizer, if set.
+func (h *httpHandler) authorize(request *http.Request, action AuthorizationAction, operation, operationID string) error {
+	if h.options.Authorizer == nil {
+		return nil
+	}
+	tenant, _ := TenantFromContext(request.Context())
+	err := h.options.Authorizer.Authorize(request.Context(), &AuthorizationRequest{
+		Principal:   h.options.Principal(request),
+		Tenant:      tenant,
+		Action:      action,
+		Operation:   operation,
+		OperationID: operationID,
+		HTTPRequest: request,
+	})
+	if err != nil && errors.Is(err, ErrForbidden) {
+		return &HandlerError{
+			StatusCode:    http.StatusForbidden,
+			Failure:       &Failure{Message: err.Error()},
+			RetryBehavior: RetryBehaviorNonRetryable,
+		}
+	}
+	return err
+}
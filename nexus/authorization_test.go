@@ -0,0 +1,213 @@
+package nexus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleAuthorizer(t *testing.T) {
+	authorizer := &RuleAuthorizer{Rules: []AuthorizationRule{
+		{Principals: []string{"billing"}, Operations: []string{"charge"}},
+		{Principals: []string{"spiffe://example.org/ns/prod/*"}, Operations: []string{"refund"}},
+		{Principals: []string{"*"}, Actions: []AuthorizationAction{AuthorizationActionGetOperationInfo}},
+		{
+			Effect:     AuthorizationEffectDeny,
+			Principals: []string{"*"},
+			Actions:    []AuthorizationAction{AuthorizationActionCancelOperation},
+			Operations: []string{"refund"},
+		},
+	}}
+	cases := []struct {
+		principal string
+		action    AuthorizationAction
+		operation string
+		allowed   bool
+	}{
+		{principal: "billing", action: AuthorizationActionStartOperation, operation: "charge", allowed: true},
+		{principal: "billing", action: AuthorizationActionCancelOperation, operation: "charge", allowed: true},
+		{principal: "billing", action: AuthorizationActionStartOperation, operation: "refund"},
+		{principal: "other", action: AuthorizationActionStartOperation, operation: "charge"},
+		{principal: "spiffe://example.org/ns/prod/sa/refunder", action: AuthorizationActionStartOperation, operation: "refund", allowed: true},
+		{principal: "spiffe://example.org/ns/prod/sa/refunder", action: AuthorizationActionCancelOperation, operation: "refund"},
+		{principal: "spiffe://example.org/ns/dev/sa/refunder", action: AuthorizationActionStartOperation, operation: "refund"},
+		{principal: "", action: AuthorizationActionGetOperationInfo, operation: "charge", allowed: true},
+		{principal: "", action: AuthorizationActionStartOperation, operation: "charge"},
+		{principal: "billing", action: AuthorizationActionDescribeService},
+	}
+	for _, c := range cases {
+		err := authorizer.Authorize(context.Background(), &AuthorizationRequest{
+			Principal: c.principal,
+			Action:    c.action,
+			Operation: c.operation,
+		})
+		if c.allowed {
+			require.NoError(t, err, "%+v", c)
+		} else {
+			require.ErrorIs(t, err, ErrForbidden, "%+v", c)
+		}
+	}
+}
+
+func TestRuleAuthorizer_EmptyPrincipals(t *testing.T) {
+	authorizer := &RuleAuthorizer{Rules: []AuthorizationRule{
+		{Principals: []string{"*"}},
+		{Effect: AuthorizationEffectDeny, Actions: []AuthorizationAction{AuthorizationActionCancelOperation}, Operations: []string{"Z"}},
+	}}
+	for _, principal := range []string{"", "billing", "spiffe://example.org/admin"} {
+		request := &AuthorizationRequest{Principal: principal, Action: AuthorizationActionCancelOperation, Operation: "Z"}
+		require.ErrorIs(t, authorizer.Authorize(context.Background(), request), ErrForbidden, principal)
+		request.Operation = "Y"
+		require.NoError(t, authorizer.Authorize(context.Background(), request), principal)
+		request.Operation, request.Action = "Z", AuthorizationActionGetOperationInfo
+		require.NoError(t, authorizer.Authorize(context.Background(), request), principal)
+	}
+}
+
+type authorizedHandler struct {
+	UnimplementedHandler
+}
+
+func (h *authorizedHandler) StartOperation(ctx context.Context, request *StartOperationRequest) (OperationResponse, error) {
+	return &OperationResponseAsync{OperationID: "id"}, nil
+}
+
+func (h *authorizedHandler) GetOperationResult(ctx context.Context, request *GetOperationResultRequest) (*OperationResponseSync, error) {
+	return &OperationResponseSync{Body: bytes.NewReader([]byte("result"))}, nil
+}
+
+func (h *authorizedHandler) GetOperationInfo(ctx context.Context, request *GetOperationInfoRequest) (*OperationInfo, error) {
+	return &OperationInfo{ID: request.OperationID, State: OperationStateRunning}, nil
+}
+
+func (h *authorizedHandler) CancelOperation(ctx context.Context, request *CancelOperationRequest) error {
+	return nil
+}
+
+type recordingAuthorizer struct {
+	requests []AuthorizationRequest
+	err      error
+}
+
+func (a *recordingAuthorizer) Authorize(ctx context.Context, request *AuthorizationRequest) error {
+	a.requests = append(a.requests, *request)
+	return a.err
+}
+
+func principalFromHeader(request *http.Request) string {
+	return request.Header.Get("X-Principal")
+}
+
+func TestAuthorizer_InvokedForEveryRoute(t *testing.T) {
+	authorizer := &recordingAuthorizer{}
+	ctx, client, teardown := setupCustom(t, HandlerOptions{
+		Handler:    &authorizedHandler{},
+		Authorizer: authorizer,
+		Principal:  principalFromHeader,
+	}, ClientOptions{})
+	defer teardown()
+
+	header := http.Header{"X-Principal": []string{"alice"}}
+	result, err := client.StartOperation(ctx, StartOperationOptions{Operation: "foo", Header: header})
+	require.NoError(t, err)
+	handle := result.Pending
+	require.NotNil(t, handle)
+	response, err := handle.GetResult(ctx, GetOperationResultOptions{Header: header})
+	require.NoError(t, err)
+	response.Body.Close()
+	_, err = handle.GetInfo(ctx, GetOperationInfoOptions{Header: header})
+	require.NoError(t, err)
+	require.NoError(t, handle.Cancel(ctx, CancelOperationOptions{Header: header}))
+
+	require.Len(t, authorizer.requests, 4)
+	expected := []struct {
+		action      AuthorizationAction
+		operationID string
+	}{
+		{AuthorizationActionStartOperation, ""},
+		{AuthorizationActionGetOperationResult, "id"},
+		{AuthorizationActionGetOperationInfo, "id"},
+		{AuthorizationActionCancelOperation, "id"},
+	}
+	for i, e := range expected {
+		require.Equal(t, "alice", authorizer.requests[i].Principal)
+		require.Equal(t, e.action, authorizer.requests[i].Action)
+		require.Equal(t, "foo", authorizer.requests[i].Operation)
+		require.Equal(t, e.operationID, authorizer.requests[i].OperationID)
+		require.NotNil(t, authorizer.requests[i].HTTPRequest)
+	}
+}
+
+func TestAuthorizer_Rules(t *testing.T) {
+	ctx, client, teardown := setupCustom(t, HandlerOptions{
+		Handler: &authorizedHandler{},
+		Authorizer: &RuleAuthorizer{Rules: []AuthorizationRule{
+			{Principals: []string{"alice"}, Operations: []string{"foo"}},
+			{Effect: AuthorizationEffectDeny, Principals: []string{"*"}, Actions: []AuthorizationAction{AuthorizationActionCancelOperation}},
+		}},
+		Principal: principalFromHeader,
+	}, ClientOptions{})
+	defer teardown()
+
+	alice := http.Header{"X-Principal": []string{"alice"}}
+	result, err := client.StartOperation(ctx, StartOperationOptions{Operation: "foo", Header: alice})
+	require.NoError(t, err)
+
+	var unexpectedResponseError *UnexpectedResponseError
+	err = result.Pending.Cancel(ctx, CancelOperationOptions{Header: alice})
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusForbidden, unexpectedResponseError.Response.StatusCode)
+	require.Contains(t, unexpectedResponseError.Failure.Message, `alice may not cancel operation "foo"`)
+	require.False(t, unexpectedResponseError.Retryable())
+
+	_, err = client.StartOperation(ctx, StartOperationOptions{Operation: "foo", Header: http.Header{"X-Principal": []string{"bob"}}})
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusForbidden, unexpectedResponseError.Response.StatusCode)
+
+	_, err = client.StartOperation(ctx, StartOperationOptions{Operation: "bar", Header: alice})
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusForbidden, unexpectedResponseError.Response.StatusCode)
+}
+
+func TestAuthorizer_Errors(t *testing.T) {
+	authorizer := &recordingAuthorizer{}
+	ctx, client, teardown := setupCustom(t, HandlerOptions{
+		Handler:    &authorizedHandler{},
+		Authorizer: authorizer,
+	}, ClientOptions{})
+	defer teardown()
+
+	var unexpectedResponseError *UnexpectedResponseError
+	authorizer.err = &HandlerError{StatusCode: http.StatusUnauthorized, Failure: &Failure{Message: "who are you"}}
+	_, err := client.StartOperation(ctx, StartOperationOptions{Operation: "foo"})
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusUnauthorized, unexpectedResponseError.Response.StatusCode)
+
+	authorizer.err = errors.New("policy store unavailable")
+	_, err = client.StartOperation(ctx, StartOperationOptions{Operation: "foo"})
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusInternalServerError, unexpectedResponseError.Response.StatusCode)
+
+	// Anonymous callers have an empty principal.
+	require.Equal(t, "", authorizer.requests[0].Principal)
+}
+
+func TestPrincipalFromPeerIdentity(t *testing.T) {
+	request, err := http.NewRequest("GET", "http://localhost/", nil)
+	require.NoError(t, err)
+	require.Equal(t, "", principalFromPeerIdentity(request))
+
+	identity := &PeerIdentity{SPIFFEID: "spiffe://example.org/caller", DNSNames: []string{"caller.example.org"}}
+	require.Equal(t, "spiffe://example.org/caller", principalFromPeerIdentity(request.WithContext(ContextWithPeerIdentity(request.Context(), identity))))
+	identity.SPIFFEID = ""
+	require.Equal(t, "dns:caller.example.org", principalFromPeerIdentity(request.WithContext(ContextWithPeerIdentity(request.Context(), identity))))
+	identity.DNSNames = nil
+	identity.Subject.CommonName = "caller"
+	require.Equal(t, "cn:caller", principalFromPeerIdentity(request.WithContext(ContextWithPeerIdentity(request.Context(), identity))))
+	identity.Subject.CommonName = ""
+	require.Equal(t, "", principalFromPeerIdentity(request.WithContext(ContextWithPeerIdentity(request.Context(), identity))))
+}
@@ -0,0 +1,233 @@
+package nexus
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrCallbackURLDenied is returned when a callback URL or the address it resolves to is denied by a
+// [CallbackURLPolicy].
+var ErrCallbackURLDenied = errors.New("callback URL denied by policy")
+
+// Special purpose ranges that are not publicly routable and not covered by [netip.Addr.IsGlobalUnicast] and
+// [netip.Addr.IsPrivate].
+var nonPublicPrefixes = []netip.Prefix{
+	// "This network" (RFC 791).
+	netip.MustParsePrefix("0.0.0.0/8"),
+	// Shared address space (RFC 6598), commonly used for carrier-grade NAT.
+	netip.MustParsePrefix("100.64.0.0/10"),
+	// Benchmarking (RFC 2544).
+	netip.MustParsePrefix("198.18.0.0/15"),
+	// Reserved (RFC 1112), including the limited broadcast address.
+	netip.MustParsePrefix("240.0.0.0/4"),
+	// Local-use IPv4/IPv6 translation (RFC 8215).
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+// IPv6 ranges that embed IPv4 addresses.
+var (
+	// IPv4-compatible addresses (RFC 4291, deprecated).
+	ipv4CompatiblePrefix = netip.MustParsePrefix("::/96")
+	// Well-known NAT64 prefix (RFC 6052).
+	nat64Prefix = netip.MustParsePrefix("64:ff9b::/96")
+	// 6to4 (RFC 3056).
+	sixToFourPrefix = netip.MustParsePrefix("2002::/16")
+	// Teredo (RFC 4380).
+	teredoPrefix = netip.MustParsePrefix("2001::/32")
+)
+
+// A CallbackURLPolicy restricts the callback URLs operations may be completed to, protecting handlers that deliver
+// completions from server-side request forgery.
+//
+// Set [HandlerOptions.CallbackURLPolicy] to reject start requests with disallowed callback URLs with a 400 status, and
+// deliver completions with the client returned by [CallbackURLPolicy.HTTPClient] to enforce the policy again when
+// connecting, after DNS resolution, so that hostnames cannot be rebound to denied addresses.
+//
+// The zero value allows https URLs to any host with a public address.
+type CallbackURLPolicy struct {
+	// URL schemes to allow.
+	// Defaults to https.
+	AllowedSchemes []string
+	// Hosts to allow. Entries are hostnames or IP addresses matched exactly, or patterns of the form *.example.com
+	// matching any subdomain of example.com. All hosts are allowed if empty.
+	AllowedHosts []string
+	// Hosts to deny, in the same format as AllowedHosts. Takes precedence over AllowedHosts.
+	DeniedHosts []string
+	// Address ranges to allow. When set, only addresses within these ranges are allowed, including private ranges
+	// listed explicitly. All public addresses are allowed if empty.
+	AllowedCIDRs []netip.Prefix
+	// Address ranges to deny. Takes precedence over AllowedCIDRs.
+	DeniedCIDRs []netip.Prefix
+	// Allow loopback, private, link-local, and other non public addresses not listed in AllowedCIDRs.
+	// Denied by default.
+	AllowPrivateNetworks bool
+}
+
+// Validate checks a callback URL against the policy. Addresses of hostnames are checked when connecting with the client
+// returned by [CallbackURLPolicy.HTTPClient], IP address literals are checked immediately.
+// Returns an error wrapping [ErrCallbackURLDenied] if the URL is not allowed.
+func (p *CallbackURLPolicy) Validate(callbackURL string) error {
+	u, err := url.Parse(callbackURL)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCallbackURLDenied, err)
+	}
+	return p.validateURL(u)
+}
+
+func (p *CallbackURLPolicy) validateURL(u *url.URL) error {
+	schemes := p.AllowedSchemes
+	if len(schemes) == 0 {
+		schemes = []string{"https"}
+	}
+	if !containsFold(schemes, u.Scheme) {
+		return fmt.Errorf("%w: scheme %q is not allowed", ErrCallbackURLDenied, u.Scheme)
+	}
+	host := u.Hostname()
+	if host == "" {
+		return fmt.Errorf("%w: missing host", ErrCallbackURLDenied)
+	}
+	if matchesHost(p.DeniedHosts, host) {
+		return fmt.Errorf("%w: host %q is denied", ErrCallbackURLDenied, host)
+	}
+	if len(p.AllowedHosts) > 0 && !matchesHost(p.AllowedHosts, host) {
+		return fmt.Errorf("%w: host %q is not allowed", ErrCallbackURLDenied, host)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return p.checkAddr(addr)
+	}
+	return nil
+}
+
+// checkAddr checks an IP address against the policy. IPv6 addresses that embed an IPv4 address, e.g. NAT64
+// addresses, are checked as both.
+func (p *CallbackURLPolicy) checkAddr(addr netip.Addr) error {
+	addr = addr.Unmap().WithZone("")
+	if embedded, ok := embeddedIPv4(addr); ok {
+		if err := p.checkAddr(embedded); err != nil {
+			return err
+		}
+	}
+	for _, prefix := range p.DeniedCIDRs {
+		if prefix.Contains(addr) {
+			return fmt.Errorf("%w: address %s is denied", ErrCallbackURLDenied, addr)
+		}
+	}
+	if len(p.AllowedCIDRs) > 0 {
+		for _, prefix := range p.AllowedCIDRs {
+			if prefix.Contains(addr) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: address %s is not allowed", ErrCallbackURLDenied, addr)
+	}
+	if !p.AllowPrivateNetworks && !isPublicAddr(addr) {
+		return fmt.Errorf("%w: address %s is not public", ErrCallbackURLDenied, addr)
+	}
+	return nil
+}
+
+func isPublicAddr(addr netip.Addr) bool {
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// embeddedIPv4 returns the IPv4 address embedded in an IPv4-compatible, NAT64, 6to4, or Teredo (client) IPv6 address.
+func embeddedIPv4(addr netip.Addr) (netip.Addr, bool) {
+	if !addr.Is6() {
+		return netip.Addr{}, false
+	}
+	b := addr.As16()
+	switch {
+	case ipv4CompatiblePrefix.Contains(addr), nat64Prefix.Contains(addr):
+		return netip.AddrFrom4([4]byte(b[12:16])), true
+	case sixToFourPrefix.Contains(addr):
+		return netip.AddrFrom4([4]byte(b[2:6])), true
+	case teredoPrefix.Contains(addr):
+		// The client address is stored inverted.
+		return netip.AddrFrom4([4]byte{^b[12], ^b[13], ^b[14], ^b[15]}), true
+	}
+	return netip.Addr{}, false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesHost reports whether host matches any of the given exact or *.domain patterns.
+func matchesHost(patterns []string, host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(strings.ToLower(pattern), ".")
+		if domain, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// dialControl checks the address a connection is about to be established to, after DNS resolution.
+func (p *CallbackURLPolicy) dialControl(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCallbackURLDenied, err)
+	}
+	return p.checkAddr(addrPort.Addr())
+}
+
+type callbackPolicyTransport struct {
+	policy *CallbackURLPolicy
+	base   http.RoundTripper
+}
+
+func (t *callbackPolicyTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if err := t.policy.validateURL(request.URL); err != nil {
+		if request.Body != nil {
+			request.Body.Close()
+		}
+		return nil, err
+	}
+	return t.base.RoundTrip(request)
+}
+
+// HTTPClient returns an [http.Client] for delivering completions that enforces the policy on every request, including
+// redirects, and on every connection after DNS resolution. Proxies are not used since they would bypass the address
+// checks.
+//
+//	request, _ := nexus.NewCompletionHTTPRequest(ctx, callbackURL, completion)
+//	response, err := policy.HTTPClient().Do(request)
+func (p *CallbackURLPolicy) HTTPClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   p.dialControl,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Transport: &callbackPolicyTransport{policy: p, base: transport},
+	}
+}
@@ -0,0 +1,162 @@
+package nexus
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallbackURLPolicy_Validate(t *testing.T) {
+	cases := []struct {
+		name    string
+		policy  CallbackURLPolicy
+		url     string
+		allowed bool
+	}{
+		{name: "public https", url: "https://example.com/callback", allowed: true},
+		{name: "http denied by default", url: "http://example.com/callback"},
+		{name: "allowed scheme", policy: CallbackURLPolicy{AllowedSchemes: []string{"http"}}, url: "http://example.com/callback", allowed: true},
+		{name: "missing host", url: "https:///callback"},
+		{name: "unparsable", url: "https://[::1"},
+		{name: "public ip", url: "https://93.184.216.34/callback", allowed: true},
+		{name: "loopback", url: "https://127.0.0.1/callback"},
+		{name: "ipv6 loopback", url: "https://[::1]/callback"},
+		{name: "mapped loopback", url: "https://[::ffff:127.0.0.1]/callback"},
+		{name: "private", url: "https://10.1.2.3/callback"},
+		{name: "link local metadata", url: "https://169.254.169.254/latest/meta-data"},
+		{name: "unspecified", url: "https://0.0.0.0/callback"},
+		{name: "shared address space", url: "https://100.64.0.1/callback"},
+		{name: "this network", url: "https://0.1.2.3/callback"},
+		{name: "benchmarking", url: "https://198.18.0.1/callback"},
+		{name: "reserved", url: "https://240.0.0.1/callback"},
+		{name: "nat64 metadata", url: "https://[64:ff9b::a9fe:a9fe]/callback"},
+		{name: "nat64 public", url: "https://[64:ff9b::5db8:d822]/callback", allowed: true},
+		{name: "local-use nat64", url: "https://[64:ff9b:1::5db8:d822]/callback"},
+		{name: "6to4 private", url: "https://[2002:a01:203::1]/callback"},
+		{name: "ipv4-compatible loopback", url: "https://[::7f00:1]/callback"},
+		{name: "teredo private client", url: "https://[2001:0:5db8:d822::f5fe:fdfc]/callback"},
+		{
+			name:   "nat64 denied cidr",
+			policy: CallbackURLPolicy{DeniedCIDRs: []netip.Prefix{netip.MustParsePrefix("93.184.216.0/24")}},
+			url:    "https://[64:ff9b::5db8:d822]/callback",
+		},
+		{name: "private allowed", policy: CallbackURLPolicy{AllowPrivateNetworks: true}, url: "https://10.1.2.3/callback", allowed: true},
+		{
+			name:    "allowed cidr",
+			policy:  CallbackURLPolicy{AllowedCIDRs: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}},
+			url:     "https://10.1.2.3/callback",
+			allowed: true,
+		},
+		{
+			name:   "outside allowed cidr",
+			policy: CallbackURLPolicy{AllowedCIDRs: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}},
+			url:    "https://93.184.216.34/callback",
+		},
+		{
+			name: "denied cidr",
+			policy: CallbackURLPolicy{
+				AllowedCIDRs: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+				DeniedCIDRs:  []netip.Prefix{netip.MustParsePrefix("10.1.0.0/16")},
+			},
+			url: "https://10.1.2.3/callback",
+		},
+		{name: "allowed host", policy: CallbackURLPolicy{AllowedHosts: []string{"example.com"}}, url: "https://EXAMPLE.com/callback", allowed: true},
+		{name: "not allowed host", policy: CallbackURLPolicy{AllowedHosts: []string{"example.com"}}, url: "https://example.org/callback"},
+		{name: "allowed subdomain", policy: CallbackURLPolicy{AllowedHosts: []string{"*.example.com"}}, url: "https://a.b.example.com/callback", allowed: true},
+		{name: "wildcard excludes apex", policy: CallbackURLPolicy{AllowedHosts: []string{"*.example.com"}}, url: "https://example.com/callback"},
+		{
+			name:   "denied host",
+			policy: CallbackURLPolicy{AllowedHosts: []string{"*.example.com"}, DeniedHosts: []string{"internal.example.com"}},
+			url:    "https://internal.example.com/callback",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.policy.Validate(c.url)
+			if c.allowed {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, ErrCallbackURLDenied)
+			}
+		})
+	}
+}
+
+func TestCallbackURLPolicy_StartOperation(t *testing.T) {
+	ctx, client, teardown := setupCustom(t, HandlerOptions{
+		Handler:           &asyncHandler{},
+		CallbackURLPolicy: &CallbackURLPolicy{},
+	}, ClientOptions{})
+	defer teardown()
+
+	_, err := client.StartOperation(ctx, StartOperationOptions{Operation: "foo", CallbackURL: "https://169.254.169.254/"})
+	var unexpectedResponseError *UnexpectedResponseError
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusBadRequest, unexpectedResponseError.Response.StatusCode)
+
+	result, err := client.StartOperation(ctx, StartOperationOptions{Operation: "foo", CallbackURL: "https://example.com/callback"})
+	require.NoError(t, err)
+	require.NotNil(t, result.Pending)
+
+	result, err = client.StartOperation(ctx, StartOperationOptions{Operation: "foo"})
+	require.NoError(t, err)
+	require.NotNil(t, result.Pending)
+}
+
+func deliverCompletion(t *testing.T, client *http.Client, callbackURL string) (*http.Response, error) {
+	request, err := NewCompletionHTTPRequest(context.Background(), callbackURL, &OperationCompletionSuccessful{
+		Body: bytes.NewReader([]byte("success")),
+	})
+	require.NoError(t, err)
+	response, err := client.Do(request)
+	if err == nil {
+		response.Body.Close()
+	}
+	return response, err
+}
+
+func TestCallbackURLPolicy_HTTPClient(t *testing.T) {
+	server := httptest.NewServer(NewCompletionHTTPHandler(CompletionHandlerOptions{Handler: &countingCompletionHandler{}}))
+	defer server.Close()
+
+	// The server listens on a loopback address.
+	policy := &CallbackURLPolicy{AllowedSchemes: []string{"http"}}
+	_, err := deliverCompletion(t, policy.HTTPClient(), server.URL+"/callback")
+	require.ErrorIs(t, err, ErrCallbackURLDenied)
+
+	// Hostnames pass validation but are checked after resolution when connecting.
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	localhostURL := "http://localhost:" + port + "/callback"
+	require.NoError(t, policy.Validate(localhostURL))
+	_, err = deliverCompletion(t, policy.HTTPClient(), localhostURL)
+	require.ErrorIs(t, err, ErrCallbackURLDenied)
+
+	allowed := &CallbackURLPolicy{
+		AllowedSchemes: []string{"http"},
+		AllowedCIDRs:   []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")},
+	}
+	response, err := deliverCompletion(t, allowed.HTTPClient(), server.URL+"/callback")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+}
+
+func TestCallbackURLPolicy_HTTPClientRedirect(t *testing.T) {
+	redirector := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		http.Redirect(writer, request, "http://169.254.169.254/", http.StatusFound)
+	}))
+	defer redirector.Close()
+
+	policy := &CallbackURLPolicy{
+		AllowedSchemes: []string{"http"},
+		AllowedCIDRs:   []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")},
+	}
+	_, err := deliverCompletion(t, policy.HTTPClient(), redirector.URL+"/callback")
+	require.ErrorIs(t, err, ErrCallbackURLDenied)
+}
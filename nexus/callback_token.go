@@ -0,0 +1,153 @@
+package nexus
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Query param for passing a callback token in callback URLs.
+const queryCallbackToken = "nexus-callback-token"
+
+// A CallbackToken identifies the operation a completion is delivered for. It is embedded in callback URLs with
+// [CallbackTokenSigner.CallbackURL] and verified by completion handlers with [CompletionHandlerOptions.CallbackTokens]
+// set.
+type CallbackToken struct {
+	// Name of the operation. Optional.
+	Operation string `json:"op,omitempty"`
+	// ID of the operation, if known when the callback URL is generated. Optional.
+	OperationID string `json:"id,omitempty"`
+	// Caller chosen key for correlating the completion with the caller's state, e.g. a workflow or request ID.
+	// Optional.
+	CorrelationKey string `json:"key,omitempty"`
+	// Time after which the token is rejected. Tokens never expire if zero.
+	ExpiresAt time.Time `json:"-"`
+}
+
+type callbackTokenPayload struct {
+	CallbackToken
+	ExpiresAt int64 `json:"exp,omitempty"`
+}
+
+// CallbackTokenSignerOptions are options for [NewCallbackTokenSigner].
+type CallbackTokenSignerOptions struct {
+	// Secret key for signing tokens with HMAC-SHA256. Required.
+	Key []byte
+	// Additional keys accepted when verifying tokens, e.g. keys that are being rotated out. Optional.
+	VerificationKeys [][]byte
+}
+
+// A CallbackTokenSigner signs and verifies [CallbackToken]s.
+type CallbackTokenSigner struct {
+	key              []byte
+	verificationKeys [][]byte
+	now              func() time.Time
+}
+
+var errEmptyCallbackTokenKey = errors.New("empty callback token key")
+
+var errInvalidCallbackToken = errors.New("invalid callback token")
+
+var errExpiredCallbackToken = errors.New("expired callback token")
+
+// NewCallbackTokenSigner constructs a [CallbackTokenSigner] from the given options.
+func NewCallbackTokenSigner(options CallbackTokenSignerOptions) (*CallbackTokenSigner, error) {
+	if len(options.Key) == 0 {
+		return nil, errEmptyCallbackTokenKey
+	}
+	return &CallbackTokenSigner{
+		key:              options.Key,
+		verificationKeys: append([][]byte{options.Key}, options.VerificationKeys...),
+		now:              time.Now,
+	}, nil
+}
+
+func callbackTokenMAC(key []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// Sign encodes and signs a token.
+func (s *CallbackTokenSigner) Sign(token CallbackToken) (string, error) {
+	payload := callbackTokenPayload{CallbackToken: token}
+	if !token.ExpiresAt.IsZero() {
+		payload.ExpiresAt = token.ExpiresAt.Unix()
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(b)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(callbackTokenMAC(s.key, encoded)), nil
+}
+
+// Verify verifies the signature and expiry of an encoded token and decodes it.
+func (s *CallbackTokenSigner) Verify(encoded string) (*CallbackToken, error) {
+	payloadPart, signaturePart, ok := strings.Cut(encoded, ".")
+	if !ok {
+		return nil, errInvalidCallbackToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(signaturePart)
+	if err != nil {
+		return nil, errInvalidCallbackToken
+	}
+	verified := false
+	for _, key := range s.verificationKeys {
+		if hmac.Equal(signature, callbackTokenMAC(key, payloadPart)) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errInvalidCallbackToken
+	}
+	b, err := base64.RawURLEncoding.DecodeString(payloadPart)
+	if err != nil {
+		return nil, errInvalidCallbackToken
+	}
+	var payload callbackTokenPayload
+	if err := json.Unmarshal(b, &payload); err != nil {
+		return nil, errInvalidCallbackToken
+	}
+	token := payload.CallbackToken
+	if payload.ExpiresAt != 0 {
+		token.ExpiresAt = time.Unix(payload.ExpiresAt, 0)
+		if !s.now().Before(token.ExpiresAt) {
+			return nil, errExpiredCallbackToken
+		}
+	}
+	return &token, nil
+}
+
+// CallbackURL returns callbackURL with a signed token embedded in its query string, to be provided as the
+// CallbackURL of start requests.
+func (s *CallbackTokenSigner) CallbackURL(callbackURL string, token CallbackToken) (string, error) {
+	u, err := url.Parse(callbackURL)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := s.Sign(token)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set(queryCallbackToken, encoded)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// verifyRequest verifies the callback token embedded in the URL of a completion request.
+func (s *CallbackTokenSigner) verifyRequest(request *http.Request) (*CallbackToken, error) {
+	token, err := s.Verify(request.URL.Query().Get(queryCallbackToken))
+	if err != nil {
+		return nil, newUnauthorizedCompletionError(err.Error())
+	}
+	return token, nil
+}
@@ -0,0 +1,145 @@
+package nexus
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallbackToken_RoundTrip(t *testing.T) {
+	signer, err := NewCallbackTokenSigner(CallbackTokenSignerOptions{Key: []byte("secret")})
+	require.NoError(t, err)
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	encoded, err := signer.Sign(CallbackToken{Operation: "op", OperationID: "id", CorrelationKey: "key", ExpiresAt: expiresAt})
+	require.NoError(t, err)
+	token, err := signer.Verify(encoded)
+	require.NoError(t, err)
+	require.Equal(t, "op", token.Operation)
+	require.Equal(t, "id", token.OperationID)
+	require.Equal(t, "key", token.CorrelationKey)
+	require.True(t, expiresAt.Equal(token.ExpiresAt))
+}
+
+func TestCallbackToken_EmptyKey(t *testing.T) {
+	_, err := NewCallbackTokenSigner(CallbackTokenSignerOptions{})
+	require.ErrorIs(t, err, errEmptyCallbackTokenKey)
+}
+
+func TestCallbackToken_Tampered(t *testing.T) {
+	signer, err := NewCallbackTokenSigner(CallbackTokenSignerOptions{Key: []byte("secret")})
+	require.NoError(t, err)
+	encoded, err := signer.Sign(CallbackToken{OperationID: "id"})
+	require.NoError(t, err)
+	other, err := signer.Sign(CallbackToken{OperationID: "other"})
+	require.NoError(t, err)
+
+	payload, _, _ := strings.Cut(other, ".")
+	_, signature, _ := strings.Cut(encoded, ".")
+	_, err = signer.Verify(payload + "." + signature)
+	require.ErrorIs(t, err, errInvalidCallbackToken)
+
+	for _, malformed := range []string{"", "no-signature", "a.b.c", encoded + "x"} {
+		_, err = signer.Verify(malformed)
+		require.ErrorIs(t, err, errInvalidCallbackToken, malformed)
+	}
+
+	forger, err := NewCallbackTokenSigner(CallbackTokenSignerOptions{Key: []byte("guess")})
+	require.NoError(t, err)
+	forged, err := forger.Sign(CallbackToken{OperationID: "id"})
+	require.NoError(t, err)
+	_, err = signer.Verify(forged)
+	require.ErrorIs(t, err, errInvalidCallbackToken)
+}
+
+func TestCallbackToken_Expired(t *testing.T) {
+	signer, err := NewCallbackTokenSigner(CallbackTokenSignerOptions{Key: []byte("secret")})
+	require.NoError(t, err)
+	now := time.Now()
+	encoded, err := signer.Sign(CallbackToken{OperationID: "id", ExpiresAt: now.Add(time.Minute)})
+	require.NoError(t, err)
+	_, err = signer.Verify(encoded)
+	require.NoError(t, err)
+
+	signer.now = func() time.Time { return now.Add(2 * time.Minute) }
+	_, err = signer.Verify(encoded)
+	require.ErrorIs(t, err, errExpiredCallbackToken)
+}
+
+func TestCallbackToken_KeyRotation(t *testing.T) {
+	old, err := NewCallbackTokenSigner(CallbackTokenSignerOptions{Key: []byte("old")})
+	require.NoError(t, err)
+	encoded, err := old.Sign(CallbackToken{OperationID: "id"})
+	require.NoError(t, err)
+
+	rotated, err := NewCallbackTokenSigner(CallbackTokenSignerOptions{
+		Key:              []byte("new"),
+		VerificationKeys: [][]byte{[]byte("old")},
+	})
+	require.NoError(t, err)
+	token, err := rotated.Verify(encoded)
+	require.NoError(t, err)
+	require.Equal(t, "id", token.OperationID)
+
+	retired, err := NewCallbackTokenSigner(CallbackTokenSignerOptions{Key: []byte("new")})
+	require.NoError(t, err)
+	_, err = retired.Verify(encoded)
+	require.ErrorIs(t, err, errInvalidCallbackToken)
+}
+
+type tokenRecordingCompletionHandler struct {
+	token *CallbackToken
+}
+
+func (h *tokenRecordingCompletionHandler) CompleteOperation(ctx context.Context, completion *CompletionRequest) error {
+	h.token = completion.CallbackToken
+	return nil
+}
+
+func TestCallbackToken_CompletionHandler(t *testing.T) {
+	signer, err := NewCallbackTokenSigner(CallbackTokenSignerOptions{Key: []byte("secret")})
+	require.NoError(t, err)
+	recorder := &tokenRecordingCompletionHandler{}
+	handler := NewCompletionHTTPHandler(CompletionHandlerOptions{
+		Handler:        recorder,
+		CallbackTokens: signer,
+	})
+
+	complete := func(callbackURL string) *httptest.ResponseRecorder {
+		request, err := NewCompletionHTTPRequest(context.Background(), callbackURL, &OperationCompletionSuccessful{
+			Body: bytes.NewReader([]byte("success")),
+		})
+		require.NoError(t, err)
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, request)
+		return response
+	}
+
+	callbackURL, err := signer.CallbackURL("http://localhost/callback?a=b", CallbackToken{Operation: "op", OperationID: "id"})
+	require.NoError(t, err)
+	require.Contains(t, callbackURL, "a=b")
+	response := complete(callbackURL)
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NotNil(t, recorder.token)
+	require.Equal(t, "op", recorder.token.Operation)
+	require.Equal(t, "id", recorder.token.OperationID)
+
+	recorder.token = nil
+	response = complete("http://localhost/callback")
+	require.Equal(t, http.StatusUnauthorized, response.Code)
+	require.Equal(t, "false", response.Header().Get(headerRetryable))
+	require.Nil(t, recorder.token)
+
+	forger, err := NewCallbackTokenSigner(CallbackTokenSignerOptions{Key: []byte("guess")})
+	require.NoError(t, err)
+	forgedURL, err := forger.CallbackURL("http://localhost/callback", CallbackToken{OperationID: "id"})
+	require.NoError(t, err)
+	response = complete(forgedURL)
+	require.Equal(t, http.StatusUnauthorized, response.Code)
+	require.Nil(t, recorder.token)
+}
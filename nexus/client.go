@@ -10,6 +10,7 @@ import (
 	"math"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -19,9 +20,42 @@ import (
 type ClientOptions struct {
 	// Base URL of the service.
 	ServiceBaseURL string
+	// Name of the service to target when ServiceBaseURL points to a [ServiceMux], e.g. a base URL of
+	// https://example.com/services and a service of foo target the service mounted at /services/foo. Optional.
+	Service string
 	// A function for making HTTP requests.
 	// Defaults to [http.DefaultClient.Do].
 	HTTPCaller func(*http.Request) (*http.Response, error)
+	// Optional handler for recording request metrics.
+	MetricsHandler MetricsHandler
+	// Optional tracer for creating spans around each call and each long poll iteration. Regardless of this option,
+	// W3C trace context found in the context of each call via [SpanContextFromContext] is injected into outgoing
+	// requests.
+	Tracer Tracer
+	// Codecs the client accepts synchronous results in, in order of preference. When set, an Accept header listing their
+	// media types is sent with start and get-result requests that do not already specify one, allowing handlers to
+	// negotiate the result's representation. Optional.
+	Codecs []Codec
+	// Compressors for compressing request bodies and decompressing response bodies, in order of preference. When set,
+	// an Accept-Encoding header listing their encodings is sent with each request and start request bodies are
+	// compressed with the first compressor. The handler must support its encoding. Optional.
+	Compressors []Compressor
+	// Minimum size in bytes of start request bodies to compress when Compressors is set. Negative values disable
+	// compression of request bodies.
+	//
+	// Defaults to 1 KiB.
+	RequestCompressionThreshold int
+	// Max size in bytes of decompressed response bodies. Reading beyond the limit fails with an [http.MaxBytesError].
+	//
+	// Defaults to 16 MiB.
+	MaxDecompressedBodySize int64
+	// Optional registry of errors to restore from failures reported by the handler. When set, errors converted from
+	// the failures of [UnsuccessfulOperationError]s and [UnexpectedResponseError]s with [ErrorFromFailure] are
+	// exposed via their Unwrap methods, allowing [errors.Is] and [errors.As] to match registered errors.
+	ErrorRegistry *ErrorRegistry
+	// Optional mutual TLS configuration. When set, requests are made with an HTTP client presenting the configured
+	// client certificate, which is reloaded when it changes on disk. Mutually exclusive with HTTPCaller.
+	TLS *ClientTLSOptions
 }
 
 // User-Agent header set on HTTP requests.
@@ -35,6 +69,9 @@ var errEmptyServiceBaseURL = errors.New("empty serviceBaseURL")
 // Error indicating a non HTTP URL was used to create a [Client].
 var errInvalidURLScheme = errors.New("invalid URL scheme")
 
+// Error indicating both the HTTPCaller and TLS options were used to create a [Client].
+var errConflictingHTTPCaller = errors.New("HTTPCaller and TLS options are mutually exclusive")
+
 var errEmptyOperationName = errors.New("empty operation name")
 
 var errEmptyOperationID = errors.New("empty operation ID")
@@ -49,6 +86,40 @@ type UnexpectedResponseError struct {
 	Response *http.Response
 	// Optional failure that may have been emedded in the HTTP response body.
 	Failure *Failure
+	// Hint from the handler whether the request may be retried, parsed from the Nexus-Request-Retryable response
+	// header. See [HandlerError.RetryBehavior].
+	RetryBehavior RetryBehavior
+	// Error converted from Failure with the client's [ErrorRegistry], if set.
+	cause error
+}
+
+// Unwrap returns the error converted from the failure with [ClientOptions.ErrorRegistry], allowing [errors.Is] and
+// [errors.As] to match registered errors. Returns nil if the client has no error registry or the response contained
+// no failure.
+func (e *UnexpectedResponseError) Unwrap() error {
+	return e.cause
+}
+
+// Retryable reports whether the request that resulted in this error may be retried.
+// Honors the handler's [RetryBehavior] hint if specified, otherwise treats 408, 429, and 5xx statuses other than 501 as
+// retryable.
+func (e *UnexpectedResponseError) Retryable() bool {
+	switch e.RetryBehavior {
+	case RetryBehaviorRetryable:
+		return true
+	case RetryBehaviorNonRetryable:
+		return false
+	}
+	if e.Response == nil {
+		return false
+	}
+	switch e.Response.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	case http.StatusNotImplemented:
+		return false
+	}
+	return e.Response.StatusCode >= 500
 }
 
 // Error implements the error interface.
@@ -64,10 +135,19 @@ func newUnexpectedResponseError(message string, response *http.Response, body []
 		}
 	}
 
+	retryBehavior := RetryBehaviorUnspecified
+	switch response.Header.Get(headerRetryable) {
+	case "true":
+		retryBehavior = RetryBehaviorRetryable
+	case "false":
+		retryBehavior = RetryBehaviorNonRetryable
+	}
+
 	return &UnexpectedResponseError{
-		Message:  message,
-		Response: response,
-		Failure:  failure,
+		Message:       message,
+		Response:      response,
+		Failure:       failure,
+		RetryBehavior: retryBehavior,
 	}
 }
 
@@ -85,14 +165,60 @@ type Client struct {
 	// The options this client was created with after applying defaults.
 	options        ClientOptions
 	serviceBaseURL *url.URL
+	accept         string
+	acceptEncoding string
+}
+
+// convertErrorCause sets the cause of errors returned from client methods to the error converted from their failure
+// with the client's error registry.
+func (c *Client) convertErrorCause(err error) {
+	if c.options.ErrorRegistry == nil {
+		return
+	}
+	var unsuccessfulError *UnsuccessfulOperationError
+	var unexpectedResponseError *UnexpectedResponseError
+	if errors.As(err, &unsuccessfulError) && unsuccessfulError.cause == nil {
+		unsuccessfulError.cause = ErrorFromFailure(unsuccessfulError.Failure, c.options.ErrorRegistry)
+	} else if errors.As(err, &unexpectedResponseError) && unexpectedResponseError.Failure != nil && unexpectedResponseError.cause == nil {
+		unexpectedResponseError.cause = ErrorFromFailure(*unexpectedResponseError.Failure, c.options.ErrorRegistry)
+	}
+}
+
+// setAccept sets the Accept header derived from the client's codecs unless header already specifies one.
+func (c *Client) setAccept(header http.Header) {
+	if c.accept != "" && header.Get(headerAccept) == "" {
+		header.Set(headerAccept, c.accept)
+	}
 }
 
 // NewClient creates a new [Client] from provided [ClientOptions].
 // Only BaseServiceURL is required.
 func NewClient(options ClientOptions) (*Client, error) {
+	if options.TLS != nil {
+		if options.HTTPCaller != nil {
+			return nil, errConflictingHTTPCaller
+		}
+		caller, err := options.TLS.newHTTPCaller()
+		if err != nil {
+			return nil, err
+		}
+		options.HTTPCaller = caller
+	}
 	if options.HTTPCaller == nil {
 		options.HTTPCaller = http.DefaultClient.Do
 	}
+	if options.MetricsHandler == nil {
+		options.MetricsHandler = noopMetricsHandler{}
+	}
+	if options.Tracer == nil {
+		options.Tracer = noopTracer{}
+	}
+	if options.RequestCompressionThreshold == 0 {
+		options.RequestCompressionThreshold = defaultRequestCompressionThreshold
+	}
+	if options.MaxDecompressedBodySize == 0 {
+		options.MaxDecompressedBodySize = defaultMaxDecompressedBodySize
+	}
 	if options.ServiceBaseURL == "" {
 		return nil, errEmptyServiceBaseURL
 	}
@@ -105,10 +231,24 @@ func NewClient(options ClientOptions) (*Client, error) {
 	if serviceBaseURL.Scheme != "http" && serviceBaseURL.Scheme != "https" {
 		return nil, errInvalidURLScheme
 	}
+	if options.Service != "" {
+		serviceBaseURL = serviceBaseURL.JoinPath(url.PathEscape(options.Service))
+	}
+
+	var accept string
+	if len(options.Codecs) > 0 {
+		accept = formatAccept(options.Codecs)
+	}
+	encodings := make([]string, len(options.Compressors))
+	for i, compressor := range options.Compressors {
+		encodings[i] = compressor.Encoding()
+	}
 
 	return &Client{
 		options:        options,
 		serviceBaseURL: serviceBaseURL,
+		accept:         accept,
+		acceptEncoding: strings.Join(encodings, ", "),
 	}, nil
 }
 
@@ -175,7 +315,13 @@ type StartOperationResult struct {
 //     [UnsuccessfulOperationError].
 //
 //  4. Any other failure.
-func (c *Client) StartOperation(ctx context.Context, options StartOperationOptions) (*StartOperationResult, error) {
+func (c *Client) StartOperation(ctx context.Context, options StartOperationOptions) (result *StartOperationResult, err error) {
+	ctx, span := c.startSpan(ctx, "nexus.client.start_operation", SpanKindClient, options.Operation, "")
+	defer func() {
+		c.convertErrorCause(err)
+		endSpan(span, err)
+	}()
+
 	if closer, ok := options.Body.(io.Closer); ok {
 		// Close the request body in case we error before sending the HTTP request (which may double close but that's fine since we ignore the error).
 		defer closer.Close()
@@ -208,8 +354,14 @@ func (c *Client) StartOperation(ctx context.Context, options StartOperationOptio
 	}
 	request.Header.Set(headerRequestID, options.RequestID)
 	request.Header.Set(headerUserAgent, userAgent)
+	c.setAccept(request.Header)
+	if len(c.options.Compressors) > 0 {
+		if err := compressRequestBody(request, c.options.Compressors[0], c.options.RequestCompressionThreshold); err != nil {
+			return nil, err
+		}
+	}
 
-	response, err := c.options.HTTPCaller(request)
+	response, err := c.send(MetricsMethodStartOperation, request)
 	if err != nil {
 		return nil, err
 	}
@@ -341,7 +493,13 @@ func (o *ExecuteOperationOptions) intoGetResultOptions() (options GetOperationRe
 //
 // ⚠️ If this method completes successfully, the returned response's body must be read in its entirety and closed to
 // free up the underlying connection.
-func (c *Client) ExecuteOperation(ctx context.Context, request ExecuteOperationOptions) (*http.Response, error) {
+func (c *Client) ExecuteOperation(ctx context.Context, request ExecuteOperationOptions) (response *http.Response, err error) {
+	ctx, span := c.startSpan(ctx, "nexus.client.execute_operation", SpanKindInternal, request.Operation, "")
+	defer func() {
+		c.convertErrorCause(err)
+		endSpan(span, err)
+	}()
+
 	result, err := c.StartOperation(ctx, request.intoStartOptions())
 	if err != nil {
 		return nil, err
@@ -374,6 +532,82 @@ func (c *Client) NewHandle(operation string, operationID string) (*OperationHand
 	}, nil
 }
 
+// Describe fetches the [ServiceDescription] of the service, listing the operations it exposes.
+//
+// The service's handler must be configured with an [OperationRegistry], otherwise an [UnexpectedResponseError] with a
+// 404 or 405 status is returned.
+func (c *Client) Describe(ctx context.Context) (description *ServiceDescription, err error) {
+	ctx, span := startSpan(ctx, c.options.Tracer, SpanOptions{Name: "nexus.client.describe_service", Kind: SpanKindClient})
+	defer func() {
+		c.convertErrorCause(err)
+		endSpan(span, err)
+	}()
+
+	request, err := http.NewRequestWithContext(ctx, "GET", c.serviceBaseURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set(headerUserAgent, userAgent)
+	response, err := c.send(MetricsMethodDescribeService, request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Do this once here and make sure it doesn't leak.
+	body, err := readAndReplaceBody(response)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, newUnexpectedResponseError(fmt.Sprintf("unexpected response status: %q", response.Status), response, body)
+	}
+	if !isContentTypeJSON(response.Header) {
+		return nil, newUnexpectedResponseError(fmt.Sprintf("invalid response content type: %q", response.Header.Get(headerContentType)), response, body)
+	}
+	if err := json.Unmarshal(body, &description); err != nil {
+		return nil, err
+	}
+	return description, nil
+}
+
+// startSpan starts a span for a client call using the configured Tracer.
+func (c *Client) startSpan(ctx context.Context, name string, kind SpanKind, operation, operationID string) (context.Context, Span) {
+	attributes := map[string]string{"nexus.operation": operation}
+	if operationID != "" {
+		attributes["nexus.operation_id"] = operationID
+	}
+	return startSpan(ctx, c.options.Tracer, SpanOptions{Name: name, Kind: kind, Attributes: attributes})
+}
+
+// send sends an HTTP request using the configured HTTPCaller, recording metrics for the given method and injecting
+// trace context from the request's context.
+func (c *Client) send(method MetricsMethod, request *http.Request) (*http.Response, error) {
+	injectSpanContext(request.Context(), request.Header)
+	if c.acceptEncoding != "" && request.Header.Get(headerAcceptEncoding) == "" {
+		request.Header.Set(headerAcceptEncoding, c.acceptEncoding)
+	}
+	metricsRequest := MetricsRequest{Component: MetricsComponentClient, Method: method}
+	startTime := time.Now()
+	c.options.MetricsHandler.RequestStarted(metricsRequest)
+	response, err := c.options.HTTPCaller(request)
+	result := MetricsResult{Latency: time.Since(startTime)}
+	if err != nil {
+		result.Outcome = MetricsOutcomeTransportError
+	} else {
+		result.StatusCode = response.StatusCode
+		result.Outcome = metricsOutcomeFromResponse(method, response.StatusCode, response.Header)
+	}
+	c.options.MetricsHandler.RequestCompleted(metricsRequest, result)
+	if err != nil {
+		return nil, err
+	}
+	if err := decompressResponseBody(response, c.options.Compressors, c.options.MaxDecompressedBodySize); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
 // readAndReplaceBody reads the response body in its entirety and closes it, and then replaces the original response
 // body with an in-memory buffer.
 // The body is replaced even when there was an error reading the entire body.
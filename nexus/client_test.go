@@ -1,6 +1,8 @@
 package nexus
 
 import (
+	"context"
+	"net/http"
 	"net/url"
 	"testing"
 
@@ -26,3 +28,50 @@ func TestServiceBaseURL(t *testing.T) {
 	_, err = NewClient(ClientOptions{ServiceBaseURL: "https://example.com"})
 	require.NoError(t, err)
 }
+
+type retryBehaviorHandler struct {
+	UnimplementedHandler
+}
+
+func (h *retryBehaviorHandler) StartOperation(ctx context.Context, request *StartOperationRequest) (OperationResponse, error) {
+	switch request.Operation {
+	case "retryable":
+		return nil, &HandlerError{StatusCode: http.StatusBadRequest, RetryBehavior: RetryBehaviorRetryable}
+	case "non-retryable":
+		return nil, &HandlerError{StatusCode: http.StatusServiceUnavailable, RetryBehavior: RetryBehaviorNonRetryable}
+	case "unavailable":
+		return nil, &HandlerError{StatusCode: http.StatusServiceUnavailable}
+	}
+	return nil, newBadRequestError("bad request")
+}
+
+func TestUnexpectedResponseError_Retryable(t *testing.T) {
+	ctx, client, teardown := setup(t, &retryBehaviorHandler{})
+	defer teardown()
+
+	cases := []struct {
+		operation string
+		behavior  RetryBehavior
+		retryable bool
+	}{
+		{operation: "retryable", behavior: RetryBehaviorRetryable, retryable: true},
+		{operation: "non-retryable", behavior: RetryBehaviorNonRetryable, retryable: false},
+		{operation: "unavailable", behavior: RetryBehaviorUnspecified, retryable: true},
+		{operation: "bad", behavior: RetryBehaviorUnspecified, retryable: false},
+	}
+	for _, c := range cases {
+		_, err := client.StartOperation(ctx, StartOperationOptions{Operation: c.operation})
+		var unexpectedResponseError *UnexpectedResponseError
+		require.ErrorAs(t, err, &unexpectedResponseError)
+		require.Equal(t, c.behavior, unexpectedResponseError.RetryBehavior, c.operation)
+		require.Equal(t, c.retryable, unexpectedResponseError.Retryable(), c.operation)
+	}
+
+	// Unimplemented methods are never retryable.
+	handle, err := client.NewHandle("foo", "id")
+	require.NoError(t, err)
+	err = handle.Cancel(ctx, CancelOperationOptions{})
+	var unexpectedResponseError *UnexpectedResponseError
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, RetryBehaviorNonRetryable, unexpectedResponseError.RetryBehavior)
+}
@@ -0,0 +1,192 @@
+package nexus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const headerAccept = "Accept"
+
+// A Codec encodes and decodes values in a single media type.
+//
+// Set [HandlerOptions.Codecs] to the codecs a handler may encode results with and [ClientOptions.Codecs] to the codecs
+// a client accepts results in.
+type Codec interface {
+	// MediaType of the encoded representation, e.g. application/json.
+	MediaType() string
+	// Marshal encodes a value.
+	Marshal(v any) ([]byte, error)
+	// Unmarshal decodes data into the value pointed to by v.
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec is a [Codec] for the application/json media type using [json.Marshal] and [json.Unmarshal].
+type JSONCodec struct{}
+
+// MediaType implements the Codec interface.
+func (JSONCodec) MediaType() string {
+	return contentTypeJSON
+}
+
+// Marshal implements the Codec interface.
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal implements the Codec interface.
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// A MediaRange is an entry of a parsed Accept header.
+type MediaRange struct {
+	// Media type or range, e.g. application/json, application/*, or */*. Parameters other than q are not retained.
+	MediaType string
+	// Relative quality, between 0 and 1. A quality of 0 indicates that the media type is not acceptable.
+	Quality float64
+}
+
+// Matches reports whether a media type, e.g. application/json or application/json; charset=utf-8, is in the range.
+func (r MediaRange) Matches(mediaType string) bool {
+	if parsed, _, err := mime.ParseMediaType(mediaType); err == nil {
+		mediaType = parsed
+	}
+	if r.MediaType == "*/*" || r.MediaType == mediaType {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(r.MediaType, "/*"); ok {
+		return strings.HasPrefix(mediaType, prefix+"/")
+	}
+	return false
+}
+
+// specificity ranks ranges so that exact media types take precedence over wildcards.
+func (r MediaRange) specificity() int {
+	switch {
+	case r.MediaType == "*/*":
+		return 0
+	case strings.HasSuffix(r.MediaType, "/*"):
+		return 1
+	default:
+		return 2
+	}
+}
+
+// parseAccept parses Accept headers into media ranges ordered by decreasing quality and specificity. Malformed entries
+// are skipped.
+func parseAccept(header http.Header) []MediaRange {
+	var ranges []MediaRange
+	for _, value := range header.Values(headerAccept) {
+		for _, entry := range strings.Split(value, ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			mediaType, params, err := mime.ParseMediaType(entry)
+			if err != nil || !strings.Contains(mediaType, "/") {
+				continue
+			}
+			quality := 1.0
+			if q, ok := params["q"]; ok {
+				quality, err = strconv.ParseFloat(q, 64)
+				if err != nil || quality < 0 || quality > 1 {
+					continue
+				}
+			}
+			ranges = append(ranges, MediaRange{MediaType: mediaType, Quality: quality})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].Quality != ranges[j].Quality {
+			return ranges[i].Quality > ranges[j].Quality
+		}
+		return ranges[i].specificity() > ranges[j].specificity()
+	})
+	return ranges
+}
+
+// formatAccept formats an Accept header listing the media types of the given codecs in order of preference.
+func formatAccept(codecs []Codec) string {
+	entries := make([]string, len(codecs))
+	for i, codec := range codecs {
+		entries[i] = codec.MediaType()
+		if i > 0 {
+			// Decreasing quality in steps of 0.1, bottoming out at 0.1.
+			entries[i] += fmt.Sprintf(";q=0.%d", max(10-i, 1))
+		}
+	}
+	return strings.Join(entries, ", ")
+}
+
+// negotiateCodec selects the codec with the highest quality according to the most specific matching range in accept.
+// Ties are broken by the order of codecs. All codecs are acceptable if accept is empty.
+func negotiateCodec(accept []MediaRange, codecs []Codec) (Codec, bool) {
+	if len(codecs) == 0 {
+		return nil, false
+	}
+	if len(accept) == 0 {
+		return codecs[0], true
+	}
+	var best Codec
+	bestQuality := 0.0
+	for _, codec := range codecs {
+		quality, specificity := 0.0, -1
+		for _, r := range accept {
+			if r.specificity() > specificity && r.Matches(codec.MediaType()) {
+				quality, specificity = r.Quality, r.specificity()
+			}
+		}
+		if quality > bestQuality {
+			best, bestQuality = codec, quality
+		}
+	}
+	return best, best != nil
+}
+
+// newNegotiatedOperationResponseSync encodes v using the codec that best matches accept.
+func newNegotiatedOperationResponseSync(accept []MediaRange, codecs []Codec, v any) (*OperationResponseSync, error) {
+	codec, ok := negotiateCodec(accept, codecs)
+	if !ok {
+		mediaTypes := make([]string, len(codecs))
+		for i, codec := range codecs {
+			mediaTypes[i] = codec.MediaType()
+		}
+		return nil, &HandlerError{
+			StatusCode: http.StatusNotAcceptable,
+			Failure: &Failure{
+				Message: fmt.Sprintf("none of the accepted media types are supported, supported media types: %s",
+					strings.Join(mediaTypes, ", ")),
+			},
+		}
+	}
+	b, err := codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	header := make(http.Header)
+	header.Set(headerContentType, codec.MediaType())
+	return &OperationResponseSync{
+		Header: header,
+		Body:   bytes.NewReader(b),
+	}, nil
+}
+
+// NegotiateResponse constructs an [OperationResponseSync] by encoding v with the [HandlerOptions.Codecs] codec that
+// best matches the request's Accept list, setting the proper Content-Type header.
+// Returns a [HandlerError] with a 406 status if none of the codecs are acceptable.
+func (r *StartOperationRequest) NegotiateResponse(v any) (*OperationResponseSync, error) {
+	return newNegotiatedOperationResponseSync(r.Accept, r.codecs, v)
+}
+
+// NegotiateResponse constructs an [OperationResponseSync] by encoding v with the [HandlerOptions.Codecs] codec that
+// best matches the request's Accept list, setting the proper Content-Type header.
+// Returns a [HandlerError] with a 406 status if none of the codecs are acceptable.
+func (r *GetOperationResultRequest) NegotiateResponse(v any) (*OperationResponseSync, error) {
+	return newNegotiatedOperationResponseSync(r.Accept, r.codecs, v)
+}
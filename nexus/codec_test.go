@@ -0,0 +1,122 @@
+package nexus
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAccept(t *testing.T) {
+	header := http.Header{}
+	header.Add(headerAccept, "text/*;q=0.5, */*;q=0.1, application/json, invalid, text/plain;q=0.5")
+	header.Add(headerAccept, "application/xml;q=2, image/png;q=0")
+	require.Equal(t, []MediaRange{
+		{MediaType: "application/json", Quality: 1},
+		{MediaType: "text/plain", Quality: 0.5},
+		{MediaType: "text/*", Quality: 0.5},
+		{MediaType: "*/*", Quality: 0.1},
+		{MediaType: "image/png", Quality: 0},
+	}, parseAccept(header))
+	require.Empty(t, parseAccept(http.Header{}))
+}
+
+type textCodec struct{}
+
+func (textCodec) MediaType() string { return "text/plain" }
+
+func (textCodec) Marshal(v any) ([]byte, error) { return []byte(fmt.Sprint(v)), nil }
+
+func (textCodec) Unmarshal(data []byte, v any) error {
+	*v.(*string) = string(data)
+	return nil
+}
+
+func TestNegotiateCodec(t *testing.T) {
+	codecs := []Codec{JSONCodec{}, textCodec{}}
+	cases := []struct {
+		accept   string
+		expected Codec
+	}{
+		{accept: "", expected: JSONCodec{}},
+		{accept: "text/plain", expected: textCodec{}},
+		{accept: "text/*, application/json;q=0.9", expected: textCodec{}},
+		{accept: "*/*", expected: JSONCodec{}},
+		{accept: "*/*, application/json;q=0", expected: textCodec{}},
+		{accept: "text/plain;q=0.5, application/*;q=0.5", expected: JSONCodec{}},
+		{accept: "image/png", expected: nil},
+	}
+	for _, c := range cases {
+		codec, ok := negotiateCodec(parseAccept(http.Header{headerAccept: []string{c.accept}}), codecs)
+		require.Equal(t, c.expected != nil, ok, c.accept)
+		require.Equal(t, c.expected, codec, c.accept)
+	}
+}
+
+func TestFormatAccept(t *testing.T) {
+	require.Equal(t, "application/json", formatAccept([]Codec{JSONCodec{}}))
+	require.Equal(t, "text/plain, application/json;q=0.9", formatAccept([]Codec{textCodec{}, JSONCodec{}}))
+}
+
+type negotiatingHandler struct {
+	UnimplementedHandler
+}
+
+func (h *negotiatingHandler) StartOperation(ctx context.Context, request *StartOperationRequest) (OperationResponse, error) {
+	return request.NegotiateResponse("hello")
+}
+
+func (h *negotiatingHandler) GetOperationResult(ctx context.Context, request *GetOperationResultRequest) (*OperationResponseSync, error) {
+	return request.NegotiateResponse("hello")
+}
+
+func TestContentNegotiation(t *testing.T) {
+	handlerOptions := HandlerOptions{
+		Handler: &negotiatingHandler{},
+		Codecs:  []Codec{JSONCodec{}, textCodec{}},
+	}
+
+	ctx, client, teardown := setupCustom(t, handlerOptions, ClientOptions{})
+	defer teardown()
+	result, err := client.StartOperation(ctx, StartOperationOptions{Operation: "foo"})
+	require.NoError(t, err)
+	defer result.Successful.Body.Close()
+	require.Equal(t, contentTypeJSON, result.Successful.Header.Get(headerContentType))
+	body, err := io.ReadAll(result.Successful.Body)
+	require.NoError(t, err)
+	require.Equal(t, `"hello"`, string(body))
+
+	_, err = client.StartOperation(ctx, StartOperationOptions{
+		Operation: "foo",
+		Header:    http.Header{headerAccept: []string{"image/png"}},
+	})
+	var unexpectedResponseError *UnexpectedResponseError
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusNotAcceptable, unexpectedResponseError.Response.StatusCode)
+
+	ctx, client, teardown = setupCustom(t, handlerOptions, ClientOptions{Codecs: []Codec{textCodec{}, JSONCodec{}}})
+	defer teardown()
+	result, err = client.StartOperation(ctx, StartOperationOptions{Operation: "foo"})
+	require.NoError(t, err)
+	defer result.Successful.Body.Close()
+	require.Equal(t, "text/plain", result.Successful.Header.Get(headerContentType))
+	body, err = io.ReadAll(result.Successful.Body)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(body))
+
+	handle, err := client.NewHandle("foo", "id")
+	require.NoError(t, err)
+	response, err := handle.GetResult(ctx, GetOperationResultOptions{})
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, "text/plain", response.Header.Get(headerContentType))
+
+	// Explicit Accept headers take precedence over the client's codecs.
+	response, err = handle.GetResult(ctx, GetOperationResultOptions{Header: http.Header{headerAccept: []string{contentTypeJSON}}})
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, contentTypeJSON, response.Header.Get(headerContentType))
+}
@@ -2,15 +2,45 @@ package nexus
 
 import (
 	"bytes"
+	"container/list"
 	"context"
 	"encoding/json"
 	"io"
 	"log/slog"
 	"net/http"
+	"time"
 )
 
 // NewCompletionHTTPRequest creates an HTTP request deliver an operation completion to a given URL.
+//
+// W3C trace context found in ctx via [SpanContextFromContext] is injected into the request headers.
 func NewCompletionHTTPRequest(ctx context.Context, url string, completion OperationCompletion) (*http.Request, error) {
+	return NewCompletionHTTPRequestWithOptions(ctx, url, completion, CompletionRequestOptions{})
+}
+
+// CompletionRequestOptions are options for [NewCompletionHTTPRequestWithOptions].
+type CompletionRequestOptions struct {
+	// Compressor for compressing the request body. The completion handler must support its encoding.
+	// Optional, bodies are sent uncompressed by default.
+	Compressor Compressor
+	// Minimum size in bytes of request bodies to compress. Negative values disable compression.
+	//
+	// Defaults to 1 KiB.
+	CompressionThreshold int
+	// ID identifying the completion across delivery attempts, used by completion handlers to detect duplicate
+	// deliveries. See [CompletionHandlerOptions.CompletionStore]. Must be unique per completion, e.g. the ID of the
+	// completed operation, and set to the same value for every delivery attempt.
+	// Optional, completions are sent without an ID and not deduplicated by default. Ignored if the completion's Header
+	// already specifies an ID.
+	CompletionID string
+	// Optional signer for signing the completion's timestamp, a random nonce, the request's method, path, and query,
+	// and the completion's state, completion ID, and body. Completion handlers created with
+	// [CompletionHandlerOptions.SignatureVerifiers] reject unsigned requests.
+	Signer CompletionSigner
+}
+
+// NewCompletionHTTPRequestWithOptions is like [NewCompletionHTTPRequest] but accepts additional options.
+func NewCompletionHTTPRequestWithOptions(ctx context.Context, url string, completion OperationCompletion, options CompletionRequestOptions) (*http.Request, error) {
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, nil)
 	if err != nil {
 		return nil, err
@@ -18,8 +48,23 @@ func NewCompletionHTTPRequest(ctx context.Context, url string, completion Operat
 	if err := completion.applyToHTTPRequest(httpReq); err != nil {
 		return nil, err
 	}
+	if options.CompletionID != "" && httpReq.Header.Get(headerCompletionID) == "" {
+		httpReq.Header.Set(headerCompletionID, options.CompletionID)
+	}
+	if options.Signer != nil {
+		if err := signCompletionRequest(httpReq, options.Signer, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	if options.CompressionThreshold == 0 {
+		options.CompressionThreshold = defaultRequestCompressionThreshold
+	}
+	if err := compressRequestBody(httpReq, options.Compressor, options.CompressionThreshold); err != nil {
+		return nil, err
+	}
 
 	httpReq.Header.Set(headerUserAgent, userAgent)
+	injectSpanContext(ctx, httpReq.Header)
 	return httpReq, nil
 }
 
@@ -103,6 +148,11 @@ type CompletionRequest struct {
 	State OperationState
 	// Parsed from request and set if State is failed or canceled.
 	Failure *Failure
+	// Verified token embedded in the callback URL, set if the handler was created with
+	// [CompletionHandlerOptions.CallbackTokens].
+	CallbackToken *CallbackToken
+
+	codecs []Codec
 }
 
 // A CompletionHandler can receive operation completion requests as delivered via the callback URL provided in
@@ -121,18 +171,123 @@ type CompletionHandlerOptions struct {
 	// Optional marshaler for marshaling objects to JSON.
 	// Defaults to json.Marshal.
 	Marshaler func(any) ([]byte, error)
+	// Optional handler for recording request metrics.
+	MetricsHandler MetricsHandler
+	// Optional tracer for creating a span for each request. Regardless of this option, W3C trace context is
+	// extracted from incoming requests and made available via [SpanContextFromContext].
+	Tracer Tracer
+	// Compressors for decompressing request bodies. Requests with a Content-Encoding not supported by any of the
+	// compressors are rejected with a 415 status.
+	// Defaults to [GzipCompressor] and [ZstdCompressor].
+	Compressors []Compressor
+	// Max size in bytes of decompressed request bodies. See [HandlerOptions.MaxDecompressedBodySize].
+	//
+	// Defaults to 16 MiB.
+	MaxDecompressedBodySize int64
+	// Optional hook invoked with panics recovered from the Handler. Regardless of this option, panics are logged and
+	// the request is failed with a 500 status.
+	PanicHandler PanicHandler
+	// Optional registry of errors that may be reported to callers. See [HandlerOptions.ErrorRegistry].
+	ErrorRegistry *ErrorRegistry
+	// Optional signer for verifying callback tokens embedded in callback URLs with [CallbackTokenSigner.CallbackURL].
+	// When set, requests without a valid, unexpired token are rejected with a 401 status without invoking the Handler.
+	CallbackTokens *CallbackTokenSigner
+	// Codecs available for decoding successful results with [NewTypedCompletionHandler], selected by the request's
+	// Content-Type.
+	// Defaults to [JSONCodec].
+	Codecs []Codec
+	// Optional store for deduplicating completions. When set, completions are identified by the completion ID set
+	// by [NewCompletionHTTPRequest] and the callback token embedded in the callback URL, and completions the Handler
+	// processed successfully are remembered for CompletionTTL. Duplicate deliveries are acknowledged with a 200 status
+	// without invoking the Handler and concurrent deliveries of the same completion are rejected with a retryable 409
+	// status. Requests without a completion ID are not deduplicated.
+	CompletionStore CompletionStore
+	// Duration to remember processed completions for.
+	// Defaults to 24 hours.
+	CompletionTTL time.Duration
+	// Optional verifiers of completion request signatures, see [CompletionRequestOptions.Signer]. When set, requests
+	// must be signed by a key matching one of the verifiers, allowing keys to be rotated by listing both the old and
+	// new keys. Unsigned, tampered, and stale requests are rejected with a 401 status without invoking the Handler.
+	// Signatures are single use: replays of a request processed successfully, or being processed, are rejected with a
+	// 401 status while the signature is fresh, unless the CompletionStore acknowledges them as duplicates. Nonces are
+	// tracked in memory and replicas do not share them. After a response was lost, senders must sign a new request to
+	// redeliver a completion the CompletionStore does not remember. Bodies are buffered for verification up to
+	// MaxDecompressedBodySize bytes, larger requests are rejected with a 413 status.
+	SignatureVerifiers []CompletionVerifier
+	// Maximum difference between a request's signature timestamp and the current time, accounting for delivery
+	// latency and clock skew.
+	// Defaults to five minutes.
+	SignatureMaxAge time.Duration
 }
 
 type completionHTTPHandler struct {
 	baseHTTPHandler
-	handler CompletionHandler
+	handler        CompletionHandler
+	callbackTokens *CallbackTokenSigner
+	codecs         []Codec
+	deduplicator   *completionDeduplicator
+	signatures     *completionSignatureVerifier
 }
 
 func (h *completionHTTPHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	h.instrument(MetricsMethodCompleteOperation, h.recoverPanics(h.decompress(h.completeOperation)))(writer, request)
+}
+
+func (h *completionHTTPHandler) completeOperation(writer http.ResponseWriter, request *http.Request) {
 	ctx := request.Context()
 	completion := CompletionRequest{
 		State:       OperationState(request.Header.Get(headerOperationState)),
 		HTTPRequest: request,
+		codecs:      h.codecs,
+	}
+	// Set once the handler has processed the completion successfully.
+	processed := false
+	var nonce string
+	if h.signatures != nil {
+		var err error
+		if nonce, err = h.signatures.verify(request); err != nil {
+			h.writeFailure(writer, err)
+			return
+		}
+	}
+	if h.callbackTokens != nil {
+		token, err := h.callbackTokens.verifyRequest(request)
+		if err != nil {
+			h.writeFailure(writer, err)
+			return
+		}
+		completion.CallbackToken = token
+	}
+	if h.deduplicator != nil {
+		key, duplicate, err := h.deduplicator.start(ctx, request)
+		if err != nil {
+			h.writeFailure(writer, err)
+			return
+		}
+		if duplicate {
+			h.logger.Debug("acknowledging duplicate completion", "completionID", request.Header.Get(headerCompletionID))
+			return
+		}
+		if key != "" {
+			defer func() {
+				if err := h.deduplicator.finish(ctx, key, processed); err != nil {
+					h.logger.Error("failed to remember completion", "error", err)
+				}
+			}()
+		}
+	}
+	if h.signatures != nil {
+		// Claimed after consulting the completion store so that replays of processed completions are acknowledged as
+		// duplicates.
+		if !h.signatures.claim(nonce) {
+			h.writeFailure(writer, newUnauthorizedCompletionError("replayed completion signature"))
+			return
+		}
+		defer func() {
+			if !processed {
+				h.signatures.release(nonce)
+			}
+		}()
 	}
 	switch completion.State {
 	case OperationStateFailed, OperationStateCanceled:
@@ -143,7 +298,7 @@ func (h *completionHTTPHandler) ServeHTTP(writer http.ResponseWriter, request *h
 		var failure Failure
 		b, err := io.ReadAll(request.Body)
 		if err != nil {
-			h.writeFailure(writer, newBadRequestError("failed to read Failure from request body"))
+			h.writeFailure(writer, newReadBodyError(err, "failed to read Failure from request body"))
 			return
 		}
 		if err := json.Unmarshal(b, &failure); err != nil {
@@ -159,7 +314,9 @@ func (h *completionHTTPHandler) ServeHTTP(writer http.ResponseWriter, request *h
 	}
 	if err := h.handler.CompleteOperation(ctx, &completion); err != nil {
 		h.writeFailure(writer, err)
+		return
 	}
+	processed = true
 }
 
 // NewCompletionHTTPHandler constructs an [http.Handler] from given options for handling operation completion requests.
@@ -170,10 +327,62 @@ func NewCompletionHTTPHandler(options CompletionHandlerOptions) http.Handler {
 	if options.Logger == nil {
 		options.Logger = slog.Default()
 	}
+	if options.MetricsHandler == nil {
+		options.MetricsHandler = noopMetricsHandler{}
+	}
+	if options.Tracer == nil {
+		options.Tracer = noopTracer{}
+	}
+	if len(options.Compressors) == 0 {
+		options.Compressors = []Compressor{GzipCompressor{}, ZstdCompressor{}}
+	}
+	if options.MaxDecompressedBodySize == 0 {
+		options.MaxDecompressedBodySize = defaultMaxDecompressedBodySize
+	}
+	if len(options.Codecs) == 0 {
+		options.Codecs = []Codec{JSONCodec{}}
+	}
+	if options.CompletionTTL <= 0 {
+		options.CompletionTTL = 24 * time.Hour
+	}
+	var deduplicator *completionDeduplicator
+	if options.CompletionStore != nil {
+		deduplicator = &completionDeduplicator{
+			store:    options.CompletionStore,
+			ttl:      options.CompletionTTL,
+			inFlight: make(map[string]struct{}),
+		}
+	}
+	if options.SignatureMaxAge <= 0 {
+		options.SignatureMaxAge = 5 * time.Minute
+	}
+	var signatures *completionSignatureVerifier
+	if len(options.SignatureVerifiers) > 0 {
+		signatures = &completionSignatureVerifier{
+			verifiers:   options.SignatureVerifiers,
+			maxAge:      options.SignatureMaxAge,
+			now:         time.Now,
+			maxBodySize: options.MaxDecompressedBodySize,
+			nonces:      make(map[string]*list.Element),
+			nonceOrder:  list.New(),
+		}
+	}
 	return &completionHTTPHandler{
 		baseHTTPHandler: baseHTTPHandler{
-			logger: options.Logger,
+			logger:           options.Logger,
+			metrics:          options.MetricsHandler,
+			metricsComponent: MetricsComponentCompletionHandler,
+			tracer:           options.Tracer,
+			compressors:      options.Compressors,
+			panicHandler:     options.PanicHandler,
+			errorRegistry:    options.ErrorRegistry,
+
+			maxDecompressedBodySize: options.MaxDecompressedBodySize,
 		},
-		handler: options.Handler,
+		handler:        options.Handler,
+		callbackTokens: options.CallbackTokens,
+		codecs:         options.Codecs,
+		deduplicator:   deduplicator,
+		signatures:     signatures,
 	}
 }
@@ -0,0 +1,266 @@
+package nexus
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Headers carrying completion request signatures.
+const (
+	headerCompletionSignature          = "Nexus-Completion-Signature"
+	headerCompletionSignatureKeyID     = "Nexus-Completion-Signature-Key-Id"
+	headerCompletionSignatureTimestamp = "Nexus-Completion-Signature-Timestamp"
+	headerCompletionSignatureNonce     = "Nexus-Completion-Signature-Nonce"
+)
+
+// Version prefix of signed completion messages, allowing the message format to evolve.
+const completionSignatureVersion = "nexus-completion-v2"
+
+// A CompletionSigner signs completion requests. Set [CompletionRequestOptions.Signer] to sign a completion request.
+//
+// [HMACCompletionKey] and [Ed25519CompletionSigner] are provided by this package.
+type CompletionSigner interface {
+	// KeyID identifies the key to the completion handler, which uses it to select a [CompletionVerifier].
+	KeyID() string
+	// Sign returns the signature of a message.
+	Sign(message []byte) ([]byte, error)
+}
+
+// A CompletionVerifier verifies signatures of completion requests. Set [CompletionHandlerOptions.SignatureVerifiers] to
+// require completion requests to be signed.
+//
+// [HMACCompletionKey] and [Ed25519CompletionVerifier] are provided by this package.
+type CompletionVerifier interface {
+	// KeyID identifies the key, matched against the key ID sent with signed requests.
+	KeyID() string
+	// Verify reports whether signature is a valid signature of message.
+	Verify(message, signature []byte) bool
+}
+
+// HMACCompletionKey is a shared secret for signing and verifying completion requests with HMAC-SHA256.
+// It implements both [CompletionSigner] and [CompletionVerifier].
+type HMACCompletionKey struct {
+	// ID of the key.
+	ID string
+	// Shared secret.
+	Secret []byte
+}
+
+// KeyID implements the CompletionSigner and CompletionVerifier interfaces.
+func (k HMACCompletionKey) KeyID() string {
+	return k.ID
+}
+
+// Sign implements the CompletionSigner interface.
+func (k HMACCompletionKey) Sign(message []byte) ([]byte, error) {
+	if len(k.Secret) == 0 {
+		return nil, errors.New("empty HMAC secret")
+	}
+	mac := hmac.New(sha256.New, k.Secret)
+	mac.Write(message)
+	return mac.Sum(nil), nil
+}
+
+// Verify implements the CompletionVerifier interface.
+func (k HMACCompletionKey) Verify(message, signature []byte) bool {
+	expected, err := k.Sign(message)
+	return err == nil && hmac.Equal(expected, signature)
+}
+
+// Ed25519CompletionSigner signs completion requests with an Ed25519 private key.
+type Ed25519CompletionSigner struct {
+	// ID of the key.
+	ID string
+	// Private key.
+	PrivateKey ed25519.PrivateKey
+}
+
+// KeyID implements the CompletionSigner interface.
+func (s Ed25519CompletionSigner) KeyID() string {
+	return s.ID
+}
+
+// Sign implements the CompletionSigner interface.
+func (s Ed25519CompletionSigner) Sign(message []byte) ([]byte, error) {
+	if len(s.PrivateKey) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid Ed25519 private key")
+	}
+	return ed25519.Sign(s.PrivateKey, message), nil
+}
+
+// Ed25519CompletionVerifier verifies signatures of completion requests with an Ed25519 public key.
+type Ed25519CompletionVerifier struct {
+	// ID of the key.
+	ID string
+	// Public key.
+	PublicKey ed25519.PublicKey
+}
+
+// KeyID implements the CompletionVerifier interface.
+func (v Ed25519CompletionVerifier) KeyID() string {
+	return v.ID
+}
+
+// Verify implements the CompletionVerifier interface.
+func (v Ed25519CompletionVerifier) Verify(message, signature []byte) bool {
+	return len(v.PublicKey) == ed25519.PublicKeySize && ed25519.Verify(v.PublicKey, message, signature)
+}
+
+// completionSignatureMessage constructs the message signed for a completion request, covering the signature
+// timestamp and nonce, the method, path, and query of the request, the operation state, the content type, the
+// completion ID, and a digest of the body.
+func completionSignatureMessage(request *http.Request, timestamp, nonce string, body []byte) []byte {
+	digest := sha256.Sum256(body)
+	return []byte(strings.Join([]string{
+		completionSignatureVersion,
+		timestamp,
+		nonce,
+		request.Method,
+		request.URL.RequestURI(),
+		request.Header.Get(headerOperationState),
+		request.Header.Get(headerContentType),
+		request.Header.Get(headerCompletionID),
+		hex.EncodeToString(digest[:]),
+	}, "\n"))
+}
+
+// signCompletionRequest buffers the body of request and sets signature headers.
+func signCompletionRequest(request *http.Request, signer CompletionSigner, now time.Time) error {
+	var body []byte
+	if request.Body != nil {
+		var err error
+		body, err = io.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return err
+		}
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	encodedNonce := base64.RawURLEncoding.EncodeToString(nonce)
+	signature, err := signer.Sign(completionSignatureMessage(request, timestamp, encodedNonce, body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set(headerCompletionSignatureKeyID, signer.KeyID())
+	request.Header.Set(headerCompletionSignatureTimestamp, timestamp)
+	request.Header.Set(headerCompletionSignatureNonce, encodedNonce)
+	request.Header.Set(headerCompletionSignature, base64.RawURLEncoding.EncodeToString(signature))
+	request.Body = io.NopCloser(bytes.NewReader(body))
+	request.ContentLength = int64(len(body))
+	request.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return nil
+}
+
+// completionSignatureVerifier verifies signatures of completion requests.
+type completionSignatureVerifier struct {
+	verifiers []CompletionVerifier
+	maxAge    time.Duration
+	now       func() time.Time
+	// Max size in bytes of bodies buffered for verification.
+	maxBodySize int64
+
+	mu sync.Mutex
+	// Nonces of accepted signatures, rejected if reused while their timestamp is fresh.
+	nonces map[string]*list.Element
+	// Accepted nonces, oldest first.
+	nonceOrder *list.List
+}
+
+type signatureNonce struct {
+	nonce     string
+	expiresAt time.Time
+}
+
+func newUnauthorizedCompletionError(message string) *HandlerError {
+	return &HandlerError{
+		StatusCode:    http.StatusUnauthorized,
+		Failure:       &Failure{Message: message},
+		RetryBehavior: RetryBehaviorNonRetryable,
+	}
+}
+
+// verify verifies the signature and freshness of a request, buffering up to maxBodySize bytes of its body so that it
+// can be read again. Returns the signature's nonce, to be claimed before processing the completion.
+func (v *completionSignatureVerifier) verify(request *http.Request) (string, error) {
+	signature, err := base64.RawURLEncoding.DecodeString(request.Header.Get(headerCompletionSignature))
+	if err != nil || len(signature) == 0 {
+		return "", newUnauthorizedCompletionError("missing or invalid completion signature")
+	}
+	timestamp := request.Header.Get(headerCompletionSignatureTimestamp)
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", newUnauthorizedCompletionError("missing or invalid completion signature timestamp")
+	}
+	nonce := request.Header.Get(headerCompletionSignatureNonce)
+	if nonce == "" {
+		return "", newUnauthorizedCompletionError("missing completion signature nonce")
+	}
+	age := v.now().Sub(time.Unix(unix, 0))
+	if age > v.maxAge || age < -v.maxAge {
+		return "", newUnauthorizedCompletionError("stale completion signature")
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(nil, request.Body, v.maxBodySize))
+	if err != nil {
+		return "", newReadBodyError(err, "failed to read request body")
+	}
+	request.Body = io.NopCloser(bytes.NewReader(body))
+
+	keyID := request.Header.Get(headerCompletionSignatureKeyID)
+	message := completionSignatureMessage(request, timestamp, nonce, body)
+	for _, verifier := range v.verifiers {
+		if verifier.KeyID() == keyID && verifier.Verify(message, signature) {
+			return keyID + "\n" + nonce, nil
+		}
+	}
+	return "", newUnauthorizedCompletionError("invalid completion signature")
+}
+
+// claim records a nonce, reporting false if it was already recorded. Nonces are remembered for twice the max age,
+// covering the period their signature timestamp is accepted in.
+func (v *completionSignatureVerifier) claim(nonce string) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	now := v.now()
+	for v.nonceOrder.Len() > 0 {
+		oldest := v.nonceOrder.Front().Value.(*signatureNonce)
+		if now.Before(oldest.expiresAt) {
+			break
+		}
+		delete(v.nonces, oldest.nonce)
+		v.nonceOrder.Remove(v.nonceOrder.Front())
+	}
+	if _, ok := v.nonces[nonce]; ok {
+		return false
+	}
+	v.nonces[nonce] = v.nonceOrder.PushBack(&signatureNonce{nonce: nonce, expiresAt: now.Add(2 * v.maxAge)})
+	return true
+}
+
+// release forgets a claimed nonce.
+func (v *completionSignatureVerifier) release(nonce string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if element, ok := v.nonces[nonce]; ok {
+		v.nonceOrder.Remove(element)
+		delete(v.nonces, nonce)
+	}
+}
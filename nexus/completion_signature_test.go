@@ -0,0 +1,226 @@
+package nexus
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newSignedCompletionRequest(t *testing.T, signer CompletionSigner, options CompletionRequestOptions) *http.Request {
+	completion, err := NewOperationCompletionSuccessful(typedResult{Value: "hello"})
+	require.NoError(t, err)
+	options.Signer = signer
+	request, err := NewCompletionHTTPRequestWithOptions(context.Background(), "http://localhost/callback", completion, options)
+	require.NoError(t, err)
+	return request
+}
+
+func newSignatureVerifyingHandler(verifiers ...CompletionVerifier) (http.Handler, *countingCompletionHandler) {
+	completionHandler := &countingCompletionHandler{}
+	return NewCompletionHTTPHandler(CompletionHandlerOptions{
+		Handler:            completionHandler,
+		SignatureVerifiers: verifiers,
+	}), completionHandler
+}
+
+func TestCompletionSignature_HMAC(t *testing.T) {
+	key := HMACCompletionKey{ID: "k1", Secret: []byte("secret")}
+	handler, completionHandler := newSignatureVerifyingHandler(key)
+
+	request := newSignedCompletionRequest(t, key, CompletionRequestOptions{})
+	require.Equal(t, "k1", request.Header.Get(headerCompletionSignatureKeyID))
+	require.Equal(t, http.StatusOK, serveCompletion(handler, request))
+	require.Equal(t, int32(1), completionHandler.calls.Load())
+
+	unsigned, err := NewCompletionHTTPRequest(context.Background(), "http://localhost/callback", &OperationCompletionSuccessful{
+		Body: bytes.NewReader([]byte("success")),
+	})
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, unsigned)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	require.Equal(t, "false", recorder.Header().Get(headerRetryable))
+
+	forged := newSignedCompletionRequest(t, HMACCompletionKey{ID: "k1", Secret: []byte("guess")}, CompletionRequestOptions{})
+	require.Equal(t, http.StatusUnauthorized, serveCompletion(handler, forged))
+	require.Equal(t, int32(1), completionHandler.calls.Load())
+}
+
+func TestCompletionSignature_Tampered(t *testing.T) {
+	key := HMACCompletionKey{ID: "k1", Secret: []byte("secret")}
+	handler, completionHandler := newSignatureVerifyingHandler(key)
+
+	tamperedBody := newSignedCompletionRequest(t, key, CompletionRequestOptions{})
+	tamperedBody.Body = io.NopCloser(bytes.NewReader([]byte(`{"value":"evil"}`)))
+	require.Equal(t, http.StatusUnauthorized, serveCompletion(handler, tamperedBody))
+
+	tamperedState := newSignedCompletionRequest(t, key, CompletionRequestOptions{})
+	tamperedState.Header.Set(headerOperationState, string(OperationStateCanceled))
+	require.Equal(t, http.StatusUnauthorized, serveCompletion(handler, tamperedState))
+
+	tamperedID := newSignedCompletionRequest(t, key, CompletionRequestOptions{})
+	tamperedID.Header.Set(headerCompletionID, "other")
+	require.Equal(t, http.StatusUnauthorized, serveCompletion(handler, tamperedID))
+
+	tamperedMethod := newSignedCompletionRequest(t, key, CompletionRequestOptions{})
+	tamperedMethod.Method = "PUT"
+	require.Equal(t, http.StatusUnauthorized, serveCompletion(handler, tamperedMethod))
+
+	tamperedPath := newSignedCompletionRequest(t, key, CompletionRequestOptions{})
+	tamperedPath.URL.Path = "/other"
+	require.Equal(t, http.StatusUnauthorized, serveCompletion(handler, tamperedPath))
+
+	tamperedQuery := newSignedCompletionRequest(t, key, CompletionRequestOptions{})
+	tamperedQuery.URL.RawQuery = "token=other"
+	require.Equal(t, http.StatusUnauthorized, serveCompletion(handler, tamperedQuery))
+
+	tamperedNonce := newSignedCompletionRequest(t, key, CompletionRequestOptions{})
+	tamperedNonce.Header.Set(headerCompletionSignatureNonce, "other")
+	require.Equal(t, http.StatusUnauthorized, serveCompletion(handler, tamperedNonce))
+
+	require.Equal(t, int32(0), completionHandler.calls.Load())
+}
+
+// replayRequest returns a copy of a request with a fresh body, as captured and resent by an attacker.
+func replayRequest(t *testing.T, request *http.Request) *http.Request {
+	replay := request.Clone(context.Background())
+	body, err := request.GetBody()
+	require.NoError(t, err)
+	replay.Body = body
+	return replay
+}
+
+// flakyCompletionHandler fails until fail is cleared.
+type flakyCompletionHandler struct {
+	countingCompletionHandler
+	fail bool
+}
+
+func (h *flakyCompletionHandler) CompleteOperation(ctx context.Context, request *CompletionRequest) error {
+	if h.fail {
+		return errors.New("transient")
+	}
+	return h.countingCompletionHandler.CompleteOperation(ctx, request)
+}
+
+func TestCompletionSignature_Replay(t *testing.T) {
+	key := HMACCompletionKey{ID: "k1", Secret: []byte("secret")}
+	completionHandler := &flakyCompletionHandler{fail: true}
+	handler := NewCompletionHTTPHandler(CompletionHandlerOptions{
+		Handler:            completionHandler,
+		SignatureVerifiers: []CompletionVerifier{key},
+	})
+	verifier := handler.(*completionHTTPHandler).signatures
+
+	request := newSignedCompletionRequest(t, key, CompletionRequestOptions{})
+	// Requests that failed to be processed may be retried.
+	require.Equal(t, http.StatusInternalServerError, serveCompletion(handler, replayRequest(t, request)))
+	completionHandler.fail = false
+	require.Equal(t, http.StatusOK, serveCompletion(handler, replayRequest(t, request)))
+	require.Equal(t, http.StatusUnauthorized, serveCompletion(handler, replayRequest(t, request)))
+	require.Equal(t, int32(1), completionHandler.calls.Load())
+
+	// A new signature of the same completion is accepted.
+	require.Equal(t, http.StatusOK, serveCompletion(handler, newSignedCompletionRequest(t, key, CompletionRequestOptions{})))
+	require.Equal(t, int32(2), completionHandler.calls.Load())
+
+	// Nonces are forgotten once their signatures are stale.
+	verifier.now = func() time.Time { return time.Now().Add(11 * time.Minute) }
+	require.True(t, verifier.claim("k1\nother"))
+	require.Equal(t, 1, verifier.nonceOrder.Len())
+}
+
+func TestCompletionSignature_ReplayOfProcessedCompletion(t *testing.T) {
+	key := HMACCompletionKey{ID: "k1", Secret: []byte("secret")}
+	completionHandler := &countingCompletionHandler{}
+	handler := NewCompletionHTTPHandler(CompletionHandlerOptions{
+		Handler:            completionHandler,
+		SignatureVerifiers: []CompletionVerifier{key},
+		CompletionStore:    NewMemoryCompletionStore(MemoryCompletionStoreOptions{}),
+	})
+
+	// A sender retrying after a lost response is acknowledged rather than rejected.
+	request := newSignedCompletionRequest(t, key, CompletionRequestOptions{CompletionID: "a"})
+	require.Equal(t, http.StatusOK, serveCompletion(handler, replayRequest(t, request)))
+	require.Equal(t, http.StatusOK, serveCompletion(handler, replayRequest(t, request)))
+	require.Equal(t, int32(1), completionHandler.calls.Load())
+}
+
+func TestCompletionSignature_MaxBodySize(t *testing.T) {
+	key := HMACCompletionKey{ID: "k1", Secret: []byte("secret")}
+	completionHandler := &countingCompletionHandler{}
+	handler := NewCompletionHTTPHandler(CompletionHandlerOptions{
+		Handler:                 completionHandler,
+		SignatureVerifiers:      []CompletionVerifier{key},
+		MaxDecompressedBodySize: 8,
+	})
+
+	require.Equal(t, http.StatusRequestEntityTooLarge, serveCompletion(handler, newSignedCompletionRequest(t, key, CompletionRequestOptions{})))
+	require.Equal(t, int32(0), completionHandler.calls.Load())
+}
+
+func TestCompletionSignature_Freshness(t *testing.T) {
+	key := HMACCompletionKey{ID: "k1", Secret: []byte("secret")}
+	handler, completionHandler := newSignatureVerifyingHandler(key)
+	verifier := handler.(*completionHTTPHandler).signatures
+
+	verifier.now = func() time.Time { return time.Now().Add(10 * time.Minute) }
+	require.Equal(t, http.StatusUnauthorized, serveCompletion(handler, newSignedCompletionRequest(t, key, CompletionRequestOptions{})))
+	verifier.now = func() time.Time { return time.Now().Add(-10 * time.Minute) }
+	require.Equal(t, http.StatusUnauthorized, serveCompletion(handler, newSignedCompletionRequest(t, key, CompletionRequestOptions{})))
+	verifier.now = func() time.Time { return time.Now().Add(time.Minute) }
+	require.Equal(t, http.StatusOK, serveCompletion(handler, newSignedCompletionRequest(t, key, CompletionRequestOptions{})))
+	require.Equal(t, int32(1), completionHandler.calls.Load())
+}
+
+func TestCompletionSignature_Ed25519KeyRotation(t *testing.T) {
+	oldPublic, oldPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	newPublic, newPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	handler, completionHandler := newSignatureVerifyingHandler(
+		Ed25519CompletionVerifier{ID: "old", PublicKey: oldPublic},
+		Ed25519CompletionVerifier{ID: "new", PublicKey: newPublic},
+	)
+	require.Equal(t, http.StatusOK, serveCompletion(handler, newSignedCompletionRequest(t, Ed25519CompletionSigner{ID: "old", PrivateKey: oldPrivate}, CompletionRequestOptions{})))
+	require.Equal(t, http.StatusOK, serveCompletion(handler, newSignedCompletionRequest(t, Ed25519CompletionSigner{ID: "new", PrivateKey: newPrivate}, CompletionRequestOptions{})))
+	// Signed with the old key under the ID of the new key.
+	require.Equal(t, http.StatusUnauthorized, serveCompletion(handler, newSignedCompletionRequest(t, Ed25519CompletionSigner{ID: "new", PrivateKey: oldPrivate}, CompletionRequestOptions{})))
+	require.Equal(t, int32(2), completionHandler.calls.Load())
+
+	retired, _ := newSignatureVerifyingHandler(Ed25519CompletionVerifier{ID: "new", PublicKey: newPublic})
+	require.Equal(t, http.StatusUnauthorized, serveCompletion(retired, newSignedCompletionRequest(t, Ed25519CompletionSigner{ID: "old", PrivateKey: oldPrivate}, CompletionRequestOptions{})))
+}
+
+func TestCompletionSignature_Compressed(t *testing.T) {
+	key := HMACCompletionKey{ID: "k1", Secret: []byte("secret")}
+	handler, completionHandler := newSignatureVerifyingHandler(key)
+	request := newSignedCompletionRequest(t, key, CompletionRequestOptions{Compressor: GzipCompressor{}, CompressionThreshold: 1})
+	require.Equal(t, "gzip", request.Header.Get(headerContentEncoding))
+	require.Equal(t, http.StatusOK, serveCompletion(handler, request))
+	require.Equal(t, int32(1), completionHandler.calls.Load())
+}
+
+func TestCompletionSignature_BodyAvailableToHandler(t *testing.T) {
+	key := HMACCompletionKey{ID: "k1", Secret: []byte("secret")}
+	var received TypedCompletion[typedResult]
+	handler := NewCompletionHTTPHandler(CompletionHandlerOptions{
+		Handler: NewTypedCompletionHandler(func(ctx context.Context, completion TypedCompletion[typedResult]) error {
+			received = completion
+			return nil
+		}),
+		SignatureVerifiers: []CompletionVerifier{key},
+	})
+	require.Equal(t, http.StatusOK, serveCompletion(handler, newSignedCompletionRequest(t, key, CompletionRequestOptions{})))
+	require.Equal(t, typedResult{Value: "hello"}, received.(*CompletionSucceeded[typedResult]).Result)
+}
@@ -0,0 +1,277 @@
+package nexus
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nexus-rpc/sdk-go/nexus/internal/zstd"
+)
+
+const (
+	headerAcceptEncoding  = "Accept-Encoding"
+	headerContentEncoding = "Content-Encoding"
+	headerContentLength   = "Content-Length"
+	headerVary            = "Vary"
+)
+
+// A Compressor compresses and decompresses HTTP bodies in a single content coding.
+//
+// [GzipCompressor] and [ZstdCompressor] are provided by this package. Other codings, e.g. br, can be supported by
+// implementing this interface on top of a third party library.
+type Compressor interface {
+	// Encoding is the content coding token used in Content-Encoding and Accept-Encoding headers, e.g. gzip.
+	Encoding() string
+	// NewWriter returns a writer that compresses data written to it into w. The writer is closed when all data has been
+	// written and must flush any buffered data to w on close.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	// NewReader returns a reader that decompresses data read from r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// GzipCompressor is a [Compressor] for the gzip content coding.
+type GzipCompressor struct {
+	// Compression level as defined in the compress/gzip package.
+	// Defaults to gzip.DefaultCompression.
+	Level int
+}
+
+// Encoding implements the Compressor interface.
+func (GzipCompressor) Encoding() string {
+	return "gzip"
+}
+
+// NewWriter implements the Compressor interface.
+func (c GzipCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	level := c.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	return gzip.NewWriterLevel(w, level)
+}
+
+// NewReader implements the Compressor interface.
+func (GzipCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// ZstdCompressor is a [Compressor] for the zstd content coding, implemented without external dependencies. It
+// compresses faster but less than the reference implementation at its lowest level, and decompresses frames with
+// windows of up to 128 MiB.
+type ZstdCompressor struct{}
+
+// Encoding implements the Compressor interface.
+func (ZstdCompressor) Encoding() string {
+	return "zstd"
+}
+
+// NewWriter implements the Compressor interface.
+func (ZstdCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w), nil
+}
+
+// NewReader implements the Compressor interface.
+func (ZstdCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return zstd.NewReader(r), nil
+}
+
+func findCompressor(compressors []Compressor, encoding string) Compressor {
+	for _, compressor := range compressors {
+		if strings.EqualFold(compressor.Encoding(), encoding) {
+			return compressor
+		}
+	}
+	return nil
+}
+
+// negotiateCompressor selects the compressor with the highest quality in an Accept-Encoding header. Ties are broken by
+// the order of compressors.
+func negotiateCompressor(header http.Header, compressors []Compressor) Compressor {
+	qualities := make(map[string]float64)
+	for _, value := range header.Values(headerAcceptEncoding) {
+		for _, entry := range strings.Split(value, ",") {
+			coding, params, _ := strings.Cut(entry, ";")
+			coding = strings.ToLower(strings.TrimSpace(coding))
+			if coding == "" {
+				continue
+			}
+			quality := 1.0
+			if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+				var err error
+				if quality, err = strconv.ParseFloat(q, 64); err != nil {
+					continue
+				}
+			}
+			qualities[coding] = quality
+		}
+	}
+	var best Compressor
+	bestQuality := 0.0
+	for _, compressor := range compressors {
+		quality, ok := qualities[strings.ToLower(compressor.Encoding())]
+		if !ok {
+			quality = qualities["*"]
+		}
+		if quality > bestQuality {
+			best, bestQuality = compressor, quality
+		}
+	}
+	return best
+}
+
+// defaultRequestCompressionThreshold leaves small request bodies, for which compression saves little or even grows the
+// body, uncompressed.
+const defaultRequestCompressionThreshold = 1024
+
+// compressRequestBody replaces the body of request with its compressed form if it is at least threshold bytes long.
+func compressRequestBody(request *http.Request, compressor Compressor, threshold int) error {
+	if compressor == nil || threshold < 0 || request.Body == nil || request.Body == http.NoBody {
+		return nil
+	}
+	if request.Header.Get(headerContentEncoding) != "" {
+		// Already encoded by the caller.
+		return nil
+	}
+	b, err := io.ReadAll(request.Body)
+	request.Body.Close()
+	if err != nil {
+		return err
+	}
+	if len(b) >= threshold && len(b) > 0 {
+		var buf bytes.Buffer
+		w, err := compressor.NewWriter(&buf)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		b = buf.Bytes()
+		request.Header.Set(headerContentEncoding, compressor.Encoding())
+	}
+	request.ContentLength = int64(len(b))
+	request.Body = io.NopCloser(bytes.NewReader(b))
+	request.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(b)), nil
+	}
+	return nil
+}
+
+type decompressedBody struct {
+	io.ReadCloser
+	underlying io.Closer
+}
+
+func (b *decompressedBody) Close() error {
+	return errors.Join(b.ReadCloser.Close(), b.underlying.Close())
+}
+
+// decompressResponseBody wraps the body of a response encoded with one of the given compressors with a decompressing
+// reader that fails with an [http.MaxBytesError] when reading more than limit bytes. Responses with unknown encodings
+// are left untouched.
+func decompressResponseBody(response *http.Response, compressors []Compressor, limit int64) error {
+	encoding := response.Header.Get(headerContentEncoding)
+	if encoding == "" {
+		return nil
+	}
+	compressor := findCompressor(compressors, encoding)
+	if compressor == nil {
+		return nil
+	}
+	reader, err := compressor.NewReader(response.Body)
+	if err != nil {
+		response.Body.Close()
+		return fmt.Errorf("failed to decompress response body: %w", err)
+	}
+	response.Body = &decompressedBody{ReadCloser: http.MaxBytesReader(nil, reader, limit), underlying: response.Body}
+	response.Header.Del(headerContentEncoding)
+	response.Header.Del(headerContentLength)
+	response.ContentLength = -1
+	response.Uncompressed = true
+	return nil
+}
+
+// defaultMaxDecompressedBodySize bounds the memory used by handlers and clients that buffer decompressed bodies, e.g.
+// for validation, against small compressed bodies that expand without bound.
+const defaultMaxDecompressedBodySize = 16 << 20
+
+// decompress wraps a handler function, transparently decompressing request bodies encoded with one of the handler's
+// compressors, up to the handler's max decompressed body size. Requests with unsupported encodings are rejected with a
+// 415 status.
+func (h *baseHTTPHandler) decompress(handler http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		encoding := request.Header.Get(headerContentEncoding)
+		if encoding == "" || strings.EqualFold(encoding, "identity") {
+			handler(writer, request)
+			return
+		}
+		compressor := findCompressor(h.compressors, encoding)
+		if compressor == nil {
+			h.writeFailure(writer, &HandlerError{
+				StatusCode: http.StatusUnsupportedMediaType,
+				Failure:    &Failure{Message: fmt.Sprintf("unsupported content encoding: %q", encoding)},
+			})
+			return
+		}
+		reader, err := compressor.NewReader(request.Body)
+		if err != nil {
+			h.writeFailure(writer, newBadRequestError("failed to decompress request body"))
+			return
+		}
+		defer reader.Close()
+		request.Body = http.MaxBytesReader(writer, reader, h.maxDecompressedBodySize)
+		request.Header.Del(headerContentEncoding)
+		request.Header.Del(headerContentLength)
+		request.ContentLength = -1
+		handler(writer, request)
+	}
+}
+
+// writeCompressed writes body to writer, compressing it with the compressor negotiated from the request's
+// Accept-Encoding header if it is at least threshold bytes long. Compression is disabled for non positive thresholds.
+func (h *baseHTTPHandler) writeCompressed(writer http.ResponseWriter, request *http.Request, threshold int, body io.Reader) error {
+	var compressor Compressor
+	if threshold > 0 && writer.Header().Get(headerContentEncoding) == "" {
+		compressor = negotiateCompressor(request.Header, h.compressors)
+	}
+	if compressor == nil {
+		_, err := io.Copy(writer, body)
+		return err
+	}
+
+	prefix := make([]byte, threshold)
+	n, err := io.ReadFull(body, prefix)
+	prefix = prefix[:n]
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		// Below the threshold.
+		_, err = writer.Write(prefix)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	w, err := compressor.NewWriter(writer)
+	if err != nil {
+		return err
+	}
+	header := writer.Header()
+	header.Set(headerContentEncoding, compressor.Encoding())
+	header.Add(headerVary, headerAcceptEncoding)
+	header.Del(headerContentLength)
+	if _, err := w.Write(prefix); err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, body); err != nil {
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,248 @@
+package nexus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type echoBodyHandler struct {
+	UnimplementedHandler
+}
+
+func (h *echoBodyHandler) StartOperation(ctx context.Context, request *StartOperationRequest) (OperationResponse, error) {
+	if request.HTTPRequest.Header.Get(headerContentEncoding) != "" {
+		return nil, newBadRequestError("expected Content-Encoding header to be removed")
+	}
+	return &OperationResponseSync{Body: request.HTTPRequest.Body}, nil
+}
+
+// encodingRecorder records the Content-Encoding of requests and responses as they go over the wire.
+type encodingRecorder struct {
+	mu                sync.Mutex
+	requestEncodings  []string
+	responseEncodings []string
+}
+
+func (r *encodingRecorder) do(request *http.Request) (*http.Response, error) {
+	response, err := http.DefaultClient.Do(request)
+	if err == nil {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.requestEncodings = append(r.requestEncodings, request.Header.Get(headerContentEncoding))
+		r.responseEncodings = append(r.responseEncodings, response.Header.Get(headerContentEncoding))
+	}
+	return response, err
+}
+
+func TestCompression(t *testing.T) {
+	recorder := &encodingRecorder{}
+	ctx, client, teardown := setupCustom(t, HandlerOptions{
+		Handler:              &echoBodyHandler{},
+		CompressionThreshold: 1024,
+	}, ClientOptions{
+		HTTPCaller:                  recorder.do,
+		Compressors:                 []Compressor{GzipCompressor{}},
+		RequestCompressionThreshold: 1024,
+	})
+	defer teardown()
+
+	for _, size := range []int{10, 1024, 1 << 20} {
+		input := strings.Repeat("x", size)
+		result, err := client.StartOperation(ctx, StartOperationOptions{Operation: "foo", Body: strings.NewReader(input)})
+		require.NoError(t, err)
+		body, err := io.ReadAll(result.Successful.Body)
+		require.NoError(t, err)
+		require.NoError(t, result.Successful.Body.Close())
+		require.Equal(t, input, string(body))
+		require.Empty(t, result.Successful.Header.Get(headerContentEncoding))
+	}
+	require.Equal(t, []string{"", "gzip", "gzip"}, recorder.requestEncodings)
+	require.Equal(t, []string{"", "gzip", "gzip"}, recorder.responseEncodings)
+}
+
+func TestCompression_Zstd(t *testing.T) {
+	recorder := &encodingRecorder{}
+	ctx, client, teardown := setupCustom(t, HandlerOptions{
+		Handler:              &echoBodyHandler{},
+		CompressionThreshold: 1024,
+	}, ClientOptions{
+		HTTPCaller:  recorder.do,
+		Compressors: []Compressor{ZstdCompressor{}},
+	})
+	defer teardown()
+
+	input := strings.Repeat(`{"key": "value"}`, 1<<16)
+	result, err := client.StartOperation(ctx, StartOperationOptions{Operation: "foo", Body: strings.NewReader(input)})
+	require.NoError(t, err)
+	body, err := io.ReadAll(result.Successful.Body)
+	require.NoError(t, err)
+	require.NoError(t, result.Successful.Body.Close())
+	require.Equal(t, input, string(body))
+	require.Equal(t, []string{"zstd"}, recorder.requestEncodings)
+	require.Equal(t, []string{"zstd"}, recorder.responseEncodings)
+}
+
+func TestCompression_DefaultRequestThreshold(t *testing.T) {
+	recorder := &encodingRecorder{}
+	ctx, client, teardown := setupCustom(t, HandlerOptions{Handler: &echoBodyHandler{}}, ClientOptions{
+		HTTPCaller:  recorder.do,
+		Compressors: []Compressor{GzipCompressor{}},
+	})
+	defer teardown()
+
+	for _, size := range []int{10, 1024} {
+		result, err := client.StartOperation(ctx, StartOperationOptions{
+			Operation: "foo",
+			Body:      strings.NewReader(strings.Repeat("x", size)),
+		})
+		require.NoError(t, err)
+		require.NoError(t, result.Successful.Body.Close())
+	}
+	require.Equal(t, []string{"", "gzip"}, recorder.requestEncodings)
+
+	completion, err := NewOperationCompletionSuccessful([]byte("success"))
+	require.NoError(t, err)
+	request, err := NewCompletionHTTPRequestWithOptions(context.Background(), "http://localhost/callback", completion,
+		CompletionRequestOptions{Compressor: GzipCompressor{}})
+	require.NoError(t, err)
+	require.Empty(t, request.Header.Get(headerContentEncoding))
+}
+
+func TestCompression_UnsupportedEncoding(t *testing.T) {
+	ctx, client, teardown := setup(t, &echoBodyHandler{})
+	defer teardown()
+
+	_, err := client.StartOperation(ctx, StartOperationOptions{
+		Operation: "foo",
+		Header:    http.Header{headerContentEncoding: []string{"br"}},
+		Body:      strings.NewReader("input"),
+	})
+	var unexpectedResponseError *UnexpectedResponseError
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusUnsupportedMediaType, unexpectedResponseError.Response.StatusCode)
+	require.Equal(t, `unsupported content encoding: "br"`, unexpectedResponseError.Failure.Message)
+}
+
+func TestCompression_Completion(t *testing.T) {
+	ctx, callbackURL, teardown := setupForCompletion(t, &successfulCompletionHandler{})
+	defer teardown()
+
+	request, err := NewCompletionHTTPRequestWithOptions(ctx, callbackURL, &OperationCompletionSuccessful{
+		Header: http.Header{"foo": []string{"bar"}},
+		Body:   bytes.NewReader([]byte("success")),
+	}, CompletionRequestOptions{Compressor: GzipCompressor{}, CompressionThreshold: 1})
+	require.NoError(t, err)
+	require.Equal(t, "gzip", request.Header.Get(headerContentEncoding))
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	_, err = io.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+}
+
+type readAllHandler struct {
+	UnimplementedHandler
+}
+
+func (h *readAllHandler) StartOperation(ctx context.Context, request *StartOperationRequest) (OperationResponse, error) {
+	body, err := io.ReadAll(request.HTTPRequest.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &OperationResponseSync{Body: bytes.NewReader(body)}, nil
+}
+
+func gzipBytes(t *testing.T, b []byte) []byte {
+	var buf bytes.Buffer
+	w, err := GzipCompressor{}.NewWriter(&buf)
+	require.NoError(t, err)
+	_, err = w.Write(b)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestCompression_MaxDecompressedBodySize(t *testing.T) {
+	ctx, client, teardown := setupCustom(t, HandlerOptions{
+		Handler:                 &readAllHandler{},
+		MaxDecompressedBodySize: 1024,
+	}, ClientOptions{})
+	defer teardown()
+
+	gzipHeader := http.Header{headerContentEncoding: []string{"gzip"}}
+	result, err := client.StartOperation(ctx, StartOperationOptions{
+		Operation: "foo",
+		Header:    gzipHeader,
+		Body:      bytes.NewReader(gzipBytes(t, bytes.Repeat([]byte("x"), 1024))),
+	})
+	require.NoError(t, err)
+	require.NoError(t, result.Successful.Body.Close())
+
+	_, err = client.StartOperation(ctx, StartOperationOptions{
+		Operation: "foo",
+		Header:    gzipHeader,
+		Body:      bytes.NewReader(gzipBytes(t, bytes.Repeat([]byte("x"), 1<<20))),
+	})
+	var unexpectedResponseError *UnexpectedResponseError
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusRequestEntityTooLarge, unexpectedResponseError.Response.StatusCode)
+}
+
+func TestCompression_ClientMaxDecompressedBodySize(t *testing.T) {
+	ctx, client, teardown := setupCustom(t, HandlerOptions{
+		Handler:              &echoBodyHandler{},
+		CompressionThreshold: 1,
+	}, ClientOptions{
+		Compressors:             []Compressor{GzipCompressor{}},
+		MaxDecompressedBodySize: 1024,
+	})
+	defer teardown()
+
+	for _, size := range []int{1024, 1 << 20} {
+		result, err := client.StartOperation(ctx, StartOperationOptions{
+			Operation: "foo",
+			Body:      bytes.NewReader(bytes.Repeat([]byte("x"), size)),
+		})
+		require.NoError(t, err)
+		body, err := io.ReadAll(result.Successful.Body)
+		require.NoError(t, result.Successful.Body.Close())
+		if size <= 1024 {
+			require.NoError(t, err)
+			require.Len(t, body, size)
+		} else {
+			var maxBytesErr *http.MaxBytesError
+			require.ErrorAs(t, err, &maxBytesErr)
+			require.Equal(t, int64(1024), maxBytesErr.Limit)
+		}
+	}
+}
+
+func TestCompression_CompletionMaxDecompressedBodySize(t *testing.T) {
+	server := httptest.NewServer(NewCompletionHTTPHandler(CompletionHandlerOptions{
+		Handler:                 &successfulCompletionHandler{},
+		MaxDecompressedBodySize: 1024,
+	}))
+	defer server.Close()
+
+	failure, err := json.Marshal(Failure{Message: strings.Repeat("x", 1<<20)})
+	require.NoError(t, err)
+	request, err := http.NewRequest("POST", server.URL, bytes.NewReader(gzipBytes(t, failure)))
+	require.NoError(t, err)
+	request.Header.Set(headerOperationState, string(OperationStateFailed))
+	request.Header.Set(headerContentType, contentTypeJSON)
+	request.Header.Set(headerContentEncoding, "gzip")
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusRequestEntityTooLarge, response.StatusCode)
+}
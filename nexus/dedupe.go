@@ -0,0 +1,161 @@
+package nexus
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Header identifying a completion across delivery attempts.
+const headerCompletionID = "Nexus-Completion-Id"
+
+// A CompletionStore remembers processed completions for deduplicating completion deliveries. See
+// [CompletionHandlerOptions.CompletionStore].
+//
+// Implementations backed by shared storage, e.g. a database or cache, deduplicate completions across handler replicas.
+// [NewMemoryCompletionStore] provides an in-process implementation.
+//
+// Seen and Remember are separate calls and concurrent deliveries of a completion are only detected within a single
+// handler. Deliveries of the same completion racing on different replicas may both be processed, completion handlers
+// must still tolerate rare duplicates.
+type CompletionStore interface {
+	// Seen reports whether a completion key was remembered and has not expired.
+	Seen(ctx context.Context, key string) (bool, error)
+	// Remember records a completion key for the given duration.
+	Remember(ctx context.Context, key string, ttl time.Duration) error
+}
+
+// MemoryCompletionStoreOptions are options for [NewMemoryCompletionStore].
+type MemoryCompletionStoreOptions struct {
+	// Maximum number of keys to remember. When exceeded, the oldest keys are evicted first.
+	//
+	// Defaults to 10000.
+	MaxEntries int
+}
+
+// MemoryCompletionStore is an in-memory [CompletionStore].
+type MemoryCompletionStore struct {
+	options MemoryCompletionStoreOptions
+	now     func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// Remembered keys, oldest first.
+	order *list.List
+}
+
+type memoryCompletionStoreEntry struct {
+	key       string
+	expiresAt time.Time
+}
+
+// NewMemoryCompletionStore constructs a new [MemoryCompletionStore] from the given options.
+func NewMemoryCompletionStore(options MemoryCompletionStoreOptions) *MemoryCompletionStore {
+	if options.MaxEntries <= 0 {
+		options.MaxEntries = 10000
+	}
+	return &MemoryCompletionStore{
+		options: options,
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Seen implements the CompletionStore interface.
+func (s *MemoryCompletionStore) Seen(ctx context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	element, ok := s.entries[key]
+	return ok && s.now().Before(element.Value.(*memoryCompletionStoreEntry).expiresAt), nil
+}
+
+// Remember implements the CompletionStore interface.
+func (s *MemoryCompletionStore) Remember(ctx context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if element, ok := s.entries[key]; ok {
+		s.order.Remove(element)
+	}
+	s.entries[key] = s.order.PushBack(&memoryCompletionStoreEntry{key: key, expiresAt: now.Add(ttl)})
+	for s.order.Len() > 0 {
+		oldest := s.order.Front().Value.(*memoryCompletionStoreEntry)
+		if s.order.Len() <= s.options.MaxEntries && now.Before(oldest.expiresAt) {
+			break
+		}
+		delete(s.entries, oldest.key)
+		s.order.Remove(s.order.Front())
+	}
+	return nil
+}
+
+// completionDeduplicator tracks completions being processed and consults a CompletionStore for processed ones.
+type completionDeduplicator struct {
+	store CompletionStore
+	ttl   time.Duration
+
+	mu       sync.Mutex
+	inFlight map[string]struct{}
+}
+
+// completionKey derives the deduplication key of a request from its completion ID and callback token.
+// Returns an empty key for requests without a completion ID.
+func completionKey(request *http.Request) string {
+	id := request.Header.Get(headerCompletionID)
+	if id == "" {
+		return ""
+	}
+	h := sha256.New()
+	h.Write([]byte(request.URL.Query().Get(queryCallbackToken)))
+	h.Write([]byte{0})
+	h.Write([]byte(id))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// start marks a completion as in-flight and reports whether it was already processed.
+// Concurrent deliveries of the same completion are rejected with a retryable 409 status. finish must be called if
+// start returns a non empty key and no error.
+func (d *completionDeduplicator) start(ctx context.Context, request *http.Request) (key string, duplicate bool, err error) {
+	key = completionKey(request)
+	if key == "" {
+		return "", false, nil
+	}
+	d.mu.Lock()
+	if _, ok := d.inFlight[key]; ok {
+		d.mu.Unlock()
+		return "", false, &HandlerError{
+			StatusCode:    http.StatusConflict,
+			Failure:       &Failure{Message: "completion is already being processed"},
+			RetryBehavior: RetryBehaviorRetryable,
+		}
+	}
+	d.inFlight[key] = struct{}{}
+	d.mu.Unlock()
+
+	seen, err := d.store.Seen(ctx, key)
+	if err != nil || seen {
+		d.release(key)
+		return "", seen, err
+	}
+	return key, false, nil
+}
+
+// finish remembers a completion if it was processed successfully and releases it.
+func (d *completionDeduplicator) finish(ctx context.Context, key string, processed bool) error {
+	defer d.release(key)
+	if !processed {
+		return nil
+	}
+	return d.store.Remember(ctx, key, d.ttl)
+}
+
+func (d *completionDeduplicator) release(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.inFlight, key)
+}
@@ -0,0 +1,160 @@
+package nexus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type countingCompletionHandler struct {
+	calls   atomic.Int32
+	fail    atomic.Bool
+	started chan struct{}
+	block   chan struct{}
+}
+
+func (h *countingCompletionHandler) CompleteOperation(ctx context.Context, completion *CompletionRequest) error {
+	h.calls.Add(1)
+	if h.started != nil {
+		h.started <- struct{}{}
+		<-h.block
+	}
+	if h.fail.Load() {
+		return errors.New("failed")
+	}
+	return nil
+}
+
+func newDedupeRequest(t *testing.T, callbackURL string, id string) *http.Request {
+	request, err := NewCompletionHTTPRequestWithOptions(context.Background(), callbackURL, &OperationCompletionSuccessful{
+		Body: bytes.NewReader([]byte("success")),
+	}, CompletionRequestOptions{CompletionID: id})
+	require.NoError(t, err)
+	return request
+}
+
+func serveCompletion(handler http.Handler, request *http.Request) int {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder.Code
+}
+
+func TestNewCompletionHTTPRequest_CompletionID(t *testing.T) {
+	// Completions are sent without an ID unless one is given, identical completions of different operations must not
+	// be mistaken for duplicates.
+	request, err := NewCompletionHTTPRequest(context.Background(), "http://localhost/callback", &OperationCompletionSuccessful{
+		Body: bytes.NewReader([]byte("result")),
+	})
+	require.NoError(t, err)
+	require.Empty(t, request.Header.Get(headerCompletionID))
+
+	explicit, err := NewCompletionHTTPRequest(context.Background(), "http://localhost/callback", &OperationCompletionSuccessful{
+		Header: http.Header{headerCompletionID: []string{"from-header"}},
+		Body:   bytes.NewReader(nil),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "from-header", explicit.Header.Get(headerCompletionID))
+
+	require.Equal(t, "stable", newDedupeRequest(t, "http://localhost/callback", "stable").Header.Get(headerCompletionID))
+}
+
+func TestCompletionDeduplication(t *testing.T) {
+	completionHandler := &countingCompletionHandler{}
+	handler := NewCompletionHTTPHandler(CompletionHandlerOptions{
+		Handler:         completionHandler,
+		CompletionStore: NewMemoryCompletionStore(MemoryCompletionStoreOptions{}),
+	})
+
+	require.Equal(t, http.StatusOK, serveCompletion(handler, newDedupeRequest(t, "http://localhost/callback", "a")))
+	require.Equal(t, http.StatusOK, serveCompletion(handler, newDedupeRequest(t, "http://localhost/callback", "a")))
+	require.Equal(t, int32(1), completionHandler.calls.Load())
+
+	// Same ID, different callback token.
+	require.Equal(t, http.StatusOK, serveCompletion(handler, newDedupeRequest(t, "http://localhost/callback?nexus-callback-token=x", "a")))
+	require.Equal(t, int32(2), completionHandler.calls.Load())
+
+	// Requests without an ID are not deduplicated.
+	for i := 0; i < 2; i++ {
+		require.Equal(t, http.StatusOK, serveCompletion(handler, newDedupeRequest(t, "http://localhost/callback", "")))
+	}
+	require.Equal(t, int32(4), completionHandler.calls.Load())
+}
+
+func TestCompletionDeduplication_FailedCompletionsAreRetried(t *testing.T) {
+	completionHandler := &countingCompletionHandler{}
+	completionHandler.fail.Store(true)
+	handler := NewCompletionHTTPHandler(CompletionHandlerOptions{
+		Handler:         completionHandler,
+		CompletionStore: NewMemoryCompletionStore(MemoryCompletionStoreOptions{}),
+	})
+
+	require.Equal(t, http.StatusInternalServerError, serveCompletion(handler, newDedupeRequest(t, "http://localhost/callback", "a")))
+	completionHandler.fail.Store(false)
+	require.Equal(t, http.StatusOK, serveCompletion(handler, newDedupeRequest(t, "http://localhost/callback", "a")))
+	require.Equal(t, http.StatusOK, serveCompletion(handler, newDedupeRequest(t, "http://localhost/callback", "a")))
+	require.Equal(t, int32(2), completionHandler.calls.Load())
+}
+
+func TestCompletionDeduplication_Concurrent(t *testing.T) {
+	completionHandler := &countingCompletionHandler{started: make(chan struct{}), block: make(chan struct{})}
+	handler := NewCompletionHTTPHandler(CompletionHandlerOptions{
+		Handler:         completionHandler,
+		CompletionStore: NewMemoryCompletionStore(MemoryCompletionStoreOptions{}),
+	})
+
+	done := make(chan int)
+	go func() {
+		done <- serveCompletion(handler, newDedupeRequest(t, "http://localhost/callback", "a"))
+	}()
+	<-completionHandler.started
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, newDedupeRequest(t, "http://localhost/callback", "a"))
+	require.Equal(t, http.StatusConflict, recorder.Code)
+	require.Equal(t, "true", recorder.Header().Get(headerRetryable))
+
+	close(completionHandler.block)
+	require.Equal(t, http.StatusOK, <-done)
+	require.Equal(t, http.StatusOK, serveCompletion(handler, newDedupeRequest(t, "http://localhost/callback", "a")))
+	require.Equal(t, int32(1), completionHandler.calls.Load())
+}
+
+func TestMemoryCompletionStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryCompletionStore(MemoryCompletionStoreOptions{MaxEntries: 2})
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	require.NoError(t, store.Remember(ctx, "a", time.Minute))
+	seen, err := store.Seen(ctx, "a")
+	require.NoError(t, err)
+	require.True(t, seen)
+	seen, err = store.Seen(ctx, "b")
+	require.NoError(t, err)
+	require.False(t, seen)
+
+	// Expiry.
+	now = now.Add(2 * time.Minute)
+	seen, err = store.Seen(ctx, "a")
+	require.NoError(t, err)
+	require.False(t, seen)
+
+	// Eviction of expired and excess entries.
+	require.NoError(t, store.Remember(ctx, "b", time.Minute))
+	require.NoError(t, store.Remember(ctx, "c", time.Minute))
+	require.NoError(t, store.Remember(ctx, "d", time.Minute))
+	require.Equal(t, 2, store.order.Len())
+	seen, err = store.Seen(ctx, "b")
+	require.NoError(t, err)
+	require.False(t, seen)
+	seen, err = store.Seen(ctx, "d")
+	require.NoError(t, err)
+	require.True(t, seen)
+}
@@ -0,0 +1,124 @@
+package nexus
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// errDraining is set as the cancelation cause of in-flight long polls when a handler is drained.
+var errDraining = errors.New("handler is draining")
+
+// requestTracker tracks in-flight requests and long polls to support draining.
+type requestTracker struct {
+	mu       sync.Mutex
+	draining bool
+	inFlight int
+	// Closed once draining and all in-flight requests have completed.
+	idle       chan struct{}
+	idleClosed bool
+	nextPoll   int
+	pollCancel map[int]context.CancelCauseFunc
+}
+
+func newRequestTracker() *requestTracker {
+	return &requestTracker{
+		idle:       make(chan struct{}),
+		pollCancel: make(map[int]context.CancelCauseFunc),
+	}
+}
+
+// track wraps an HTTP handler function, tracking it as in-flight until it returns.
+func (t *requestTracker) track(handler http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		t.mu.Lock()
+		t.inFlight++
+		t.mu.Unlock()
+		defer func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.inFlight--
+			t.maybeCloseIdleLocked()
+		}()
+		handler(writer, request)
+	}
+}
+
+// startLongPoll registers a long poll, returning a context that is canceled when the handler is drained and a function
+// to call when the long poll completes.
+// Returns false if the handler is draining and no new long polls should be accepted.
+func (t *requestTracker) startLongPoll(ctx context.Context) (context.Context, func(), bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		return ctx, func() {}, false
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	id := t.nextPoll
+	t.nextPoll++
+	t.pollCancel[id] = cancel
+	return ctx, func() {
+		t.mu.Lock()
+		delete(t.pollCancel, id)
+		t.mu.Unlock()
+		cancel(nil)
+	}, true
+}
+
+// maybeCloseIdleLocked closes the idle channel if draining and there are no in-flight requests.
+// Must be called with the lock held.
+func (t *requestTracker) maybeCloseIdleLocked() {
+	if t.draining && t.inFlight == 0 && !t.idleClosed {
+		t.idleClosed = true
+		close(t.idle)
+	}
+}
+
+func (t *requestTracker) drain(ctx context.Context) error {
+	t.mu.Lock()
+	if !t.draining {
+		t.draining = true
+		for _, cancel := range t.pollCancel {
+			cancel(errDraining)
+		}
+		t.maybeCloseIdleLocked()
+	}
+	t.mu.Unlock()
+
+	select {
+	case <-t.idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// HTTPHandler is an [http.Handler] for handling Nexus service requests, constructed with [NewHTTPHandler].
+type HTTPHandler struct {
+	router  http.Handler
+	tracker *requestTracker
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *HTTPHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	h.router.ServeHTTP(writer, request)
+}
+
+// Drain prepares the handler for shutdown.
+//
+// Once called, new long poll get-result requests are immediately answered with a 408 (Request Timeout) status, which
+// clients treat as a signal to poll again, potentially reaching another replica. The context of in-flight long polls is
+// canceled and they are answered with a 408 status as well once [Handler.GetOperationResult] returns. All other requests
+// continue to be served.
+//
+// Drain blocks until all in-flight requests have completed or ctx is done, in which case the context's error is
+// returned. Calling Drain more than once is safe.
+//
+// Drain does not close any listeners, it is meant to be called before [http.Server.Shutdown]:
+//
+//	_ = handler.Drain(ctx)
+//	_ = server.Shutdown(ctx)
+func (h *HTTPHandler) Drain(ctx context.Context) error {
+	return h.tracker.drain(ctx)
+}
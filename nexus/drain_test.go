@@ -0,0 +1,160 @@
+package nexus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type drainTestHandler struct {
+	UnimplementedHandler
+	pollStarted chan struct{}
+	release     chan struct{}
+}
+
+func (h *drainTestHandler) GetOperationResult(ctx context.Context, request *GetOperationResultRequest) (*OperationResponseSync, error) {
+	if request.HTTPRequest.URL.Query().Get(queryWait) == "" {
+		return nil, ErrOperationStillRunning
+	}
+	h.pollStarted <- struct{}{}
+	<-ctx.Done()
+	return nil, ErrOperationStillRunning
+}
+
+func (h *drainTestHandler) CancelOperation(ctx context.Context, request *CancelOperationRequest) error {
+	<-h.release
+	return nil
+}
+
+func TestDrain_AnswersLongPolls(t *testing.T) {
+	handler := &drainTestHandler{pollStarted: make(chan struct{}, 1)}
+	httpHandler := NewHTTPHandler(HandlerOptions{Handler: handler, GetResultTimeout: testTimeout})
+	server := httptest.NewServer(httpHandler)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	type result struct {
+		response *http.Response
+		err      error
+	}
+	results := make(chan result, 1)
+	go func() {
+		response, err := http.Get(server.URL + "/foo/bar/result?wait=1m")
+		results <- result{response, err}
+	}()
+	<-handler.pollStarted
+
+	startTime := time.Now()
+	require.NoError(t, httpHandler.Drain(ctx))
+	r := <-results
+	require.NoError(t, r.err)
+	r.response.Body.Close()
+	require.Equal(t, http.StatusRequestTimeout, r.response.StatusCode)
+	require.Less(t, time.Since(startTime), time.Second)
+
+	// New long polls are rejected immediately without invoking the handler.
+	response, err := http.Get(server.URL + "/foo/bar/result?wait=1m")
+	require.NoError(t, err)
+	response.Body.Close()
+	require.Equal(t, http.StatusRequestTimeout, response.StatusCode)
+	require.Equal(t, 0, len(handler.pollStarted))
+
+	// Other requests are still served.
+	response, err = http.Get(server.URL + "/foo/bar/result")
+	require.NoError(t, err)
+	response.Body.Close()
+	require.Equal(t, statusOperationRunning, response.StatusCode)
+}
+
+func TestDrain_AnswersZeroWaitLongPolls(t *testing.T) {
+	handler := &drainTestHandler{pollStarted: make(chan struct{}, 1)}
+	httpHandler := NewHTTPHandler(HandlerOptions{Handler: handler, GetResultTimeout: testTimeout})
+	server := httptest.NewServer(httpHandler)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	type result struct {
+		response *http.Response
+		err      error
+	}
+	results := make(chan result, 1)
+	go func() {
+		response, err := http.Get(server.URL + "/foo/bar/result?wait=0s")
+		results <- result{response, err}
+	}()
+	<-handler.pollStarted
+
+	require.NoError(t, httpHandler.Drain(ctx))
+	r := <-results
+	require.NoError(t, r.err)
+	r.response.Body.Close()
+	require.Equal(t, http.StatusRequestTimeout, r.response.StatusCode)
+}
+
+func TestDrain_WaitsForInFlightRequests(t *testing.T) {
+	handler := &drainTestHandler{release: make(chan struct{})}
+	httpHandler := NewHTTPHandler(HandlerOptions{Handler: handler})
+	server := httptest.NewServer(httpHandler)
+	defer server.Close()
+
+	client, err := NewClient(ClientOptions{ServiceBaseURL: server.URL})
+	require.NoError(t, err)
+	handle, err := client.NewHandle("foo", "bar")
+	require.NoError(t, err)
+	cancelErr := make(chan error, 1)
+	go func() {
+		cancelErr <- handle.Cancel(context.Background(), CancelOperationOptions{})
+	}()
+
+	// Wait for the request to be in flight.
+	require.Eventually(t, func() bool {
+		httpHandler.tracker.mu.Lock()
+		defer httpHandler.tracker.mu.Unlock()
+		return httpHandler.tracker.inFlight == 1
+	}, time.Second, time.Millisecond*10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	require.ErrorIs(t, httpHandler.Drain(ctx), context.DeadlineExceeded)
+
+	close(handler.release)
+	ctx, cancel = context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	require.NoError(t, httpHandler.Drain(ctx))
+	require.NoError(t, <-cancelErr)
+}
+
+func TestDrain_ClientRepolls(t *testing.T) {
+	drained := NewHTTPHandler(HandlerOptions{Handler: &drainTestHandler{}})
+	healthy := NewHTTPHandler(HandlerOptions{Handler: &asyncWithResultHandler{}, GetResultTimeout: getResultMaxTimeout})
+	require.NoError(t, drained.Drain(context.Background()))
+
+	// Simulate a load balancer that directs the first poll to the drained replica.
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		calls++
+		if calls == 1 {
+			drained.ServeHTTP(writer, request)
+		} else {
+			healthy.ServeHTTP(writer, request)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(ClientOptions{ServiceBaseURL: server.URL})
+	require.NoError(t, err)
+	handle, err := client.NewHandle("foo", "bar")
+	require.NoError(t, err)
+	response, err := handle.GetResult(context.Background(), GetOperationResultOptions{Wait: time.Second})
+	require.NoError(t, err)
+	response.Body.Close()
+	require.Equal(t, 2, calls)
+}
@@ -0,0 +1,333 @@
+package nexus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Keys of Failure metadata set by NewFailureFromError.
+const (
+	// Registered name of the error a failure was converted from.
+	failureMetadataType = "type"
+	// Stack trace of the error a failure was converted from.
+	failureMetadataStack = "stack"
+)
+
+// maxFailureCauseDepth bounds the nesting of failure causes to protect against cyclic error chains and maliciously deep
+// failures.
+const maxFailureCauseDepth = 32
+
+var errEmptyErrorName = errors.New("empty error name")
+
+var errDuplicateErrorName = errors.New("duplicate error name")
+
+// An ErrorRegistry maps Go errors to stable names transmitted in [Failure] metadata, allowing errors to retain their
+// identity across process boundaries.
+//
+// Register sentinel errors with [ErrorRegistry.RegisterSentinel] to have [ErrorFromFailure] restore them so that
+// [errors.Is] matches them on the receiving side. Register error types with [RegisterErrorType] to have their fields
+// transmitted in the failure's details and restored so that [errors.As] matches them on the receiving side.
+//
+// Set [HandlerOptions.ErrorRegistry] and [ClientOptions.ErrorRegistry] to apply a registry to all failures exchanged
+// between a handler and a client.
+type ErrorRegistry struct {
+	mu        sync.RWMutex
+	sentinels map[string]error
+	types     map[string]reflect.Type
+	typeNames map[reflect.Type]string
+}
+
+// NewErrorRegistry constructs a new, empty [ErrorRegistry].
+func NewErrorRegistry() *ErrorRegistry {
+	return &ErrorRegistry{
+		sentinels: make(map[string]error),
+		types:     make(map[string]reflect.Type),
+		typeNames: make(map[reflect.Type]string),
+	}
+}
+
+// checkNameLocked fails if name is empty or registered.
+func (r *ErrorRegistry) checkNameLocked(name string) error {
+	if name == "" {
+		return errEmptyErrorName
+	}
+	_, isSentinel := r.sentinels[name]
+	_, isType := r.types[name]
+	if isSentinel || isType {
+		return fmt.Errorf("%w: %q", errDuplicateErrorName, name)
+	}
+	return nil
+}
+
+// RegisterSentinel registers a sentinel error value under a stable name. Both sides of a call must register the same
+// sentinels under the same names.
+// Fails if the name is empty or already registered.
+func (r *ErrorRegistry) RegisterSentinel(name string, err error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.checkNameLocked(name); err != nil {
+		return err
+	}
+	r.sentinels[name] = err
+	return nil
+}
+
+// RegisterErrorType registers the error type E under a stable name. Both sides of a call must register the same types
+// under the same names.
+//
+// Errors of type E are encoded into failure details with [json.Marshal] and decoded with [json.Unmarshal], only their
+// exported fields are transmitted. E is typically a pointer to a struct type, e.g. *MyError.
+// Fails if the name is empty or already registered, or E is already registered under another name.
+func RegisterErrorType[E error](r *ErrorRegistry, name string) error {
+	t := reflect.TypeOf((*E)(nil)).Elem()
+	if t.Kind() == reflect.Interface {
+		return fmt.Errorf("cannot register interface type %s", t)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.checkNameLocked(name); err != nil {
+		return err
+	}
+	if existing, ok := r.typeNames[t]; ok {
+		return fmt.Errorf("%w: type %s already registered as %q", errDuplicateErrorName, t, existing)
+	}
+	r.types[name] = t
+	r.typeNames[t] = name
+	return nil
+}
+
+// typeName returns the name the dynamic type of err is registered under.
+func (r *ErrorRegistry) typeName(err error) (string, bool) {
+	if r == nil {
+		return "", false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name, ok := r.typeNames[reflect.TypeOf(err)]
+	return name, ok
+}
+
+// findRegistered returns the first error in the chain of err, in pre-order, that is a registered sentinel or of a
+// registered type.
+func (r *ErrorRegistry) findRegistered(err error, depth int) (error, bool) {
+	if err == nil || depth > maxFailureCauseDepth {
+		return nil, false
+	}
+	if _, ok := r.sentinelName(err); ok {
+		return err, true
+	}
+	if _, ok := r.typeName(err); ok {
+		return err, true
+	}
+	for _, cause := range unwrapErrors(err) {
+		if found, ok := r.findRegistered(cause, depth+1); ok {
+			return found, true
+		}
+	}
+	return nil, false
+}
+
+// decodeType decodes failure details into an error of the type registered under name.
+func (r *ErrorRegistry) decodeType(name string, details json.RawMessage) (error, bool) {
+	if r == nil {
+		return nil, false
+	}
+	r.mu.RLock()
+	t, ok := r.types[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	var v reflect.Value
+	if t.Kind() == reflect.Pointer {
+		v = reflect.New(t.Elem())
+		if len(details) > 0 && json.Unmarshal(details, v.Interface()) != nil {
+			return nil, false
+		}
+	} else {
+		ptr := reflect.New(t)
+		if len(details) > 0 && json.Unmarshal(details, ptr.Interface()) != nil {
+			return nil, false
+		}
+		v = ptr.Elem()
+	}
+	return v.Interface().(error), true
+}
+
+// sentinelName returns the name err is registered under if it is a registered sentinel.
+func (r *ErrorRegistry) sentinelName(err error) (string, bool) {
+	if r == nil || !reflect.TypeOf(err).Comparable() {
+		return "", false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for name, sentinel := range r.sentinels {
+		if reflect.TypeOf(sentinel) == reflect.TypeOf(err) && sentinel == err {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func (r *ErrorRegistry) sentinel(name string) (error, bool) {
+	if r == nil {
+		return nil, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	err, ok := r.sentinels[name]
+	return err, ok
+}
+
+// FailureFromErrorOptions are options for [NewFailureFromError].
+type FailureFromErrorOptions struct {
+	// Registry of errors to record the names of in the "type" metadata key of the corresponding failures. The fields
+	// of errors of registered types are encoded into the failure's details. Optional.
+	Registry *ErrorRegistry
+	// Optional function that returns the message to transmit for each error in the chain, e.g. to strip sensitive
+	// information. Defaults to the error's Error() string.
+	RedactMessage func(err error) string
+	// Optional function that returns a stack trace for an error, recorded in the "stack" metadata key of the
+	// corresponding failure. Errors for which an empty string is returned are recorded without a stack trace.
+	StackTrace func(err error) string
+}
+
+// NewFailureFromError converts a Go error into a [Failure], encoding the errors it wraps, as returned by
+// Unwrap() error or Unwrap() []error (e.g. [errors.Join]), into the failure's Causes. Returns a zero Failure if err is
+// nil.
+//
+// Use it to report Go errors as operation failures:
+//
+//	return nil, &nexus.UnsuccessfulOperationError{
+//		State:   nexus.OperationStateFailed,
+//		Failure: nexus.NewFailureFromError(err, nexus.FailureFromErrorOptions{Registry: registry}),
+//	}
+func NewFailureFromError(err error, options FailureFromErrorOptions) Failure {
+	if err == nil {
+		return Failure{}
+	}
+	return newFailureFromError(err, options, 0)
+}
+
+func newFailureFromError(err error, options FailureFromErrorOptions, depth int) Failure {
+	failure := newFailureWithoutCauses(err, options)
+	if depth >= maxFailureCauseDepth {
+		return failure
+	}
+	for _, cause := range unwrapErrors(err) {
+		failure.Causes = append(failure.Causes, newFailureFromError(cause, options, depth+1))
+	}
+	return failure
+}
+
+// newFailureWithoutCauses converts err into a failure, ignoring the errors it wraps.
+func newFailureWithoutCauses(err error, options FailureFromErrorOptions) Failure {
+	failure := Failure{Message: err.Error()}
+	if options.RedactMessage != nil {
+		failure.Message = options.RedactMessage(err)
+	}
+	if name, ok := options.Registry.sentinelName(err); ok {
+		failure.Metadata = map[string]string{failureMetadataType: name}
+	} else if name, ok := options.Registry.typeName(err); ok {
+		failure.Metadata = map[string]string{failureMetadataType: name}
+		// Errors that fail to marshal are still identified by name.
+		if details, err := json.Marshal(err); err == nil {
+			failure.Details = details
+		}
+	}
+	if options.StackTrace != nil {
+		if stack := options.StackTrace(err); stack != "" {
+			if failure.Metadata == nil {
+				failure.Metadata = make(map[string]string)
+			}
+			failure.Metadata[failureMetadataStack] = stack
+		}
+	}
+	return failure
+}
+
+// unwrapErrors returns the non nil errors wrapped by err.
+func unwrapErrors(err error) []error {
+	var wrapped []error
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		wrapped = []error{e.Unwrap()}
+	case interface{ Unwrap() []error }:
+		wrapped = e.Unwrap()
+	}
+	causes := make([]error, 0, len(wrapped))
+	for _, cause := range wrapped {
+		if cause != nil {
+			causes = append(causes, cause)
+		}
+	}
+	return causes
+}
+
+// newRegisteredFailure converts a registered error into a failure whose causes are converted from the registered errors
+// in its chain only, so that the unregistered errors it wraps are not exposed.
+func newRegisteredFailure(err error, registry *ErrorRegistry, depth int) Failure {
+	failure := newFailureWithoutCauses(err, FailureFromErrorOptions{Registry: registry})
+	if depth >= maxFailureCauseDepth {
+		return failure
+	}
+	for _, cause := range unwrapErrors(err) {
+		if registered, ok := registry.findRegistered(cause, depth+1); ok {
+			failure.Causes = append(failure.Causes, newRegisteredFailure(registered, registry, depth+1))
+		}
+	}
+	return failure
+}
+
+// FailureError is an error reconstructed from a [Failure] by [ErrorFromFailure].
+type FailureError struct {
+	// The failure this error was reconstructed from.
+	Failure Failure
+	causes  []error
+}
+
+// Error implements the error interface, returning the failure's message.
+func (e *FailureError) Error() string {
+	return e.Failure.Message
+}
+
+// Unwrap returns the errors reconstructed from the failure's causes.
+func (e *FailureError) Unwrap() []error {
+	return e.causes
+}
+
+// Stack returns the stack trace recorded for the error, if any.
+func (e *FailureError) Stack() string {
+	return e.Failure.Metadata[failureMetadataStack]
+}
+
+// ErrorFromFailure converts a [Failure] into a Go error chain, the inverse of [NewFailureFromError].
+//
+// Failures of errors registered as sentinels in registry are converted back into the sentinel values and failures of
+// errors of registered types are decoded into values of those types. Other failures are converted into
+// [FailureError]s whose Error() returns the failure's message and that wrap the errors converted from the failure's
+// causes. registry may be nil.
+func ErrorFromFailure(failure Failure, registry *ErrorRegistry) error {
+	return errorFromFailure(failure, registry, 0)
+}
+
+func errorFromFailure(failure Failure, registry *ErrorRegistry, depth int) error {
+	name := failure.Metadata[failureMetadataType]
+	if sentinel, ok := registry.sentinel(name); ok {
+		return sentinel
+	}
+	if typed, ok := registry.decodeType(name, failure.Details); ok {
+		return typed
+	}
+	err := &FailureError{Failure: failure}
+	if depth >= maxFailureCauseDepth {
+		return err
+	}
+	for _, cause := range failure.Causes {
+		err.causes = append(err.causes, errorFromFailure(cause, registry, depth+1))
+	}
+	return err
+}
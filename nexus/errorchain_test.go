@@ -0,0 +1,288 @@
+package nexus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var errTestNotFound = errors.New("not found")
+
+func TestNewFailureFromError(t *testing.T) {
+	registry := NewErrorRegistry()
+	require.NoError(t, registry.RegisterSentinel("test.NotFound", errTestNotFound))
+	require.ErrorIs(t, registry.RegisterSentinel("test.NotFound", errTestNotFound), errDuplicateErrorName)
+	require.ErrorIs(t, registry.RegisterSentinel("", errTestNotFound), errEmptyErrorName)
+
+	err := fmt.Errorf("lookup failed: %w", errors.Join(errTestNotFound, fs.ErrPermission))
+	failure := NewFailureFromError(err, FailureFromErrorOptions{
+		Registry: registry,
+		RedactMessage: func(err error) string {
+			return strings.ReplaceAll(err.Error(), "permission", "<redacted>")
+		},
+		StackTrace: func(err error) string {
+			if err == fs.ErrPermission {
+				return "stack"
+			}
+			return ""
+		},
+	})
+	require.Equal(t, Failure{
+		Message: "lookup failed: not found\n<redacted> denied",
+		Causes: []Failure{
+			{
+				Message: "not found\n<redacted> denied",
+				Causes: []Failure{
+					{Message: "not found", Metadata: map[string]string{"type": "test.NotFound"}},
+					{Message: "<redacted> denied", Metadata: map[string]string{"stack": "stack"}},
+				},
+			},
+		},
+	}, failure)
+
+	// Round trip through JSON as a client would receive the failure.
+	b, err := json.Marshal(failure)
+	require.NoError(t, err)
+	var received Failure
+	require.NoError(t, json.Unmarshal(b, &received))
+
+	decoded := ErrorFromFailure(received, registry)
+	require.Equal(t, "lookup failed: not found\n<redacted> denied", decoded.Error())
+	require.ErrorIs(t, decoded, errTestNotFound)
+	require.NotErrorIs(t, decoded, fs.ErrPermission)
+	var failureError *FailureError
+	require.ErrorAs(t, decoded, &failureError)
+	require.Equal(t, received, failureError.Failure)
+
+	// Find the cause with the stack trace.
+	joined := failureError.Unwrap()[0].(*FailureError)
+	require.Equal(t, "stack", joined.Unwrap()[1].(*FailureError).Stack())
+
+	// Without a registry, sentinels are not restored.
+	require.NotErrorIs(t, ErrorFromFailure(received, nil), errTestNotFound)
+}
+
+type cyclicError struct{}
+
+func (e *cyclicError) Error() string { return "cycle" }
+
+func (e *cyclicError) Unwrap() error { return e }
+
+func TestNewFailureFromError_BoundedDepth(t *testing.T) {
+	failure := NewFailureFromError(&cyclicError{}, FailureFromErrorOptions{})
+	depth := 0
+	for len(failure.Causes) > 0 {
+		failure = failure.Causes[0]
+		depth++
+	}
+	require.Equal(t, maxFailureCauseDepth, depth)
+}
+
+type errorChainHandler struct {
+	UnimplementedHandler
+	registry *ErrorRegistry
+}
+
+func (h *errorChainHandler) StartOperation(ctx context.Context, request *StartOperationRequest) (OperationResponse, error) {
+	err := fmt.Errorf("failed to get item: %w", errTestNotFound)
+	return nil, &UnsuccessfulOperationError{
+		State:   OperationStateFailed,
+		Failure: NewFailureFromError(err, FailureFromErrorOptions{Registry: h.registry}),
+	}
+}
+
+func TestErrorFromFailure_UnsuccessfulOperation(t *testing.T) {
+	registry := NewErrorRegistry()
+	require.NoError(t, registry.RegisterSentinel("test.NotFound", errTestNotFound))
+	ctx, client, teardown := setup(t, &errorChainHandler{registry: registry})
+	defer teardown()
+
+	_, err := client.StartOperation(ctx, StartOperationOptions{Operation: "foo"})
+	var unsuccessfulError *UnsuccessfulOperationError
+	require.ErrorAs(t, err, &unsuccessfulError)
+	cause := ErrorFromFailure(unsuccessfulError.Failure, registry)
+	require.Equal(t, "failed to get item: not found", cause.Error())
+	require.ErrorIs(t, cause, errTestNotFound)
+}
+
+func TestFailure_UnmarshalCause(t *testing.T) {
+	var failure Failure
+	require.NoError(t, json.Unmarshal([]byte(`{"message": "a", "cause": {"message": "b", "cause": {"message": "c"}}}`), &failure))
+	require.Equal(t, Failure{Message: "a", Causes: []Failure{{Message: "b", Causes: []Failure{{Message: "c"}}}}}, failure)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"message": "a", "causes": [{"message": "b"}, {"message": "c"}]}`), &failure))
+	require.Equal(t, Failure{Message: "a", Causes: []Failure{{Message: "b"}, {Message: "c"}}}, failure)
+
+	b, err := json.Marshal(failure)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"message": "a", "causes": [{"message": "b"}, {"message": "c"}]}`, string(b))
+}
+
+func TestFailure_MarshalCause(t *testing.T) {
+	failure := NewFailureFromError(fmt.Errorf("a: %w", fmt.Errorf("b: %w", errors.New("c"))), FailureFromErrorOptions{})
+	b, err := json.Marshal(failure)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"message": "a: b: c", "cause": {"message": "b: c", "cause": {"message": "c"}}}`, string(b))
+
+	var decoded Failure
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	require.Equal(t, failure, decoded)
+}
+
+func TestNewFailureFromError_Nil(t *testing.T) {
+	require.Equal(t, Failure{}, NewFailureFromError(nil, FailureFromErrorOptions{}))
+}
+
+type testValidationError struct {
+	Field string `json:"field"`
+}
+
+func (e *testValidationError) Error() string {
+	return "invalid field: " + e.Field
+}
+
+type testCodeError struct {
+	Code int `json:"code"`
+}
+
+func (e testCodeError) Error() string {
+	return fmt.Sprintf("code %d", e.Code)
+}
+
+func TestRegisterErrorType(t *testing.T) {
+	registry := NewErrorRegistry()
+	require.NoError(t, RegisterErrorType[*testValidationError](registry, "test.Validation"))
+	require.NoError(t, RegisterErrorType[testCodeError](registry, "test.Code"))
+	require.ErrorIs(t, RegisterErrorType[*testValidationError](registry, "test.Other"), errDuplicateErrorName)
+	require.ErrorIs(t, registry.RegisterSentinel("test.Validation", errTestNotFound), errDuplicateErrorName)
+	require.Error(t, RegisterErrorType[error](registry, "test.Interface"))
+
+	err := errors.Join(&testValidationError{Field: "name"}, testCodeError{Code: 3})
+	failure := NewFailureFromError(err, FailureFromErrorOptions{Registry: registry})
+	require.Equal(t, Failure{Message: "invalid field: name", Metadata: map[string]string{"type": "test.Validation"}, Details: json.RawMessage(`{"field":"name"}`)}, failure.Causes[0])
+	require.Equal(t, Failure{Message: "code 3", Metadata: map[string]string{"type": "test.Code"}, Details: json.RawMessage(`{"code":3}`)}, failure.Causes[1])
+
+	decoded := ErrorFromFailure(failure, registry)
+	var validationError *testValidationError
+	require.ErrorAs(t, decoded, &validationError)
+	require.Equal(t, "name", validationError.Field)
+	var codeError testCodeError
+	require.ErrorAs(t, decoded, &codeError)
+	require.Equal(t, 3, codeError.Code)
+}
+
+type typedErrorHandler struct {
+	UnimplementedHandler
+	registry *ErrorRegistry
+}
+
+func (h *typedErrorHandler) StartOperation(ctx context.Context, request *StartOperationRequest) (OperationResponse, error) {
+	switch request.Operation {
+	case "wrapped":
+		return nil, fmt.Errorf("failed to validate: %w", &testValidationError{Field: "name"})
+	case "handler-error":
+		return nil, &HandlerError{StatusCode: http.StatusBadRequest, Cause: &testValidationError{Field: "id"}}
+	case "sentinel":
+		return nil, &HandlerError{StatusCode: http.StatusNotFound, Cause: fmt.Errorf("no such row: %w", errTestNotFound)}
+	case "wrapping":
+		return nil, &HandlerError{StatusCode: http.StatusBadRequest, Cause: &testOpError{
+			Op:  "get",
+			err: errors.Join(errors.New("secret dsn"), fmt.Errorf("no such row: %w", errTestNotFound)),
+		}}
+	case "wrapped-sentinel":
+		return nil, fmt.Errorf("lookup failed: %w", errTestNotFound)
+	case "unsuccessful":
+		return nil, &UnsuccessfulOperationError{
+			State:   OperationStateFailed,
+			Failure: NewFailureFromError(testCodeError{Code: 7}, FailureFromErrorOptions{Registry: h.registry}),
+		}
+	}
+	return nil, errors.New("secret")
+}
+
+// testOpError is a registered error type that wraps other errors.
+type testOpError struct {
+	Op  string `json:"op"`
+	err error
+}
+
+func (e *testOpError) Error() string {
+	return e.Op + " failed"
+}
+
+func (e *testOpError) Unwrap() error {
+	return e.err
+}
+
+func TestErrorRegistry_HandlerAndClient(t *testing.T) {
+	registry := NewErrorRegistry()
+	require.NoError(t, RegisterErrorType[*testValidationError](registry, "test.Validation"))
+	require.NoError(t, RegisterErrorType[testCodeError](registry, "test.Code"))
+	require.NoError(t, RegisterErrorType[*testOpError](registry, "test.Op"))
+	require.NoError(t, registry.RegisterSentinel("test.NotFound", errTestNotFound))
+	ctx, client, teardown := setupCustom(t, HandlerOptions{
+		Handler:       &typedErrorHandler{registry: registry},
+		ErrorRegistry: registry,
+	}, ClientOptions{ErrorRegistry: registry})
+	defer teardown()
+
+	_, err := client.StartOperation(ctx, StartOperationOptions{Operation: "wrapped"})
+	var unexpectedResponseError *UnexpectedResponseError
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusInternalServerError, unexpectedResponseError.Response.StatusCode)
+	var validationError *testValidationError
+	require.ErrorAs(t, err, &validationError)
+	require.Equal(t, "name", validationError.Field)
+
+	_, err = client.StartOperation(ctx, StartOperationOptions{Operation: "handler-error"})
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusBadRequest, unexpectedResponseError.Response.StatusCode)
+	require.ErrorAs(t, err, &validationError)
+	require.Equal(t, "id", validationError.Field)
+
+	_, err = client.StartOperation(ctx, StartOperationOptions{Operation: "sentinel"})
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusNotFound, unexpectedResponseError.Response.StatusCode)
+	require.ErrorIs(t, err, errTestNotFound)
+	require.NotContains(t, unexpectedResponseError.Failure.Message, "no such row")
+
+	// Only registered errors wrapped by registered errors are exposed.
+	_, err = client.StartOperation(ctx, StartOperationOptions{Operation: "wrapping"})
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, Failure{
+		Message:  "get failed",
+		Metadata: map[string]string{"type": "test.Op"},
+		Details:  json.RawMessage(`{"op":"get"}`),
+		Causes:   []Failure{{Message: "not found", Metadata: map[string]string{"type": "test.NotFound"}}},
+	}, *unexpectedResponseError.Failure)
+	var opError *testOpError
+	require.ErrorAs(t, err, &opError)
+	require.Equal(t, "get", opError.Op)
+
+	_, err = client.StartOperation(ctx, StartOperationOptions{Operation: "wrapped-sentinel"})
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusInternalServerError, unexpectedResponseError.Response.StatusCode)
+	require.ErrorIs(t, err, errTestNotFound)
+
+	_, err = client.StartOperation(ctx, StartOperationOptions{Operation: "unsuccessful"})
+	var unsuccessfulError *UnsuccessfulOperationError
+	require.ErrorAs(t, err, &unsuccessfulError)
+	var codeError testCodeError
+	require.ErrorAs(t, err, &codeError)
+	require.Equal(t, 7, codeError.Code)
+
+	// Unregistered errors are not exposed.
+	_, err = client.StartOperation(ctx, StartOperationOptions{Operation: "unregistered"})
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, "internal server error", unexpectedResponseError.Failure.Message)
+	var failureError *FailureError
+	require.ErrorAs(t, err, &failureError)
+	require.False(t, errors.As(err, &validationError))
+}
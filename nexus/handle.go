@@ -27,7 +27,13 @@ type GetOperationInfoOptions struct {
 }
 
 // GetInfo gets operation information, issuing a network request to the service handler.
-func (h *OperationHandle) GetInfo(ctx context.Context, options GetOperationInfoOptions) (*OperationInfo, error) {
+func (h *OperationHandle) GetInfo(ctx context.Context, options GetOperationInfoOptions) (info *OperationInfo, err error) {
+	ctx, span := h.client.startSpan(ctx, "nexus.client.get_operation_info", SpanKindClient, h.Operation, h.ID)
+	defer func() {
+		h.client.convertErrorCause(err)
+		endSpan(span, err)
+	}()
+
 	url := h.client.serviceBaseURL.JoinPath(url.PathEscape(h.Operation), url.PathEscape(h.ID))
 	request, err := http.NewRequestWithContext(ctx, "GET", url.String(), nil)
 	if err != nil {
@@ -38,7 +44,7 @@ func (h *OperationHandle) GetInfo(ctx context.Context, options GetOperationInfoO
 	}
 
 	request.Header.Set(headerUserAgent, userAgent)
-	response, err := h.client.options.HTTPCaller(request)
+	response, err := h.client.send(MetricsMethodGetOperationInfo, request)
 	if err != nil {
 		return nil, err
 	}
@@ -80,7 +86,13 @@ type GetOperationResultOptions struct {
 // context deadline to the max allowed wait period to ensure this call returns in a timely fashion.
 //
 // ⚠️ If a response is returned, its body must be read in its entirety and closed to free up the underlying connection.
-func (h *OperationHandle) GetResult(ctx context.Context, options GetOperationResultOptions) (*http.Response, error) {
+func (h *OperationHandle) GetResult(ctx context.Context, options GetOperationResultOptions) (result *http.Response, err error) {
+	ctx, span := h.client.startSpan(ctx, "nexus.client.get_operation_result", SpanKindInternal, h.Operation, h.ID)
+	defer func() {
+		h.client.convertErrorCause(err)
+		endSpan(span, err)
+	}()
+
 	url := h.client.serviceBaseURL.JoinPath(url.PathEscape(h.Operation), url.PathEscape(h.ID), "result")
 	request, err := http.NewRequestWithContext(ctx, "GET", url.String(), nil)
 	if err != nil {
@@ -90,6 +102,7 @@ func (h *OperationHandle) GetResult(ctx context.Context, options GetOperationRes
 		request.Header = options.Header.Clone()
 	}
 	request.Header.Set(headerUserAgent, userAgent)
+	h.client.setAccept(request.Header)
 
 	startTime := time.Now()
 	wait := options.Wait
@@ -110,7 +123,12 @@ func (h *OperationHandle) GetResult(ctx context.Context, options GetOperationRes
 			request.URL.RawQuery = ""
 		}
 
-		response, err := h.sendGetOperationRequest(ctx, request)
+		pollCtx, pollSpan := h.client.startSpan(ctx, "nexus.client.get_operation_result.poll", SpanKindClient, h.Operation, h.ID)
+		if wait > 0 {
+			pollSpan.SetAttribute("nexus.wait", wait.String())
+		}
+		response, err := h.sendGetOperationRequest(pollCtx, request.WithContext(pollCtx))
+		endSpan(pollSpan, err)
 		if err != nil {
 			if wait > 0 && errors.Is(err, errOperationWaitTimeout) {
 				// TODO: Backoff a bit in case the server is continually returning timeouts due to some LB configuration
@@ -124,7 +142,7 @@ func (h *OperationHandle) GetResult(ctx context.Context, options GetOperationRes
 }
 
 func (h *OperationHandle) sendGetOperationRequest(ctx context.Context, request *http.Request) (*http.Response, error) {
-	response, err := h.client.options.HTTPCaller(request)
+	response, err := h.client.send(MetricsMethodGetOperationResult, request)
 	if err != nil {
 		return nil, err
 	}
@@ -171,7 +189,13 @@ type CancelOperationOptions struct {
 // Cancel requests to cancel an asynchronous operation.
 //
 // Cancelation is asynchronous and may be not be respected by the operation's implementation.
-func (h *OperationHandle) Cancel(ctx context.Context, options CancelOperationOptions) error {
+func (h *OperationHandle) Cancel(ctx context.Context, options CancelOperationOptions) (err error) {
+	ctx, span := h.client.startSpan(ctx, "nexus.client.cancel_operation", SpanKindClient, h.Operation, h.ID)
+	defer func() {
+		h.client.convertErrorCause(err)
+		endSpan(span, err)
+	}()
+
 	url := h.client.serviceBaseURL.JoinPath(url.PathEscape(h.Operation), url.PathEscape(h.ID), "cancel")
 	request, err := http.NewRequestWithContext(ctx, "POST", url.String(), nil)
 	if err != nil {
@@ -182,7 +206,7 @@ func (h *OperationHandle) Cancel(ctx context.Context, options CancelOperationOpt
 	}
 
 	request.Header.Set(headerUserAgent, userAgent)
-	response, err := h.client.options.HTTPCaller(request)
+	response, err := h.client.send(MetricsMethodCancelOperation, request)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,130 @@
+package zstd
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// bitWriter writes a little endian bitstream, least significant bits first.
+type bitWriter struct {
+	out       []byte
+	container uint64
+	n         uint
+}
+
+// addBits appends the low n bits of value. n must not exceed 56.
+func (w *bitWriter) addBits(value uint64, n uint) {
+	w.container |= (value & (1<<n - 1)) << w.n
+	w.n += n
+	for w.n >= 8 {
+		w.out = append(w.out, byte(w.container))
+		w.container >>= 8
+		w.n -= 8
+	}
+}
+
+// close terminates the stream with the end marker bit that backward readers start from.
+func (w *bitWriter) close() []byte {
+	w.addBits(1, 1)
+	if w.n > 0 {
+		w.out = append(w.out, byte(w.container))
+		w.container, w.n = 0, 0
+	}
+	return w.out
+}
+
+// reverseBitReader reads a bitstream written by a bitWriter backwards, starting from the end marker. Reading past the
+// start of the stream yields zero bits and marks the reader as overflowed.
+type reverseBitReader struct {
+	data []byte
+	// Number of unread bits, bits at positions [0, pos) of data are yet to be read.
+	pos int
+}
+
+func newReverseBitReader(data []byte) (reverseBitReader, error) {
+	if len(data) == 0 || data[len(data)-1] == 0 {
+		return reverseBitReader{}, errCorrupt
+	}
+	return reverseBitReader{data: data, pos: (len(data)-1)*8 + bits.Len8(data[len(data)-1]) - 1}, nil
+}
+
+// peek returns the next n bits without consuming them. n must not exceed 56.
+func (r *reverseBitReader) peek(n uint) uint64 {
+	if n == 0 {
+		return 0
+	}
+	start := r.pos - int(n)
+	if start >= 0 {
+		return r.extract(start, n)
+	}
+	if r.pos <= 0 {
+		return 0
+	}
+	return r.extract(0, uint(r.pos)) << uint(-start)
+}
+
+func (r *reverseBitReader) readBits(n uint) uint64 {
+	v := r.peek(n)
+	r.pos -= int(n)
+	return v
+}
+
+func (r *reverseBitReader) skip(n uint) {
+	r.pos -= int(n)
+}
+
+// extract returns the n bits starting at bit position start.
+func (r *reverseBitReader) extract(start int, n uint) uint64 {
+	i := start >> 3
+	var v uint64
+	if i+8 <= len(r.data) {
+		v = binary.LittleEndian.Uint64(r.data[i:])
+	} else {
+		for j := len(r.data) - 1; j >= i; j-- {
+			v = v<<8 | uint64(r.data[j])
+		}
+	}
+	return (v >> uint(start&7)) & (1<<n - 1)
+}
+
+// overflowed reports whether more bits were read than the stream contains.
+func (r *reverseBitReader) overflowed() bool {
+	return r.pos < 0
+}
+
+// finished reports whether the stream was consumed exactly.
+func (r *reverseBitReader) finished() bool {
+	return r.pos == 0
+}
+
+// forwardBitReader reads a little endian bitstream, least significant bits first. Reading past the end of the stream
+// yields zero bits.
+type forwardBitReader struct {
+	data []byte
+	pos  int
+}
+
+// peek returns the next n bits without consuming them. n must not exceed 56.
+func (r *forwardBitReader) peek(n uint) uint64 {
+	i := r.pos >> 3
+	var v uint64
+	if i+8 <= len(r.data) {
+		v = binary.LittleEndian.Uint64(r.data[i:])
+	} else {
+		for j := len(r.data) - 1; j >= i; j-- {
+			v = v<<8 | uint64(r.data[j])
+		}
+	}
+	return (v >> uint(r.pos&7)) & (1<<n - 1)
+}
+
+func (r *forwardBitReader) readBits(n uint) uint64 {
+	v := r.peek(n)
+	r.pos += int(n)
+	return v
+}
+
+// bytesRead returns the number of bytes touched by the bits read so far.
+func (r *forwardBitReader) bytesRead() int {
+	return (r.pos + 7) >> 3
+}
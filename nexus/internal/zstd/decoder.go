@@ -0,0 +1,567 @@
+package zstd
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Reader decompresses a stream of zstd frames.
+type Reader struct {
+	r   io.Reader
+	err error
+	// Number of frames fully decoded.
+	frames int
+
+	inFrame     bool
+	lastBlock   bool
+	windowSize  int
+	blockSize   int
+	hasChecksum bool
+	hash        xxhash64
+	contentSize int64
+	produced    int64
+
+	// Decoded data of the current frame, the trailing window of which is kept as history for matches.
+	history []byte
+	// Offset in history of the next byte to return from Read.
+	out int
+
+	huffman        *huffmanTable
+	literalsLength *fseTable
+	offset         *fseTable
+	matchLength    *fseTable
+	repeatOffsets  [3]int
+
+	block    []byte
+	literals []byte
+	// Large enough for the largest frame header: magic number, descriptor, window, dictionary ID, and content size.
+	header [18]byte
+}
+
+// NewReader returns a reader that decompresses the zstd frames read from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: r}
+}
+
+// Read implements the io.Reader interface.
+func (r *Reader) Read(p []byte) (int, error) {
+	for {
+		if r.out < len(r.history) {
+			n := copy(p, r.history[r.out:])
+			r.out += n
+			return n, nil
+		}
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.err = r.next()
+	}
+}
+
+// Close implements the io.Closer interface. It does not close the underlying reader.
+func (r *Reader) Close() error {
+	r.history, r.block, r.literals = nil, nil, nil
+	if r.err == nil {
+		r.err = errors.New("zstd: reader closed")
+	}
+	return nil
+}
+
+// next decodes the next frame header or block, or finishes the current frame.
+func (r *Reader) next() error {
+	if !r.inFrame {
+		return r.readFrameHeader()
+	}
+	if r.lastBlock {
+		return r.finishFrame()
+	}
+	return r.readBlock()
+}
+
+// readFull reads exactly len(p) bytes, treating a premature end of the stream as an unexpected EOF.
+func (r *Reader) readFull(p []byte) error {
+	if _, err := io.ReadFull(r.r, p); err != nil {
+		if errors.Is(err, io.EOF) {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *Reader) readFrameHeader() error {
+	magic := r.header[:4]
+	if n, err := io.ReadFull(r.r, magic); err != nil {
+		if n == 0 && errors.Is(err, io.EOF) && r.frames > 0 {
+			return io.EOF
+		}
+		if errors.Is(err, io.EOF) {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	if binary.LittleEndian.Uint32(magic)&skippableFrameMask == skippableFrameMagic {
+		size := r.header[4:8]
+		if err := r.readFull(size); err != nil {
+			return err
+		}
+		n := int64(binary.LittleEndian.Uint32(size))
+		if copied, err := io.CopyN(io.Discard, r.r, n); err != nil {
+			if copied < n && errors.Is(err, io.EOF) {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		r.frames++
+		return nil
+	}
+	if binary.LittleEndian.Uint32(magic) != frameMagic {
+		return errInvalidMagicNumber
+	}
+
+	descriptor := r.header[4:5]
+	if err := r.readFull(descriptor); err != nil {
+		return err
+	}
+	contentSizeFlag := descriptor[0] >> 6
+	singleSegment := descriptor[0]&(1<<5) != 0
+	if descriptor[0]&(1<<3) != 0 {
+		return errCorrupt
+	}
+	hasChecksum := descriptor[0]&(1<<2) != 0
+	dictionaryIDSize := [4]int{0, 1, 2, 4}[descriptor[0]&3]
+	contentSizeSize := [4]int{0, 2, 4, 8}[contentSizeFlag]
+	if singleSegment && contentSizeFlag == 0 {
+		contentSizeSize = 1
+	}
+	windowDescriptorSize := 1
+	if singleSegment {
+		windowDescriptorSize = 0
+	}
+	fields := r.header[5 : 5+windowDescriptorSize+dictionaryIDSize+contentSizeSize]
+	if err := r.readFull(fields); err != nil {
+		return err
+	}
+
+	var windowSize uint64
+	if !singleSegment {
+		exponent := uint64(fields[0] >> 3)
+		mantissa := uint64(fields[0] & 7)
+		base := uint64(1) << (10 + exponent)
+		windowSize = base + base/8*mantissa
+		fields = fields[1:]
+	}
+	var dictionaryID uint64
+	for i := dictionaryIDSize - 1; i >= 0; i-- {
+		dictionaryID = dictionaryID<<8 | uint64(fields[i])
+	}
+	if dictionaryID != 0 {
+		return errDictionary
+	}
+	fields = fields[dictionaryIDSize:]
+	contentSize := int64(-1)
+	if contentSizeSize > 0 {
+		var size uint64
+		for i := contentSizeSize - 1; i >= 0; i-- {
+			size = size<<8 | uint64(fields[i])
+		}
+		if contentSizeSize == 2 {
+			size += 256
+		}
+		if size > 1<<62 {
+			return errCorrupt
+		}
+		contentSize = int64(size)
+		if singleSegment {
+			windowSize = size
+		}
+	}
+	if windowSize > MaxWindowSize {
+		return errWindowTooLarge
+	}
+
+	r.inFrame = true
+	r.lastBlock = false
+	r.windowSize = int(windowSize)
+	r.blockSize = min(r.windowSize, maxBlockSize)
+	r.hasChecksum = hasChecksum
+	r.hash.reset()
+	r.contentSize = contentSize
+	r.produced = 0
+	// Matches can't reference data of previous frames, all of which has been read by now.
+	r.history = r.history[:0]
+	r.out = 0
+	r.huffman = nil
+	r.literalsLength, r.offset, r.matchLength = nil, nil, nil
+	r.repeatOffsets = [3]int{1, 4, 8}
+	return nil
+}
+
+func (r *Reader) finishFrame() error {
+	if r.contentSize >= 0 && r.produced != r.contentSize {
+		return errCorrupt
+	}
+	if r.hasChecksum {
+		checksum := r.header[:4]
+		if err := r.readFull(checksum); err != nil {
+			return err
+		}
+		if binary.LittleEndian.Uint32(checksum) != uint32(r.hash.sum64()) {
+			return errChecksum
+		}
+	}
+	r.inFrame = false
+	r.frames++
+	return nil
+}
+
+func (r *Reader) readBlock() error {
+	header := r.header[:3]
+	if err := r.readFull(header); err != nil {
+		return err
+	}
+	value := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	r.lastBlock = value&1 != 0
+	blockType := (value >> 1) & 3
+	size := value >> 3
+	if size > r.blockSize {
+		return errCorrupt
+	}
+
+	// Drop history beyond the window once all of it has been read.
+	if len(r.history)+r.blockSize > cap(r.history) && len(r.history) > r.windowSize {
+		n := copy(r.history, r.history[len(r.history)-r.windowSize:])
+		r.history = r.history[:n]
+	}
+	start := len(r.history)
+	r.out = start
+
+	switch blockType {
+	case blockTypeRaw:
+		r.history = grow(r.history, size)
+		if err := r.readFull(r.history[start:]); err != nil {
+			return err
+		}
+	case blockTypeRLE:
+		b := r.header[3:4]
+		if err := r.readFull(b); err != nil {
+			return err
+		}
+		r.history = grow(r.history, size)
+		for i := start; i < len(r.history); i++ {
+			r.history[i] = b[0]
+		}
+	case blockTypeCompressed:
+		if cap(r.block) < size {
+			r.block = make([]byte, size, maxBlockSize)
+		}
+		r.block = r.block[:size]
+		if err := r.readFull(r.block); err != nil {
+			return err
+		}
+		if err := r.decodeCompressedBlock(r.block); err != nil {
+			return err
+		}
+	default:
+		return errCorrupt
+	}
+
+	r.produced += int64(len(r.history) - start)
+	if r.contentSize >= 0 && r.produced > r.contentSize {
+		return errCorrupt
+	}
+	if r.hasChecksum {
+		r.hash.write(r.history[start:])
+	}
+	return nil
+}
+
+// grow extends b by n bytes.
+func grow(b []byte, n int) []byte {
+	if len(b)+n > cap(b) {
+		grown := make([]byte, len(b), 2*cap(b)+n)
+		copy(grown, b)
+		b = grown
+	}
+	return b[:len(b)+n]
+}
+
+func (r *Reader) decodeCompressedBlock(data []byte) error {
+	literals, n, err := r.decodeLiterals(data)
+	if err != nil {
+		return err
+	}
+	data = data[n:]
+
+	if len(data) == 0 {
+		return errCorrupt
+	}
+	numSequences := int(data[0])
+	switch {
+	case numSequences < 128:
+		data = data[1:]
+	case numSequences < 255:
+		if len(data) < 2 {
+			return errCorrupt
+		}
+		numSequences = (numSequences-128)<<8 | int(data[1])
+		data = data[2:]
+	default:
+		if len(data) < 3 {
+			return errCorrupt
+		}
+		numSequences = (int(data[1]) | int(data[2])<<8) + 0x7f00
+		data = data[3:]
+	}
+	if numSequences == 0 {
+		if len(data) != 0 || len(literals) > r.blockSize {
+			return errCorrupt
+		}
+		r.history = append(r.history, literals...)
+		return nil
+	}
+
+	if len(data) == 0 {
+		return errCorrupt
+	}
+	modes := data[0]
+	if modes&3 != 0 {
+		return errCorrupt
+	}
+	data = data[1:]
+	if r.literalsLength, data, err = readSequenceTable(data, modes>>6, r.literalsLength,
+		literalsLengthDefaultTable, maxLiteralsLengthSymbol, maxLiteralsLengthLog); err != nil {
+		return err
+	}
+	if r.offset, data, err = readSequenceTable(data, (modes>>4)&3, r.offset,
+		offsetDefaultTable, maxOffsetSymbol, maxOffsetLog); err != nil {
+		return err
+	}
+	if r.matchLength, data, err = readSequenceTable(data, (modes>>2)&3, r.matchLength,
+		matchLengthDefaultTable, maxMatchLengthSymbol, maxMatchLengthLog); err != nil {
+		return err
+	}
+	return r.executeSequences(data, numSequences, literals)
+}
+
+// readSequenceTable reads the table of a sequence symbol type given its compression mode, returning the table and the
+// remaining data.
+func readSequenceTable(data []byte, mode uint8, previous, predefined *fseTable, maxSymbol int, maxLog uint) (*fseTable, []byte, error) {
+	switch mode {
+	case modePredefined:
+		return predefined, data, nil
+	case modeRLE:
+		if len(data) == 0 || int(data[0]) > maxSymbol {
+			return nil, nil, errCorrupt
+		}
+		return rleFSETable(data[0]), data[1:], nil
+	case modeFSE:
+		norm, accuracyLog, n, err := readFSETableDescription(data, maxSymbol, maxLog)
+		if err != nil {
+			return nil, nil, err
+		}
+		table, err := buildFSETable(norm, accuracyLog)
+		if err != nil {
+			return nil, nil, err
+		}
+		return table, data[n:], nil
+	default:
+		if previous == nil {
+			return nil, nil, errCorrupt
+		}
+		return previous, data, nil
+	}
+}
+
+// decodeLiterals decodes the literals section of a compressed block, returning the literals and the size of the
+// section.
+func (r *Reader) decodeLiterals(data []byte) ([]byte, int, error) {
+	if len(data) == 0 {
+		return nil, 0, errCorrupt
+	}
+	blockType := data[0] & 3
+	sizeFormat := (data[0] >> 2) & 3
+
+	if blockType == literalsBlockRaw || blockType == literalsBlockRLE {
+		var size, headerSize int
+		switch sizeFormat {
+		case 0, 2:
+			size, headerSize = int(data[0]>>3), 1
+		case 1:
+			if len(data) < 2 {
+				return nil, 0, errCorrupt
+			}
+			size, headerSize = int(data[0]>>4)|int(data[1])<<4, 2
+		default:
+			if len(data) < 3 {
+				return nil, 0, errCorrupt
+			}
+			size, headerSize = int(data[0]>>4)|int(data[1])<<4|int(data[2])<<12, 3
+		}
+		if size > r.blockSize {
+			return nil, 0, errCorrupt
+		}
+		if blockType == literalsBlockRaw {
+			if len(data) < headerSize+size {
+				return nil, 0, errCorrupt
+			}
+			return data[headerSize : headerSize+size], headerSize + size, nil
+		}
+		if len(data) < headerSize+1 {
+			return nil, 0, errCorrupt
+		}
+		literals := r.literalsBuffer(size)
+		for i := range literals {
+			literals[i] = data[headerSize]
+		}
+		return literals, headerSize + 1, nil
+	}
+
+	var size, compressedSize, headerSize int
+	switch sizeFormat {
+	case 0, 1:
+		if len(data) < 3 {
+			return nil, 0, errCorrupt
+		}
+		v := int(data[0]) | int(data[1])<<8 | int(data[2])<<16
+		size, compressedSize, headerSize = (v>>4)&0x3ff, (v>>14)&0x3ff, 3
+	case 2:
+		if len(data) < 4 {
+			return nil, 0, errCorrupt
+		}
+		v := int(binary.LittleEndian.Uint32(data))
+		size, compressedSize, headerSize = (v>>4)&0x3fff, (v>>18)&0x3fff, 4
+	default:
+		if len(data) < 5 {
+			return nil, 0, errCorrupt
+		}
+		v := int(binary.LittleEndian.Uint32(data)) | int(data[4])<<32
+		size, compressedSize, headerSize = (v>>4)&0x3ffff, (v>>22)&0x3ffff, 5
+	}
+	if size > r.blockSize || len(data) < headerSize+compressedSize {
+		return nil, 0, errCorrupt
+	}
+	src := data[headerSize : headerSize+compressedSize]
+	if blockType == literalsBlockCompressed {
+		table, n, err := readHuffmanTable(src)
+		if err != nil {
+			return nil, 0, err
+		}
+		r.huffman = table
+		src = src[n:]
+	} else if r.huffman == nil {
+		return nil, 0, errCorrupt
+	}
+	literals := r.literalsBuffer(size)
+	var err error
+	if sizeFormat == 0 {
+		err = r.huffman.decode(src, literals)
+	} else {
+		err = r.huffman.decode4(src, literals)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return literals, headerSize + compressedSize, nil
+}
+
+func (r *Reader) literalsBuffer(size int) []byte {
+	if cap(r.literals) < size {
+		r.literals = make([]byte, maxBlockSize)
+	}
+	return r.literals[:size]
+}
+
+// executeSequences decodes the sequences of a block from their bitstream and appends the block's content to the
+// history.
+func (r *Reader) executeSequences(data []byte, numSequences int, literals []byte) error {
+	br, err := newReverseBitReader(data)
+	if err != nil {
+		return err
+	}
+	literalsLengthTable, offsetTable, matchLengthTable := r.literalsLength, r.offset, r.matchLength
+	literalsLengthState := br.readBits(literalsLengthTable.accuracyLog)
+	offsetState := br.readBits(offsetTable.accuracyLog)
+	matchLengthState := br.readBits(matchLengthTable.accuracyLog)
+
+	start := len(r.history)
+	r.history = grow(r.history, r.blockSize)[:start]
+	for i := 0; i < numSequences; i++ {
+		literalsLengthEntry := literalsLengthTable.entries[literalsLengthState]
+		offsetEntry := offsetTable.entries[offsetState]
+		matchLengthEntry := matchLengthTable.entries[matchLengthState]
+		literalsLengthCode := literalsLengthEntry.symbol
+		offsetCode := offsetEntry.symbol
+		matchLengthCode := matchLengthEntry.symbol
+		if offsetCode > maxOffsetSymbol {
+			return errCorrupt
+		}
+
+		offsetValue := int(1)<<offsetCode + int(br.readBits(uint(offsetCode)))
+		matchLength := int(matchLengthBaselines[matchLengthCode]) +
+			int(br.readBits(uint(matchLengthBits[matchLengthCode])))
+		literalsLength := int(literalsLengthBaselines[literalsLengthCode]) +
+			int(br.readBits(uint(literalsLengthBits[literalsLengthCode])))
+
+		var offset int
+		if offsetValue > 3 {
+			offset = offsetValue - 3
+			r.repeatOffsets = [3]int{offset, r.repeatOffsets[0], r.repeatOffsets[1]}
+		} else {
+			index := offsetValue - 1
+			if literalsLength == 0 {
+				index++
+			}
+			switch index {
+			case 0:
+				offset = r.repeatOffsets[0]
+			case 1:
+				offset = r.repeatOffsets[1]
+				r.repeatOffsets = [3]int{offset, r.repeatOffsets[0], r.repeatOffsets[2]}
+			case 2:
+				offset = r.repeatOffsets[2]
+				r.repeatOffsets = [3]int{offset, r.repeatOffsets[0], r.repeatOffsets[1]}
+			default:
+				offset = r.repeatOffsets[0] - 1
+				if offset == 0 {
+					return errCorrupt
+				}
+				r.repeatOffsets = [3]int{offset, r.repeatOffsets[0], r.repeatOffsets[1]}
+			}
+		}
+
+		if i < numSequences-1 {
+			literalsLengthState = uint64(literalsLengthEntry.newState) +
+				br.readBits(uint(literalsLengthEntry.nbBits))
+			matchLengthState = uint64(matchLengthEntry.newState) + br.readBits(uint(matchLengthEntry.nbBits))
+			offsetState = uint64(offsetEntry.newState) + br.readBits(uint(offsetEntry.nbBits))
+		}
+		if br.overflowed() {
+			return errCorrupt
+		}
+
+		if literalsLength > len(literals) ||
+			len(r.history)-start+literalsLength+matchLength > r.blockSize {
+			return errCorrupt
+		}
+		r.history = append(r.history, literals[:literalsLength]...)
+		literals = literals[literalsLength:]
+		if offset > len(r.history) || offset > r.windowSize {
+			return errCorrupt
+		}
+		from := len(r.history) - offset
+		for matchLength > 0 {
+			// Copy in chunks no longer than the offset, as a match may overlap its own output.
+			n := min(matchLength, offset)
+			r.history = append(r.history, r.history[from:from+n]...)
+			from += n
+			matchLength -= n
+		}
+	}
+	if !br.finished() || len(r.history)-start+len(literals) > r.blockSize {
+		return errCorrupt
+	}
+	r.history = append(r.history, literals...)
+	return nil
+}
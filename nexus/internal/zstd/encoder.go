@@ -0,0 +1,398 @@
+package zstd
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/bits"
+	"sort"
+)
+
+const (
+	encoderWindowLog  = 20
+	encoderWindowSize = 1 << encoderWindowLog
+	encoderHashLog    = 16
+	minMatchLength    = 4
+)
+
+var errWriterClosed = errors.New("zstd: writer closed")
+
+// Writer compresses data written to it into a single zstd frame.
+type Writer struct {
+	w   io.Writer
+	err error
+
+	wroteHeader bool
+	hash        xxhash64
+	// Data written so far, the trailing window of which is kept for matches. Data at and after blockStart is yet to
+	// be compressed.
+	history    []byte
+	blockStart int
+	// Positions in history plus one of recently seen 4 byte sequences, indexed by their hash.
+	table [1 << encoderHashLog]int32
+
+	sequences []sequence
+	literals  []byte
+	out       []byte
+}
+
+// sequence copies literalsLength literals followed by matchLength bytes from offset bytes back.
+type sequence struct {
+	literalsLength int
+	matchLength    int
+	offset         int
+}
+
+// NewWriter returns a writer that compresses data written to it into w. The writer must be closed to complete the
+// frame.
+func NewWriter(w io.Writer) *Writer {
+	e := &Writer{w: w}
+	e.hash.reset()
+	return e
+}
+
+// Write implements the io.Writer interface.
+func (e *Writer) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	written := 0
+	for len(p) > 0 {
+		if len(e.history)-e.blockStart == maxBlockSize {
+			// Only compress a full block once more data arrives, the final block is written on close.
+			if err := e.writeBlock(false); err != nil {
+				e.err = err
+				return written, err
+			}
+		}
+		n := min(len(p), maxBlockSize-(len(e.history)-e.blockStart))
+		e.history = append(e.history, p[:n]...)
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close completes the frame. It does not close the underlying writer.
+func (e *Writer) Close() error {
+	if e.err != nil {
+		if e.err == errWriterClosed {
+			return nil
+		}
+		return e.err
+	}
+	if err := e.writeBlock(true); err != nil {
+		e.err = err
+		return err
+	}
+	var checksum [4]byte
+	binary.LittleEndian.PutUint32(checksum[:], uint32(e.hash.sum64()))
+	if _, err := e.w.Write(checksum[:]); err != nil {
+		e.err = err
+		return err
+	}
+	e.err = errWriterClosed
+	e.history = nil
+	return nil
+}
+
+func (e *Writer) writeBlock(last bool) error {
+	out := e.out[:0]
+	if !e.wroteHeader {
+		// A frame with a checksum and a window of 1<<encoderWindowLog bytes, of unknown content size.
+		out = binary.LittleEndian.AppendUint32(out, frameMagic)
+		out = append(out, 1<<2, (encoderWindowLog-10)<<3)
+		e.wroteHeader = true
+	}
+
+	block := e.history[e.blockStart:]
+	e.hash.write(block)
+	header := 0
+	if last {
+		header = 1
+	}
+	headerOffset := len(out)
+	out = append(out, 0, 0, 0)
+	switch {
+	case len(block) > 0 && isRLE(block):
+		header |= blockTypeRLE<<1 | len(block)<<3
+		out = append(out, block[0])
+	default:
+		contentOffset := len(out)
+		out = e.compressBlock(out)
+		if size := len(out) - contentOffset; size > 0 && size < len(block) {
+			header |= blockTypeCompressed<<1 | size<<3
+		} else {
+			out = append(out[:contentOffset], block...)
+			header |= blockTypeRaw<<1 | len(block)<<3
+		}
+	}
+	out[headerOffset] = byte(header)
+	out[headerOffset+1] = byte(header >> 8)
+	out[headerOffset+2] = byte(header >> 16)
+	e.out = out
+	if _, err := e.w.Write(out); err != nil {
+		return err
+	}
+
+	e.blockStart = len(e.history)
+	if len(e.history) >= 2*encoderWindowSize {
+		e.slide()
+	}
+	return nil
+}
+
+func isRLE(b []byte) bool {
+	for _, c := range b[1:] {
+		if c != b[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// slide discards history beyond the window, rebasing the positions in the match table.
+func (e *Writer) slide() {
+	shift := len(e.history) - encoderWindowSize
+	n := copy(e.history, e.history[shift:])
+	e.history = e.history[:n]
+	e.blockStart -= shift
+	for i, v := range e.table {
+		if int(v) > shift {
+			e.table[i] = v - int32(shift)
+		} else {
+			e.table[i] = 0
+		}
+	}
+}
+
+func hash4(v uint32) uint32 {
+	return (v * 2654435761) >> (32 - encoderHashLog)
+}
+
+// findSequences greedily matches the block at the end of the history against the history.
+func (e *Writer) findSequences() {
+	e.sequences = e.sequences[:0]
+	e.literals = e.literals[:0]
+	history := e.history
+	start, end := e.blockStart, len(history)
+	literalsStart := start
+	// Leave room for 4 byte loads.
+	limit := end - minMatchLength
+	for i := start; i < limit; {
+		v := binary.LittleEndian.Uint32(history[i:])
+		h := hash4(v)
+		candidate := int(e.table[h]) - 1
+		e.table[h] = int32(i + 1)
+		if candidate < 0 || i-candidate > encoderWindowSize || binary.LittleEndian.Uint32(history[candidate:]) != v {
+			i += 1 + (i-literalsStart)>>6
+			continue
+		}
+		matchLength := minMatchLength
+		for i+matchLength < end && history[candidate+matchLength] == history[i+matchLength] {
+			matchLength++
+		}
+		for i > literalsStart && candidate > 0 && history[i-1] == history[candidate-1] {
+			i--
+			candidate--
+			matchLength++
+		}
+		e.literals = append(e.literals, history[literalsStart:i]...)
+		e.sequences = append(e.sequences, sequence{
+			literalsLength: i - literalsStart,
+			matchLength:    matchLength,
+			offset:         i - candidate,
+		})
+		i += matchLength
+		literalsStart = i
+		if i-2 < limit {
+			e.table[hash4(binary.LittleEndian.Uint32(history[i-2:]))] = int32(i - 2 + 1)
+		}
+	}
+	e.literals = append(e.literals, history[literalsStart:end]...)
+}
+
+// compressBlock appends the compressed form of the block at the end of the history.
+func (e *Writer) compressBlock(out []byte) []byte {
+	e.findSequences()
+	out = appendLiterals(out, e.literals)
+
+	numSequences := len(e.sequences)
+	switch {
+	case numSequences < 128:
+		out = append(out, byte(numSequences))
+	case numSequences < 0x7f00:
+		out = append(out, byte(numSequences>>8+128), byte(numSequences))
+	default:
+		out = append(out, 255, byte(numSequences-0x7f00), byte((numSequences-0x7f00)>>8))
+	}
+	if numSequences == 0 {
+		return out
+	}
+	return e.appendSequences(out)
+}
+
+// sequenceCodes are the codes of a sequence and the extra bits following them.
+type sequenceCodes struct {
+	literalsLength, matchLength, offset                uint8
+	literalsLengthExtra, matchLengthExtra, offsetExtra uint32
+}
+
+// appendSequences appends the compression modes and the bitstream of the sequences, which are encoded in reverse for
+// decoders to read them forwards.
+func (e *Writer) appendSequences(out []byte) []byte {
+	coded := make([]sequenceCodes, len(e.sequences))
+	var literalsLengthCounts [maxLiteralsLengthSymbol + 1]int
+	var matchLengthCounts [maxMatchLengthSymbol + 1]int
+	var offsetCounts [maxOffsetSymbol + 1]int
+	for i, s := range e.sequences {
+		c := &coded[i]
+		c.literalsLength = findCode(literalsLengthBaselines[:], uint32(s.literalsLength))
+		c.literalsLengthExtra = uint32(s.literalsLength) - literalsLengthBaselines[c.literalsLength]
+		c.matchLength = findCode(matchLengthBaselines[:], uint32(s.matchLength))
+		c.matchLengthExtra = uint32(s.matchLength) - matchLengthBaselines[c.matchLength]
+		// Offsets are never coded as repeat offsets.
+		offsetValue := uint32(s.offset + 3)
+		c.offset = uint8(bits.Len32(offsetValue) - 1)
+		c.offsetExtra = offsetValue - 1<<c.offset
+		literalsLengthCounts[c.literalsLength]++
+		matchLengthCounts[c.matchLength]++
+		offsetCounts[c.offset]++
+	}
+
+	modesOffset := len(out)
+	out = append(out, 0)
+	literalsLengthMode, literalsLengthEncoder, out := appendSequenceTable(out, literalsLengthCounts[:], len(coded),
+		maxLiteralsLengthLog, literalsLengthDefaultDistribution, 6, literalsLengthDefaultEncoder)
+	offsetMode, offsetEncoder, out := appendSequenceTable(out, offsetCounts[:], len(coded),
+		maxOffsetLog, offsetDefaultDistribution, 5, offsetDefaultEncoder)
+	matchLengthMode, matchLengthEncoder, out := appendSequenceTable(out, matchLengthCounts[:], len(coded),
+		maxMatchLengthLog, matchLengthDefaultDistribution, 6, matchLengthDefaultEncoder)
+	out[modesOffset] = byte(literalsLengthMode<<6 | offsetMode<<4 | matchLengthMode<<2)
+
+	w := bitWriter{out: out}
+	var literalsLength, matchLength, offset fseEncoderState
+	lastCodes := coded[len(coded)-1]
+	matchLength.init(matchLengthEncoder, lastCodes.matchLength)
+	offset.init(offsetEncoder, lastCodes.offset)
+	literalsLength.init(literalsLengthEncoder, lastCodes.literalsLength)
+	writeExtra := func(c sequenceCodes) {
+		w.addBits(uint64(c.literalsLengthExtra), uint(literalsLengthBits[c.literalsLength]))
+		w.addBits(uint64(c.matchLengthExtra), uint(matchLengthBits[c.matchLength]))
+		w.addBits(uint64(c.offsetExtra), uint(c.offset))
+	}
+	writeExtra(lastCodes)
+	for i := len(coded) - 2; i >= 0; i-- {
+		c := coded[i]
+		offset.encode(&w, c.offset)
+		matchLength.encode(&w, c.matchLength)
+		literalsLength.encode(&w, c.literalsLength)
+		writeExtra(c)
+	}
+	matchLength.flush(&w)
+	offset.flush(&w)
+	literalsLength.flush(&w)
+	return w.close()
+}
+
+// appendSequenceTable picks the cheapest way to code symbols with the given counts: a single repeated symbol, the
+// predefined distribution, or a distribution fitted to the counts, whose description it appends. Returns the mode and
+// the encoder to use, nil for a repeated symbol.
+func appendSequenceTable(
+	out []byte,
+	counts []int,
+	total int,
+	maxLog uint,
+	predefined []int16,
+	predefinedLog uint,
+	predefinedEncoder *fseEncoder,
+) (int, *fseEncoder, []byte) {
+	distinct, symbol := 0, 0
+	for s, count := range counts {
+		if count > 0 {
+			distinct++
+			symbol = s
+		}
+	}
+	if distinct == 1 {
+		return modeRLE, nil, append(out, byte(symbol))
+	}
+	accuracyLog := fseAccuracyLog(total, distinct, maxLog)
+	norm := normalizeCounts(counts, total, accuracyLog)
+	description := appendFSETableDescription(nil, norm, accuracyLog)
+	if fseCost(counts, norm, accuracyLog)+float64(8*len(description)) >= fseCost(counts, predefined, predefinedLog) {
+		return modePredefined, predefinedEncoder, out
+	}
+	return modeFSE, newFSEEncoder(norm, accuracyLog), append(out, description...)
+}
+
+// findCode returns the code of the largest baseline not exceeding v.
+func findCode(baselines []uint32, v uint32) uint8 {
+	return uint8(sort.Search(len(baselines), func(i int) bool { return baselines[i] > v }) - 1)
+}
+
+// appendLiterals appends a literals section, Huffman coding the literals if that makes them smaller.
+func appendLiterals(out []byte, literals []byte) []byte {
+	var histogram [256]int
+	for _, c := range literals {
+		histogram[c]++
+	}
+	if len(literals) > 0 && histogram[literals[0]] == len(literals) {
+		return appendLiteralsHeader(out, literalsBlockRLE, len(literals), literals[0])
+	}
+	// Small sections don't benefit from Huffman coding.
+	if huffman := newHuffmanEncoder(&histogram); len(literals) >= 64 && huffman != nil {
+		start := len(out)
+		headerSize := 3
+		if len(literals) >= 1024 {
+			headerSize = 4
+		}
+		if len(literals) >= 16384 {
+			headerSize = 5
+		}
+		out = append(out, make([]byte, headerSize)...)
+		content := len(out)
+		var ok bool
+		if out, ok = huffman.writeTable(out); !ok {
+			out = out[:start]
+			out = appendLiteralsHeader(out, literalsBlockRaw, len(literals), 0)
+			return append(out, literals...)
+		}
+		sizeFormat := 0
+		if len(literals) < 256 {
+			out = huffman.encode(out, literals)
+			ok = true
+		} else {
+			sizeFormat = headerSize - 2
+			out, ok = huffman.encode4(out, literals)
+		}
+		compressedSize := len(out) - content
+		if ok && compressedSize < len(literals) && compressedSize < 1<<(10+4*(headerSize-3)) {
+			v := uint64(literalsBlockCompressed) | uint64(sizeFormat)<<2 | uint64(len(literals))<<4 |
+				uint64(compressedSize)<<(4+10+4*(headerSize-3))
+			for i := 0; i < headerSize; i++ {
+				out[start+i] = byte(v >> (8 * i))
+			}
+			return out
+		}
+		out = out[:start]
+	}
+	out = appendLiteralsHeader(out, literalsBlockRaw, len(literals), 0)
+	return append(out, literals...)
+}
+
+// appendLiteralsHeader appends the header of a raw or RLE literals section, followed by the byte b for RLE sections.
+func appendLiteralsHeader(out []byte, blockType int, size int, b byte) []byte {
+	switch {
+	case size < 32:
+		out = append(out, byte(blockType|size<<3))
+	case size < 4096:
+		out = append(out, byte(blockType|1<<2|size<<4), byte(size>>4))
+	default:
+		out = append(out, byte(blockType|3<<2|size<<4), byte(size>>4), byte(size>>12))
+	}
+	if blockType == literalsBlockRLE {
+		out = append(out, b)
+	}
+	return out
+}
@@ -0,0 +1,368 @@
+package zstd
+
+import (
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// fseEntry is a state of an FSE decoding table.
+type fseEntry struct {
+	symbol   uint8
+	nbBits   uint8
+	newState uint16
+}
+
+// fseTable is an FSE decoding table with 1<<accuracyLog states.
+type fseTable struct {
+	accuracyLog uint
+	entries     []fseEntry
+}
+
+// spreadSymbols assigns symbols to the states of a table of the given accuracy log following the distribution norm,
+// where -1 denotes a "less than 1" probability. Returns false if norm does not add up to the table size.
+func spreadSymbols(norm []int16, accuracyLog uint, symbols []uint8) bool {
+	size := 1 << accuracyLog
+	high := size - 1
+	for s, count := range norm {
+		if count == -1 {
+			symbols[high] = uint8(s)
+			high--
+		}
+	}
+	step := size>>1 + size>>3 + 3
+	mask := size - 1
+	pos := 0
+	for s, count := range norm {
+		for i := 0; i < int(count); i++ {
+			symbols[pos] = uint8(s)
+			pos = (pos + step) & mask
+			for pos > high {
+				pos = (pos + step) & mask
+			}
+		}
+	}
+	return pos == 0
+}
+
+func buildFSETable(norm []int16, accuracyLog uint) (*fseTable, error) {
+	size := 1 << accuracyLog
+	total := 0
+	for _, count := range norm {
+		if count < -1 {
+			return nil, errCorrupt
+		}
+		if count == -1 {
+			total++
+		} else {
+			total += int(count)
+		}
+	}
+	if total != size || len(norm) > 256 {
+		return nil, errCorrupt
+	}
+	symbols := make([]uint8, size)
+	if !spreadSymbols(norm, accuracyLog, symbols) {
+		return nil, errCorrupt
+	}
+	next := make([]uint16, len(norm))
+	for s, count := range norm {
+		if count == -1 {
+			next[s] = 1
+		} else {
+			next[s] = uint16(count)
+		}
+	}
+	table := &fseTable{accuracyLog: accuracyLog, entries: make([]fseEntry, size)}
+	for u, s := range symbols {
+		x := next[s]
+		next[s]++
+		nbBits := accuracyLog - uint(bits.Len16(x)-1)
+		table.entries[u] = fseEntry{symbol: s, nbBits: uint8(nbBits), newState: uint16(int(x)<<nbBits - size)}
+	}
+	return table, nil
+}
+
+func mustBuildFSETable(norm []int16, accuracyLog uint) *fseTable {
+	table, err := buildFSETable(norm, accuracyLog)
+	if err != nil {
+		panic(err)
+	}
+	return table
+}
+
+// rleFSETable returns a table that always decodes symbol without consuming any bits.
+func rleFSETable(symbol uint8) *fseTable {
+	return &fseTable{entries: []fseEntry{{symbol: symbol}}}
+}
+
+// readFSETableDescription decodes a normalized distribution of at most maxSymbol+1 symbols with an accuracy log of up
+// to maxLog, returning the distribution, its accuracy log, and the number of bytes consumed.
+func readFSETableDescription(data []byte, maxSymbol int, maxLog uint) ([]int16, uint, int, error) {
+	r := forwardBitReader{data: data}
+	accuracyLog := uint(r.readBits(4)) + 5
+	if accuracyLog > maxLog {
+		return nil, 0, 0, errCorrupt
+	}
+	remaining := 1<<accuracyLog + 1
+	threshold := 1 << accuracyLog
+	nbBits := accuracyLog + 1
+	norm := make([]int16, 0, maxSymbol+1)
+	previousZero := false
+	for remaining > 1 && len(norm) <= maxSymbol {
+		if previousZero {
+			n := len(norm)
+			for {
+				repeat := int(r.readBits(2))
+				n += repeat
+				if repeat != 3 || n > maxSymbol {
+					break
+				}
+			}
+			if n > maxSymbol {
+				return nil, 0, 0, errCorrupt
+			}
+			for len(norm) < n {
+				norm = append(norm, 0)
+			}
+		}
+		max := 2*threshold - 1 - remaining
+		var count int
+		if low := int(r.peek(nbBits - 1)); low < max {
+			count = low
+			r.readBits(nbBits - 1)
+		} else {
+			count = int(r.readBits(nbBits))
+			if count >= threshold {
+				count -= max
+			}
+		}
+		count--
+		if count < 0 {
+			remaining += count
+		} else {
+			remaining -= count
+		}
+		if remaining < 1 {
+			return nil, 0, 0, errCorrupt
+		}
+		norm = append(norm, int16(count))
+		previousZero = count == 0
+		for remaining < threshold {
+			nbBits--
+			threshold >>= 1
+		}
+	}
+	if remaining != 1 || r.bytesRead() > len(data) {
+		return nil, 0, 0, errCorrupt
+	}
+	return norm, accuracyLog, r.bytesRead(), nil
+}
+
+// fseEncoder encodes symbols with the FSE distribution it was built from.
+type fseEncoder struct {
+	accuracyLog uint
+	stateTable  []uint16
+	transforms  []fseTransform
+}
+
+type fseTransform struct {
+	deltaNbBits    uint32
+	deltaFindState int32
+}
+
+func newFSEEncoder(norm []int16, accuracyLog uint) *fseEncoder {
+	size := 1 << accuracyLog
+	symbols := make([]uint8, size)
+	if !spreadSymbols(norm, accuracyLog, symbols) {
+		panic("zstd: invalid distribution")
+	}
+	cumulative := make([]int, len(norm)+1)
+	for s, count := range norm {
+		if count == -1 {
+			cumulative[s+1] = cumulative[s] + 1
+		} else {
+			cumulative[s+1] = cumulative[s] + int(count)
+		}
+	}
+	e := &fseEncoder{
+		accuracyLog: accuracyLog,
+		stateTable:  make([]uint16, size),
+		transforms:  make([]fseTransform, len(norm)),
+	}
+	for u, s := range symbols {
+		e.stateTable[cumulative[s]] = uint16(size + u)
+		cumulative[s]++
+	}
+	total := 0
+	for s, count := range norm {
+		switch count {
+		case 0:
+			e.transforms[s].deltaNbBits = uint32((accuracyLog+1)<<16 - uint(size))
+		case -1, 1:
+			e.transforms[s] = fseTransform{
+				deltaNbBits:    uint32(accuracyLog<<16 - uint(size)),
+				deltaFindState: int32(total - 1),
+			}
+			total++
+		default:
+			maxBitsOut := accuracyLog - uint(bits.Len16(uint16(count-1))-1)
+			minStatePlus := uint(count) << maxBitsOut
+			e.transforms[s] = fseTransform{
+				deltaNbBits:    uint32(maxBitsOut<<16 - minStatePlus),
+				deltaFindState: int32(total - int(count)),
+			}
+			total += int(count)
+		}
+	}
+	return e
+}
+
+// fseEncoderState is the state of an encoder encoding a sequence of symbols in reverse.
+type fseEncoderState struct {
+	encoder *fseEncoder
+	value   uint32
+}
+
+// init initializes the state with the last symbol of the sequence without writing any bits. A nil encoder codes a
+// single repeated symbol, for which no bits are written at all.
+func (s *fseEncoderState) init(encoder *fseEncoder, symbol uint8) {
+	s.encoder = encoder
+	if encoder == nil {
+		return
+	}
+	t := encoder.transforms[symbol]
+	nbBitsOut := (t.deltaNbBits + 1<<15) >> 16
+	value := nbBitsOut<<16 - t.deltaNbBits
+	s.value = uint32(encoder.stateTable[int32(value>>nbBitsOut)+t.deltaFindState])
+}
+
+func (s *fseEncoderState) encode(w *bitWriter, symbol uint8) {
+	if s.encoder == nil {
+		return
+	}
+	t := s.encoder.transforms[symbol]
+	nbBitsOut := (s.value + t.deltaNbBits) >> 16
+	w.addBits(uint64(s.value), uint(nbBitsOut))
+	s.value = uint32(s.encoder.stateTable[int32(s.value>>nbBitsOut)+t.deltaFindState])
+}
+
+// flush writes the final state, which the decoder starts from.
+func (s *fseEncoderState) flush(w *bitWriter) {
+	if s.encoder == nil {
+		return
+	}
+	w.addBits(uint64(s.value), s.encoder.accuracyLog)
+}
+
+// fseAccuracyLog returns the accuracy log of a table for total symbols of distinct kinds, at most maxLog.
+func fseAccuracyLog(total, distinct int, maxLog uint) uint {
+	accuracyLog := min(maxLog, max(5, uint(bits.Len(uint(total)))))
+	for 1<<accuracyLog < distinct {
+		accuracyLog++
+	}
+	return accuracyLog
+}
+
+// normalizeCounts scales counts, which add up to total, to a distribution for a table of 1<<accuracyLog states. Each
+// present symbol is given at least one state, the remaining states are apportioned by largest remainder.
+func normalizeCounts(counts []int, total int, accuracyLog uint) []int16 {
+	last := len(counts) - 1
+	for last > 0 && counts[last] == 0 {
+		last--
+	}
+	norm := make([]int16, last+1)
+	present := 0
+	for _, count := range counts[:last+1] {
+		if count > 0 {
+			present++
+		}
+	}
+	size := 1 << accuracyLog
+	spare := size - present
+	type remainder struct {
+		symbol int
+		value  int
+	}
+	remainders := make([]remainder, 0, present)
+	assigned := 0
+	for s, count := range counts[:last+1] {
+		if count == 0 {
+			continue
+		}
+		share := count * spare
+		norm[s] = int16(1 + share/total)
+		assigned += int(norm[s])
+		remainders = append(remainders, remainder{s, share % total})
+	}
+	sort.SliceStable(remainders, func(i, j int) bool { return remainders[i].value > remainders[j].value })
+	for i := 0; assigned < size; i++ {
+		norm[remainders[i%len(remainders)].symbol]++
+		assigned++
+	}
+	return norm
+}
+
+// appendFSETableDescription appends the description of a distribution as read by readFSETableDescription.
+func appendFSETableDescription(out []byte, norm []int16, accuracyLog uint) []byte {
+	w := bitWriter{out: out}
+	w.addBits(uint64(accuracyLog-5), 4)
+	remaining := 1<<accuracyLog + 1
+	threshold := 1 << accuracyLog
+	nbBits := accuracyLog + 1
+	previousZero := false
+	for s := 0; s < len(norm) && remaining > 1; {
+		if previousZero {
+			start := s
+			for norm[s] == 0 {
+				s++
+			}
+			for ; s-start >= 3; start += 3 {
+				w.addBits(3, 2)
+			}
+			w.addBits(uint64(s-start), 2)
+		}
+		count := int(norm[s])
+		s++
+		max := 2*threshold - 1 - remaining
+		if count < 0 {
+			remaining += count
+		} else {
+			remaining -= count
+		}
+		value := count + 1
+		if value >= threshold {
+			value += max
+		}
+		if value < max {
+			w.addBits(uint64(value), nbBits-1)
+		} else {
+			w.addBits(uint64(value), nbBits)
+		}
+		previousZero = value == 1
+		for remaining < threshold {
+			nbBits--
+			threshold >>= 1
+		}
+	}
+	if w.n > 0 {
+		w.out = append(w.out, byte(w.container))
+	}
+	return w.out
+}
+
+// fseCost estimates the number of bits needed to code symbols with the given counts using a distribution.
+func fseCost(counts []int, norm []int16, accuracyLog uint) float64 {
+	cost := 0.0
+	for s, count := range counts {
+		if count == 0 {
+			continue
+		}
+		if s >= len(norm) || norm[s] == 0 {
+			return math.Inf(1)
+		}
+		probability := max(1, int(norm[s]))
+		cost += float64(count) * (float64(accuracyLog) - math.Log2(float64(probability)))
+	}
+	return cost
+}
@@ -0,0 +1,390 @@
+package zstd
+
+import (
+	"math/bits"
+	"sort"
+)
+
+const maxHuffmanBits = 11
+
+type huffmanEntry struct {
+	symbol uint8
+	nbBits uint8
+}
+
+// huffmanTable is a Huffman decoding table indexed by the next maxBits bits of a stream.
+type huffmanTable struct {
+	maxBits uint
+	entries []huffmanEntry
+}
+
+// readHuffmanTable decodes a Huffman tree description, returning the decoding table and the number of bytes consumed.
+func readHuffmanTable(data []byte) (*huffmanTable, int, error) {
+	if len(data) == 0 {
+		return nil, 0, errCorrupt
+	}
+	var weights [256]uint8
+	var n int
+	header := int(data[0])
+	consumed := 1
+	if header < 128 {
+		if 1+header > len(data) {
+			return nil, 0, errCorrupt
+		}
+		var err error
+		if n, err = decodeHuffmanWeights(data[1:1+header], weights[:]); err != nil {
+			return nil, 0, err
+		}
+		consumed += header
+	} else {
+		n = header - 127
+		size := (n + 1) / 2
+		if 1+size > len(data) {
+			return nil, 0, errCorrupt
+		}
+		for i := 0; i < n; i++ {
+			b := data[1+i/2]
+			if i%2 == 0 {
+				weights[i] = b >> 4
+			} else {
+				weights[i] = b & 0xf
+			}
+		}
+		consumed += size
+	}
+	if n > 255 {
+		return nil, 0, errCorrupt
+	}
+
+	// The weight of the last symbol is implied by the others completing the tree.
+	total := 0
+	for _, w := range weights[:n] {
+		if w > maxHuffmanBits {
+			return nil, 0, errCorrupt
+		}
+		if w > 0 {
+			total += 1 << (w - 1)
+		}
+	}
+	if total == 0 {
+		return nil, 0, errCorrupt
+	}
+	maxBits := uint(bits.Len(uint(total)))
+	if maxBits > maxHuffmanBits {
+		return nil, 0, errCorrupt
+	}
+	rest := 1<<maxBits - total
+	if rest&(rest-1) != 0 {
+		return nil, 0, errCorrupt
+	}
+	weights[n] = uint8(bits.Len(uint(rest)))
+	numSymbols := n + 1
+
+	table := &huffmanTable{maxBits: maxBits, entries: make([]huffmanEntry, 1<<maxBits)}
+	pos := 0
+	for w := uint8(1); uint(w) <= maxBits; w++ {
+		for s := 0; s < numSymbols; s++ {
+			if weights[s] != w {
+				continue
+			}
+			entry := huffmanEntry{symbol: uint8(s), nbBits: uint8(maxBits + 1 - uint(w))}
+			for end := pos + 1<<(w-1); pos < end; pos++ {
+				table.entries[pos] = entry
+			}
+		}
+	}
+	return table, consumed, nil
+}
+
+// decodeHuffmanWeights decodes FSE compressed Huffman weights into weights, returning the number of weights decoded.
+func decodeHuffmanWeights(data []byte, weights []uint8) (int, error) {
+	norm, accuracyLog, consumed, err := readFSETableDescription(data, maxHuffmanBits, 6)
+	if err != nil {
+		return 0, err
+	}
+	table, err := buildFSETable(norm, accuracyLog)
+	if err != nil {
+		return 0, err
+	}
+	r, err := newReverseBitReader(data[consumed:])
+	if err != nil {
+		return 0, err
+	}
+	// Two interleaved states share the stream until it overflows.
+	states := [2]uint64{r.readBits(accuracyLog), r.readBits(accuracyLog)}
+	n := 0
+	for i := 0; ; i ^= 1 {
+		if n >= len(weights)-1 {
+			return 0, errCorrupt
+		}
+		entry := table.entries[states[i]]
+		weights[n] = entry.symbol
+		n++
+		states[i] = uint64(entry.newState) + r.readBits(uint(entry.nbBits))
+		if r.overflowed() {
+			weights[n] = table.entries[states[i^1]].symbol
+			return n + 1, nil
+		}
+	}
+}
+
+// decode decodes len(out) symbols from a single Huffman coded stream, which must be consumed exactly.
+func (t *huffmanTable) decode(data []byte, out []byte) error {
+	r, err := newReverseBitReader(data)
+	if err != nil {
+		return err
+	}
+	for i := range out {
+		entry := t.entries[r.peek(t.maxBits)]
+		out[i] = entry.symbol
+		r.skip(uint(entry.nbBits))
+	}
+	if !r.finished() {
+		return errCorrupt
+	}
+	return nil
+}
+
+// decode4 decodes len(out) symbols from four Huffman coded streams preceded by a jump table.
+func (t *huffmanTable) decode4(data []byte, out []byte) error {
+	if len(data) < 6 {
+		return errCorrupt
+	}
+	sizes := [3]int{
+		int(data[0]) | int(data[1])<<8,
+		int(data[2]) | int(data[3])<<8,
+		int(data[4]) | int(data[5])<<8,
+	}
+	data = data[6:]
+	segment := (len(out) + 3) / 4
+	if 3*segment > len(out) {
+		return errCorrupt
+	}
+	for i := 0; i < 4; i++ {
+		stream := data
+		if i < 3 {
+			if sizes[i] > len(data) {
+				return errCorrupt
+			}
+			stream, data = data[:sizes[i]], data[sizes[i]:]
+		}
+		end := (i + 1) * segment
+		if i == 3 {
+			end = len(out)
+		}
+		if err := t.decode(stream, out[i*segment:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// huffmanEncoder encodes literals with a Huffman code built from their histogram.
+type huffmanEncoder struct {
+	codes   [256]uint16
+	lengths [256]uint8
+	// Highest symbol present in the histogram.
+	maxSymbol int
+	maxBits   uint
+}
+
+// newHuffmanEncoder builds a length limited Huffman code for the histogram of literals. Returns nil if there are
+// fewer than two distinct symbols.
+func newHuffmanEncoder(histogram *[256]int) *huffmanEncoder {
+	type leaf struct {
+		symbol int
+		freq   int
+	}
+	var leaves []leaf
+	for s, freq := range histogram {
+		if freq > 0 {
+			leaves = append(leaves, leaf{s, freq})
+		}
+	}
+	if len(leaves) < 2 {
+		return nil
+	}
+	sort.Slice(leaves, func(i, j int) bool {
+		if leaves[i].freq != leaves[j].freq {
+			return leaves[i].freq < leaves[j].freq
+		}
+		return leaves[i].symbol < leaves[j].symbol
+	})
+
+	e := &huffmanEncoder{}
+	freqs := make([]int, len(leaves))
+	for i, l := range leaves {
+		freqs[i] = l.freq
+		if l.symbol > e.maxSymbol {
+			e.maxSymbol = l.symbol
+		}
+	}
+	// Flatten the distribution until the code fits in maxHuffmanBits. Leaves stay sorted since halving is monotonic.
+	var lengths []int
+	for {
+		lengths = huffmanCodeLengths(freqs)
+		e.maxBits = 0
+		for _, length := range lengths {
+			e.maxBits = max(e.maxBits, uint(length))
+		}
+		if e.maxBits <= maxHuffmanBits {
+			break
+		}
+		for i := range freqs {
+			freqs[i] = (freqs[i] + 1) / 2
+		}
+	}
+	for i, l := range leaves {
+		e.lengths[l.symbol] = uint8(lengths[i])
+	}
+
+	// Assign codes in the order decoders fill their tables: by increasing weight, then symbol.
+	pos := 0
+	for w := uint(1); w <= e.maxBits; w++ {
+		length := uint8(e.maxBits + 1 - w)
+		for s := 0; s <= e.maxSymbol; s++ {
+			if e.lengths[s] == length {
+				e.codes[s] = uint16(pos >> (w - 1))
+				pos += 1 << (w - 1)
+			}
+		}
+	}
+	return e
+}
+
+// huffmanCodeLengths returns the code lengths of a Huffman code for frequencies sorted in ascending order.
+func huffmanCodeLengths(freqs []int) []int {
+	n := len(freqs)
+	nodeFreqs := make([]int, 2*n-1)
+	parents := make([]int, 2*n-1)
+	copy(nodeFreqs, freqs)
+	nextLeaf, nextNode := 0, n
+	pick := func(created int) int {
+		if nextLeaf < n && (nextNode >= created || nodeFreqs[nextLeaf] <= nodeFreqs[nextNode]) {
+			nextLeaf++
+			return nextLeaf - 1
+		}
+		nextNode++
+		return nextNode - 1
+	}
+	for k := n; k < 2*n-1; k++ {
+		a := pick(k)
+		b := pick(k)
+		nodeFreqs[k] = nodeFreqs[a] + nodeFreqs[b]
+		parents[a], parents[b] = k, k
+	}
+	depths := make([]int, 2*n-1)
+	for k := 2*n - 3; k >= 0; k-- {
+		depths[k] = depths[parents[k]] + 1
+	}
+	return depths[:n]
+}
+
+// writeTable appends the tree description, FSE coding the weights unless writing them directly is smaller. Returns
+// false if the weights can't be described.
+func (e *huffmanEncoder) writeTable(out []byte) ([]byte, bool) {
+	// The weight of the last symbol is implied.
+	weights := make([]uint8, e.maxSymbol)
+	for s := range weights {
+		weights[s] = e.weight(s)
+	}
+	start := len(out)
+	out, ok := appendCompressedWeights(out, weights)
+	if ok && (len(weights) > 128 || len(out)-start <= 1+(len(weights)+1)/2) {
+		return out, true
+	}
+	out = out[:start]
+	if len(weights) > 128 {
+		return out, false
+	}
+	out = append(out, byte(127+len(weights)))
+	for i := 0; i < len(weights); i += 2 {
+		b := weights[i] << 4
+		if i+1 < len(weights) {
+			b |= weights[i+1]
+		}
+		out = append(out, b)
+	}
+	return out, true
+}
+
+// appendCompressedWeights appends FSE coded Huffman weights, coded with two interleaved states. Returns false if the
+// weights can't be FSE coded in less than 128 bytes.
+func appendCompressedWeights(out []byte, weights []uint8) ([]byte, bool) {
+	n := len(weights)
+	counts := make([]int, maxHuffmanBits+1)
+	distinct := 0
+	for _, w := range weights {
+		if counts[w] == 0 {
+			distinct++
+		}
+		counts[w]++
+	}
+	if distinct < 2 {
+		return out, false
+	}
+	accuracyLog := fseAccuracyLog(n, distinct, 6)
+	norm := normalizeCounts(counts, n, accuracyLog)
+	start := len(out)
+	out = appendFSETableDescription(append(out, 0), norm, accuracyLog)
+	encoder := newFSEEncoder(norm, accuracyLog)
+	w := bitWriter{out: out}
+	// Decoders alternate between the states starting with the first, the last two weights initialize them.
+	var states [2]fseEncoderState
+	states[(n-1)%2].init(encoder, weights[n-1])
+	states[(n-2)%2].init(encoder, weights[n-2])
+	for i := n - 3; i >= 0; i-- {
+		states[i%2].encode(&w, weights[i])
+	}
+	states[1].flush(&w)
+	states[0].flush(&w)
+	out = w.close()
+	size := len(out) - start - 1
+	if size >= 128 {
+		return out[:start], false
+	}
+	out[start] = byte(size)
+	return out, true
+}
+
+func (e *huffmanEncoder) weight(symbol int) uint8 {
+	if e.lengths[symbol] == 0 {
+		return 0
+	}
+	return uint8(e.maxBits+1) - e.lengths[symbol]
+}
+
+// encode appends a single Huffman coded stream of literals.
+func (e *huffmanEncoder) encode(out []byte, literals []byte) []byte {
+	w := bitWriter{out: out}
+	for i := len(literals) - 1; i >= 0; i-- {
+		s := literals[i]
+		w.addBits(uint64(e.codes[s]), uint(e.lengths[s]))
+	}
+	return w.close()
+}
+
+// encode4 appends four Huffman coded streams of literals preceded by a jump table. Returns false if a stream is too
+// large for the jump table.
+func (e *huffmanEncoder) encode4(out []byte, literals []byte) ([]byte, bool) {
+	segment := (len(literals) + 3) / 4
+	start := len(out)
+	out = append(out, 0, 0, 0, 0, 0, 0)
+	for i := 0; i < 4; i++ {
+		end := (i + 1) * segment
+		if i == 3 {
+			end = len(literals)
+		}
+		streamStart := len(out)
+		out = e.encode(out, literals[i*segment:end])
+		if i < 3 {
+			size := len(out) - streamStart
+			if size > 0xffff {
+				return out, false
+			}
+			out[start+2*i] = byte(size)
+			out[start+2*i+1] = byte(size >> 8)
+		}
+	}
+	return out, true
+}
@@ -0,0 +1,113 @@
+package zstd
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+const (
+	prime64n1 uint64 = 11400714785074694791
+	prime64n2 uint64 = 14029467366897019727
+	prime64n3 uint64 = 1609587929392839161
+	prime64n4 uint64 = 9650029242287828579
+	prime64n5 uint64 = 2870177450012600261
+)
+
+// xxhash64 is a streaming implementation of the XXH64 hash with a zero seed, used for frame checksums.
+type xxhash64 struct {
+	v1, v2, v3, v4 uint64
+	total          uint64
+	mem            [32]byte
+	n              int
+}
+
+func (h *xxhash64) reset() {
+	// The primes are held in variables for the additions to wrap around.
+	p1, p2 := prime64n1, prime64n2
+	h.v1 = p1 + p2
+	h.v2 = p2
+	h.v3 = 0
+	h.v4 = -p1
+	h.total = 0
+	h.n = 0
+}
+
+func xxhashRound(acc, input uint64) uint64 {
+	acc += input * prime64n2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * prime64n1
+}
+
+func xxhashMergeRound(acc, val uint64) uint64 {
+	acc ^= xxhashRound(0, val)
+	return acc*prime64n1 + prime64n4
+}
+
+func (h *xxhash64) write(b []byte) {
+	h.total += uint64(len(b))
+	if h.n+len(b) < len(h.mem) {
+		h.n += copy(h.mem[h.n:], b)
+		return
+	}
+	if h.n > 0 {
+		c := copy(h.mem[h.n:], b)
+		h.stripes(h.mem[:])
+		b = b[c:]
+		h.n = 0
+	}
+	if len(b) >= len(h.mem) {
+		full := len(b) &^ (len(h.mem) - 1)
+		h.stripes(b[:full])
+		b = b[full:]
+	}
+	h.n = copy(h.mem[:], b)
+}
+
+// stripes consumes b, whose length must be a multiple of 32.
+func (h *xxhash64) stripes(b []byte) {
+	v1, v2, v3, v4 := h.v1, h.v2, h.v3, h.v4
+	for ; len(b) >= 32; b = b[32:] {
+		v1 = xxhashRound(v1, binary.LittleEndian.Uint64(b[0:]))
+		v2 = xxhashRound(v2, binary.LittleEndian.Uint64(b[8:]))
+		v3 = xxhashRound(v3, binary.LittleEndian.Uint64(b[16:]))
+		v4 = xxhashRound(v4, binary.LittleEndian.Uint64(b[24:]))
+	}
+	h.v1, h.v2, h.v3, h.v4 = v1, v2, v3, v4
+}
+
+func (h *xxhash64) sum64() uint64 {
+	var sum uint64
+	if h.total >= 32 {
+		sum = bits.RotateLeft64(h.v1, 1) + bits.RotateLeft64(h.v2, 7) + bits.RotateLeft64(h.v3, 12) +
+			bits.RotateLeft64(h.v4, 18)
+		sum = xxhashMergeRound(sum, h.v1)
+		sum = xxhashMergeRound(sum, h.v2)
+		sum = xxhashMergeRound(sum, h.v3)
+		sum = xxhashMergeRound(sum, h.v4)
+	} else {
+		sum = h.v3 + prime64n5
+	}
+	sum += h.total
+
+	b := h.mem[:h.n]
+	for ; len(b) >= 8; b = b[8:] {
+		sum ^= xxhashRound(0, binary.LittleEndian.Uint64(b))
+		sum = bits.RotateLeft64(sum, 27)*prime64n1 + prime64n4
+	}
+	if len(b) >= 4 {
+		sum ^= uint64(binary.LittleEndian.Uint32(b)) * prime64n1
+		sum = bits.RotateLeft64(sum, 23)*prime64n2 + prime64n3
+		b = b[4:]
+	}
+	for _, c := range b {
+		sum ^= uint64(c) * prime64n5
+		sum = bits.RotateLeft64(sum, 11) * prime64n1
+	}
+
+	sum ^= sum >> 33
+	sum *= prime64n2
+	sum ^= sum >> 29
+	sum *= prime64n3
+	sum ^= sum >> 32
+	return sum
+}
@@ -0,0 +1,98 @@
+// Package zstd implements the Zstandard compression format as specified by RFC 8878.
+//
+// The decoder supports all frames without dictionaries. The encoder trades compression ratio for simplicity: it finds
+// matches greedily with a single hash table and never refers to repeat offsets.
+package zstd
+
+import (
+	"errors"
+)
+
+const (
+	frameMagic          = 0xfd2fb528
+	skippableFrameMagic = 0x184d2a50
+	skippableFrameMask  = 0xfffffff0
+
+	maxBlockSize = 128 << 10
+	// MaxWindowSize is the largest window accepted by decoders, matching the default limit of the reference
+	// implementation.
+	MaxWindowSize = 1 << 27
+
+	blockTypeRaw        = 0
+	blockTypeRLE        = 1
+	blockTypeCompressed = 2
+
+	literalsBlockRaw        = 0
+	literalsBlockRLE        = 1
+	literalsBlockCompressed = 2
+	literalsBlockTreeless   = 3
+
+	modePredefined = 0
+	modeRLE        = 1
+	modeFSE        = 2
+	modeRepeat     = 3
+
+	maxLiteralsLengthSymbol = 35
+	maxMatchLengthSymbol    = 52
+	maxOffsetSymbol         = 31
+	maxLiteralsLengthLog    = 9
+	maxMatchLengthLog       = 9
+	maxOffsetLog            = 8
+)
+
+var (
+	errCorrupt            = errors.New("zstd: corrupt input")
+	errChecksum           = errors.New("zstd: checksum mismatch")
+	errDictionary         = errors.New("zstd: dictionaries are not supported")
+	errWindowTooLarge     = errors.New("zstd: window size exceeds limit")
+	errInvalidMagicNumber = errors.New("zstd: invalid magic number")
+)
+
+var (
+	literalsLengthBaselines = [maxLiteralsLengthSymbol + 1]uint32{
+		0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+		16, 18, 20, 22, 24, 28, 32, 40, 48, 64, 128, 256, 512, 1024, 2048, 4096,
+		8192, 16384, 32768, 65536,
+	}
+	literalsLengthBits = [maxLiteralsLengthSymbol + 1]uint8{
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		1, 1, 1, 1, 2, 2, 3, 3, 4, 6, 7, 8, 9, 10, 11, 12,
+		13, 14, 15, 16,
+	}
+	matchLengthBaselines = [maxMatchLengthSymbol + 1]uint32{
+		3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18,
+		19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32, 33, 34,
+		35, 37, 39, 41, 43, 47, 51, 59, 67, 83, 99, 131, 259, 515, 1027, 2051,
+		4099, 8195, 16387, 32771, 65539,
+	}
+	matchLengthBits = [maxMatchLengthSymbol + 1]uint8{
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		1, 1, 1, 1, 2, 2, 3, 3, 4, 4, 5, 7, 8, 9, 10, 11,
+		12, 13, 14, 15, 16,
+	}
+
+	literalsLengthDefaultDistribution = []int16{
+		4, 3, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1, 1, 1,
+		2, 2, 2, 2, 2, 2, 2, 2, 2, 3, 2, 1, 1, 1, 1, 1,
+		-1, -1, -1, -1,
+	}
+	matchLengthDefaultDistribution = []int16{
+		1, 4, 3, 2, 2, 2, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, -1, -1,
+		-1, -1, -1, -1, -1,
+	}
+	offsetDefaultDistribution = []int16{
+		1, 1, 1, 1, 1, 1, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1, -1, -1,
+	}
+
+	literalsLengthDefaultTable = mustBuildFSETable(literalsLengthDefaultDistribution, 6)
+	matchLengthDefaultTable    = mustBuildFSETable(matchLengthDefaultDistribution, 6)
+	offsetDefaultTable         = mustBuildFSETable(offsetDefaultDistribution, 5)
+
+	literalsLengthDefaultEncoder = newFSEEncoder(literalsLengthDefaultDistribution, 6)
+	matchLengthDefaultEncoder    = newFSEEncoder(matchLengthDefaultDistribution, 6)
+	offsetDefaultEncoder         = newFSEEncoder(offsetDefaultDistribution, 5)
+)
@@ -0,0 +1,200 @@
+package zstd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// operationsJSON returns the input of the reference frames below.
+func operationsJSON() []byte {
+	states := []string{"running", "succeeded", "failed"}
+	entries := make([]string, 40)
+	for i := range entries {
+		entries[i] = fmt.Sprintf(`{"id": %d, "name": "operation-%d", "state": "%s"}`, i, i, states[i%3])
+	}
+	return []byte("[" + strings.Join(entries, ", ") + "]")
+}
+
+// Frames produced by the reference implementation.
+var (
+	// zstd -19: Huffman coded literals and FSE coded sequences.
+	referenceLevel19 = "KLUv/WS5B60GAOIJHhiAbQOqI0LTYYd3T1EcmogoMzMZni7GdQGEc601Y2v9t3s3N/f2tra2c60xtta/ezcz8y4rKzvXGmNr/bt3M+/uqq5zrTG21r97t5K8g9mABoRFET27CpgSiUmMkQM96EkFpAaRSaDnwIZJJSRcXkwFqBLSSFIaAlKoERB3qDHsZ6CVtNgRJAQIgULAEB7GiqcoswOwyH1vsV8Xq/tfH9eWaH1NB/N9UlMdC1N2dQpnlrxupZL3nigZ7cu+rb9/W28yaAFmrMSx7Q1WZrKqX8BWAVBBrlU="
+	// zstd -1 --no-check.
+	referenceLevel1 = "KLUv/WC5B00HAJLJHRtwp+3Bv31Wa5UgtJsgXlXFTZLdZFdgJ1FVTwkA/+7dzLuqmZiYl5cnT/67dzPvqmYiIh4eHjz4797NvKt6d3d3/+7dzLuqmQMKBSAayUipKJbkzgwHJrDUWguiICUxM9ghrEMpCEkFhxnCEgFaZoZjCDOUGRQCV6hRzBtqDPsZoKMUpAMRJAQIgUIAQYTECAhGSixl8SSX2ckie83iz3Beq7f66iZ/7TjW5rRpJA0aRAGKVVOxSJHayjNPSCaSOPCcRSHYiTSKOykJYUQhKjxu57rabcocN2NH61A2y3W02a7qF/hV"
+	// Two frames of "hello, " and "world" with a skippable frame between them.
+	referenceMultiFrame = "28b52ffd045839000068656c6c6f2c20cfb93e73532a4d180300000061626328b52ffd0458290000776f726c64ef51ee66"
+)
+
+func decompress(t *testing.T, compressed []byte) ([]byte, error) {
+	t.Helper()
+	r := NewReader(bytes.NewReader(compressed))
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func compress(t *testing.T, data []byte, chunkSize int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for p := data; len(p) > 0; {
+		n := min(len(p), chunkSize)
+		_, err := w.Write(p[:n])
+		require.NoError(t, err)
+		p = p[n:]
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestReader_ReferenceFrames(t *testing.T) {
+	for _, frame := range []string{referenceLevel19, referenceLevel1} {
+		compressed, err := base64.StdEncoding.DecodeString(frame)
+		require.NoError(t, err)
+		decompressed, err := decompress(t, compressed)
+		require.NoError(t, err)
+		require.Equal(t, operationsJSON(), decompressed)
+	}
+
+	compressed, err := hex.DecodeString(referenceMultiFrame)
+	require.NoError(t, err)
+	decompressed, err := decompress(t, compressed)
+	require.NoError(t, err)
+	require.Equal(t, "hello, world", string(decompressed))
+}
+
+func TestRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := make([]byte, 300_000)
+	rng.Read(random)
+	// Random data over a small alphabet with repetitions, spanning multiple blocks and windows.
+	mixed := make([]byte, 3*encoderWindowSize)
+	for i := range mixed {
+		if i > 8 && rng.Intn(3) == 0 {
+			mixed[i] = mixed[i-1-rng.Intn(8)]
+		} else {
+			mixed[i] = byte(rng.Intn(200))
+		}
+	}
+	var text bytes.Buffer
+	for text.Len() < 200_000 {
+		text.Write(operationsJSON())
+	}
+
+	inputs := map[string][]byte{
+		"empty":  {},
+		"byte":   {42},
+		"zeros":  make([]byte, 1_000_000),
+		"short":  []byte("a short text that is not worth compressing"),
+		"text":   text.Bytes(),
+		"random": random,
+		"mixed":  mixed,
+	}
+	for name, data := range inputs {
+		t.Run(name, func(t *testing.T) {
+			for _, chunkSize := range []int{1 << 30, 70_001, 4096} {
+				compressed := compress(t, data, chunkSize)
+				decompressed, err := decompress(t, compressed)
+				require.NoError(t, err)
+				require.Equal(t, len(data), len(decompressed))
+				require.True(t, bytes.Equal(data, decompressed))
+			}
+		})
+	}
+
+	compressed := compress(t, text.Bytes(), 1<<30)
+	require.Less(t, len(compressed), text.Len()/10)
+}
+
+func TestWriter_Closed(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	require.NoError(t, w.Close())
+	require.NoError(t, w.Close())
+	_, err := w.Write([]byte("x"))
+	require.ErrorIs(t, err, errWriterClosed)
+}
+
+func TestReader_Errors(t *testing.T) {
+	compressed := compress(t, operationsJSON(), 1<<30)
+
+	t.Run("checksum", func(t *testing.T) {
+		corrupted := bytes.Clone(compressed)
+		corrupted[len(corrupted)-1] ^= 1
+		_, err := decompress(t, corrupted)
+		require.ErrorIs(t, err, errChecksum)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		for n := 1; n < len(compressed); n += 7 {
+			_, err := decompress(t, compressed[:n])
+			require.Error(t, err)
+		}
+	})
+
+	t.Run("magic", func(t *testing.T) {
+		_, err := decompress(t, []byte("not a zstd frame"))
+		require.ErrorIs(t, err, errInvalidMagicNumber)
+	})
+
+	t.Run("window too large", func(t *testing.T) {
+		// A frame header with a window descriptor for 2 GiB.
+		frame := binary.LittleEndian.AppendUint32(nil, frameMagic)
+		frame = append(frame, 0, (31-10)<<3)
+		_, err := decompress(t, frame)
+		require.ErrorIs(t, err, errWindowTooLarge)
+	})
+
+	t.Run("dictionary", func(t *testing.T) {
+		frame := binary.LittleEndian.AppendUint32(nil, frameMagic)
+		frame = append(frame, 1, (20-10)<<3, 7)
+		_, err := decompress(t, frame)
+		require.ErrorIs(t, err, errDictionary)
+	})
+
+	t.Run("random corruption", func(t *testing.T) {
+		reference, err := base64.StdEncoding.DecodeString(referenceLevel19)
+		require.NoError(t, err)
+		rng := rand.New(rand.NewSource(1))
+		for i := 0; i < 2000; i++ {
+			corrupted := bytes.Clone(reference)
+			for j := 0; j < 1+rng.Intn(4); j++ {
+				// Keep the magic number to reach the block decoders.
+				corrupted[4+rng.Intn(len(corrupted)-4)] ^= byte(1 + rng.Intn(255))
+			}
+			decompressed, err := decompress(t, corrupted)
+			if err == nil {
+				require.Equal(t, operationsJSON(), decompressed)
+			}
+		}
+	})
+}
+
+func TestXXHash64(t *testing.T) {
+	sum := func(b []byte) uint64 {
+		var h xxhash64
+		h.reset()
+		h.write(b)
+		return h.sum64()
+	}
+	require.Equal(t, uint64(0xef46db3751d8e999), sum(nil))
+	require.Equal(t, uint64(0x44bc2cf5ad770999), sum([]byte("abc")))
+
+	data := operationsJSON()
+	var h xxhash64
+	h.reset()
+	for p := data; len(p) > 0; {
+		n := min(len(p), 13)
+		h.write(p[:n])
+		p = p[n:]
+	}
+	require.Equal(t, sum(data), h.sum64())
+}
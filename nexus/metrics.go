@@ -0,0 +1,362 @@
+package nexus
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MetricsComponent identifies the part of the SDK that is recording metrics.
+type MetricsComponent string
+
+const (
+	// Metrics recorded by the handler constructed with [NewHTTPHandler].
+	MetricsComponentHandler MetricsComponent = "handler"
+	// Metrics recorded by the handler constructed with [NewCompletionHTTPHandler].
+	MetricsComponentCompletionHandler MetricsComponent = "completion_handler"
+	// Metrics recorded by [Client] and [OperationHandle] methods.
+	MetricsComponentClient MetricsComponent = "client"
+)
+
+// MetricsMethod identifies the Nexus API method a request is made for.
+type MetricsMethod string
+
+const (
+	MetricsMethodStartOperation     MetricsMethod = "start_operation"
+	MetricsMethodGetOperationResult MetricsMethod = "get_operation_result"
+	MetricsMethodGetOperationInfo   MetricsMethod = "get_operation_info"
+	MetricsMethodCancelOperation    MetricsMethod = "cancel_operation"
+	MetricsMethodCompleteOperation  MetricsMethod = "complete_operation"
+	MetricsMethodDescribeService    MetricsMethod = "describe_service"
+)
+
+// MetricsOutcome describes the result of a request.
+type MetricsOutcome string
+
+const (
+	// The operation completed successfully and its result was delivered inline.
+	MetricsOutcomeSyncSuccess MetricsOutcome = "sync_success"
+	// An asynchronous operation was started.
+	MetricsOutcomeAsyncStarted MetricsOutcome = "async_started"
+	// The request completed successfully. Used for get-info, cancel, complete, and describe requests.
+	MetricsOutcomeSuccess MetricsOutcome = "success"
+	// The operation completed as failed.
+	MetricsOutcomeFailed MetricsOutcome = "failed"
+	// The operation completed as canceled.
+	MetricsOutcomeCanceled MetricsOutcome = "canceled"
+	// The operation is still running.
+	MetricsOutcomeStillRunning MetricsOutcome = "still_running"
+	// A long poll get-result request timed out.
+	MetricsOutcomeTimeout MetricsOutcome = "timeout"
+	// The request failed with a [HandlerError] or an unexpected status code, see [MetricsResult.StatusCode].
+	MetricsOutcomeHandlerError MetricsOutcome = "handler_error"
+	// The request could not be delivered (client only).
+	MetricsOutcomeTransportError MetricsOutcome = "transport_error"
+)
+
+// MetricsRequest identifies a request being recorded.
+type MetricsRequest struct {
+	Component MetricsComponent
+	Method    MetricsMethod
+}
+
+// MetricsResult is the result of a recorded request.
+type MetricsResult struct {
+	Outcome MetricsOutcome
+	// HTTP status code of the response. Zero for transport errors.
+	StatusCode int
+	// Time elapsed between the start of the request and the response headers being written or received.
+	Latency time.Duration
+}
+
+// A MetricsHandler records metrics for Nexus requests. Implementations must be safe for concurrent use.
+//
+// Use [NewPrometheusMetricsHandler] for a built-in implementation or adapt to a metrics library of your choice.
+type MetricsHandler interface {
+	// RequestStarted is called before a request is processed or sent.
+	RequestStarted(MetricsRequest)
+	// RequestCompleted is called once for every call to RequestStarted.
+	RequestCompleted(MetricsRequest, MetricsResult)
+}
+
+type noopMetricsHandler struct{}
+
+func (noopMetricsHandler) RequestStarted(MetricsRequest)                  {}
+func (noopMetricsHandler) RequestCompleted(MetricsRequest, MetricsResult) {}
+
+// metricsOutcomeFromResponse derives the outcome of a request from the status code and headers of its response.
+func metricsOutcomeFromResponse(method MetricsMethod, statusCode int, header http.Header) MetricsOutcome {
+	switch statusCode {
+	case http.StatusOK:
+		if method == MetricsMethodStartOperation || method == MetricsMethodGetOperationResult {
+			return MetricsOutcomeSyncSuccess
+		}
+		return MetricsOutcomeSuccess
+	case http.StatusCreated:
+		if method == MetricsMethodStartOperation {
+			return MetricsOutcomeAsyncStarted
+		}
+	case http.StatusAccepted:
+		if method == MetricsMethodCancelOperation {
+			return MetricsOutcomeSuccess
+		}
+	case statusOperationRunning:
+		if method == MetricsMethodGetOperationResult {
+			return MetricsOutcomeStillRunning
+		}
+	case http.StatusRequestTimeout:
+		if method == MetricsMethodGetOperationResult {
+			return MetricsOutcomeTimeout
+		}
+	case statusOperationFailed:
+		switch OperationState(header.Get(headerOperationState)) {
+		case OperationStateFailed:
+			return MetricsOutcomeFailed
+		case OperationStateCanceled:
+			return MetricsOutcomeCanceled
+		}
+	}
+	return MetricsOutcomeHandlerError
+}
+
+// statusRecordingWriter is an [http.ResponseWriter] that records the status code written to the response.
+type statusRecordingWriter struct {
+	http.ResponseWriter
+	statusCode int
+	written    time.Time
+}
+
+func (w *statusRecordingWriter) WriteHeader(statusCode int) {
+	if w.statusCode == 0 {
+		w.statusCode = statusCode
+		w.written = time.Now()
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *statusRecordingWriter) Write(b []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+		w.written = time.Now()
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements the http.Flusher interface, flushing the underlying writer if it supports flushing.
+func (w *statusRecordingWriter) Flush() {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+		w.written = time.Now()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap allows [http.ResponseController] to access the underlying writer.
+func (w *statusRecordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Default histogram buckets for request latencies, in seconds.
+var defaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+const (
+	metricRequests        = "nexus_requests_total"
+	metricRequestLatency  = "nexus_request_latency_seconds"
+	metricRequestInFlight = "nexus_requests_in_flight"
+)
+
+type requestSeriesKey struct {
+	MetricsRequest
+	outcome    MetricsOutcome
+	statusCode int
+}
+
+type latencySeriesKey struct {
+	MetricsRequest
+	outcome MetricsOutcome
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// PrometheusMetricsHandlerOptions are options for [NewPrometheusMetricsHandler].
+type PrometheusMetricsHandlerOptions struct {
+	// Upper bounds of the latency histogram buckets, in seconds.
+	// Defaults to buckets ranging from 5ms to 60s.
+	LatencyBuckets []float64
+}
+
+// PrometheusMetricsHandler is a [MetricsHandler] that keeps metrics in memory and serves them in the Prometheus text
+// exposition format when used as an [http.Handler].
+//
+// The following metrics are exposed:
+//
+//   - nexus_requests_total: counter of completed requests, labeled by component, method, outcome, and status_code.
+//   - nexus_request_latency_seconds: histogram of request latencies, labeled by component, method, and outcome.
+//   - nexus_requests_in_flight: gauge of in-flight requests, labeled by component and method.
+type PrometheusMetricsHandler struct {
+	buckets  []float64
+	mu       sync.Mutex
+	requests map[requestSeriesKey]uint64
+	latency  map[latencySeriesKey]*histogram
+	inFlight map[MetricsRequest]int64
+}
+
+// NewPrometheusMetricsHandler constructs a new [PrometheusMetricsHandler].
+func NewPrometheusMetricsHandler(options PrometheusMetricsHandlerOptions) *PrometheusMetricsHandler {
+	buckets := options.LatencyBuckets
+	if len(buckets) == 0 {
+		buckets = defaultLatencyBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &PrometheusMetricsHandler{
+		buckets:  buckets,
+		requests: make(map[requestSeriesKey]uint64),
+		latency:  make(map[latencySeriesKey]*histogram),
+		inFlight: make(map[MetricsRequest]int64),
+	}
+}
+
+// RequestStarted implements the MetricsHandler interface.
+func (h *PrometheusMetricsHandler) RequestStarted(request MetricsRequest) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.inFlight[request]++
+}
+
+// RequestCompleted implements the MetricsHandler interface.
+func (h *PrometheusMetricsHandler) RequestCompleted(request MetricsRequest, result MetricsResult) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.inFlight[request]--
+	h.requests[requestSeriesKey{request, result.Outcome, result.StatusCode}]++
+
+	key := latencySeriesKey{request, result.Outcome}
+	hist := h.latency[key]
+	if hist == nil {
+		hist = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.latency[key] = hist
+	}
+	seconds := result.Latency.Seconds()
+	for i, bound := range h.buckets {
+		if seconds <= bound {
+			hist.counts[i]++
+		}
+	}
+	hist.count++
+	hist.sum += seconds
+}
+
+// ServeHTTP serves the recorded metrics in the Prometheus text exposition format.
+func (h *PrometheusMetricsHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set(headerContentType, "text/plain; version=0.0.4; charset=utf-8")
+	if _, err := h.WriteTo(writer); err != nil {
+		// Nothing to do but abort the response.
+		return
+	}
+}
+
+// WriteTo writes the recorded metrics to w in the Prometheus text exposition format.
+func (h *PrometheusMetricsHandler) WriteTo(w io.Writer) (int64, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var b strings.Builder
+
+	b.WriteString("# HELP " + metricRequests + " Total number of Nexus requests.\n")
+	b.WriteString("# TYPE " + metricRequests + " counter\n")
+	lines := make([]string, 0, len(h.requests))
+	for key, count := range h.requests {
+		labels := formatLabels(
+			"component", string(key.Component),
+			"method", string(key.Method),
+			"outcome", string(key.outcome),
+			"status_code", strconv.Itoa(key.statusCode),
+		)
+		lines = append(lines, metricRequests+labels+" "+strconv.FormatUint(count, 10)+"\n")
+	}
+	sort.Strings(lines)
+	b.WriteString(strings.Join(lines, ""))
+
+	b.WriteString("# HELP " + metricRequestLatency + " Latency of Nexus requests in seconds.\n")
+	b.WriteString("# TYPE " + metricRequestLatency + " histogram\n")
+	lines = lines[:0]
+	for key, hist := range h.latency {
+		var series strings.Builder
+		base := []string{
+			"component", string(key.Component),
+			"method", string(key.Method),
+			"outcome", string(key.outcome),
+		}
+		for i, bound := range h.buckets {
+			labels := formatLabels(append(base, "le", formatFloat(bound))...)
+			fmt.Fprintf(&series, "%s_bucket%s %d\n", metricRequestLatency, labels, hist.counts[i])
+		}
+		fmt.Fprintf(&series, "%s_bucket%s %d\n", metricRequestLatency, formatLabels(append(base, "le", "+Inf")...), hist.count)
+		fmt.Fprintf(&series, "%s_sum%s %s\n", metricRequestLatency, formatLabels(base...), formatFloat(hist.sum))
+		fmt.Fprintf(&series, "%s_count%s %d\n", metricRequestLatency, formatLabels(base...), hist.count)
+		lines = append(lines, series.String())
+	}
+	sort.Strings(lines)
+	b.WriteString(strings.Join(lines, ""))
+
+	b.WriteString("# HELP " + metricRequestInFlight + " Number of Nexus requests currently in flight.\n")
+	b.WriteString("# TYPE " + metricRequestInFlight + " gauge\n")
+	lines = lines[:0]
+	for key, value := range h.inFlight {
+		labels := formatLabels("component", string(key.Component), "method", string(key.Method))
+		lines = append(lines, metricRequestInFlight+labels+" "+strconv.FormatInt(value, 10)+"\n")
+	}
+	sort.Strings(lines)
+	b.WriteString(strings.Join(lines, ""))
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// formatLabels formats label name and value pairs in the Prometheus text format.
+func formatLabels(pairs ...string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pairs[i])
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(pairs[i+1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueEscaper.Replace(v)
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
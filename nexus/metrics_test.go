@@ -0,0 +1,116 @@
+package nexus
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics_HandlerAndClient(t *testing.T) {
+	handlerMetrics := NewPrometheusMetricsHandler(PrometheusMetricsHandlerOptions{})
+	clientMetrics := NewPrometheusMetricsHandler(PrometheusMetricsHandlerOptions{})
+	ctx, client, teardown := setupCustom(t, HandlerOptions{
+		Handler:          &asyncWithResultHandler{resultError: ErrOperationStillRunning},
+		GetResultTimeout: getResultMaxTimeout,
+		MetricsHandler:   handlerMetrics,
+	}, ClientOptions{MetricsHandler: clientMetrics})
+	defer teardown()
+
+	result, err := client.StartOperation(ctx, StartOperationOptions{Operation: "foo"})
+	require.NoError(t, err)
+	_, err = result.Pending.GetResult(ctx, GetOperationResultOptions{})
+	require.ErrorIs(t, err, ErrOperationStillRunning)
+	// asyncWithResultHandler does not implement cancel.
+	err = result.Pending.Cancel(ctx, CancelOperationOptions{})
+	require.Error(t, err)
+
+	for _, m := range []*PrometheusMetricsHandler{handlerMetrics, clientMetrics} {
+		// Handler metrics are recorded after responses are written, wait for the last request to be recorded.
+		output := waitForMetrics(t, m, `method="cancel_operation"} 0`)
+		require.Contains(t, output, `method="start_operation",outcome="async_started",status_code="201"} 1`)
+		require.Contains(t, output, `method="get_operation_result",outcome="still_running",status_code="412"} 1`)
+		require.Contains(t, output, `method="cancel_operation",outcome="handler_error",status_code="501"} 1`)
+		require.Contains(t, output, `method="start_operation"} 0`)
+	}
+}
+
+// waitForMetrics waits for the exposition of m to contain substr and returns it.
+func waitForMetrics(t *testing.T, m *PrometheusMetricsHandler, substr string) string {
+	var output string
+	require.Eventually(t, func() bool {
+		var b strings.Builder
+		if _, err := m.WriteTo(&b); err != nil {
+			return false
+		}
+		output = b.String()
+		return strings.Contains(output, substr)
+	}, time.Second, time.Millisecond*10)
+	return output
+}
+
+func TestStatusRecordingWriter_Flush(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	writer := &statusRecordingWriter{ResponseWriter: recorder}
+	_, ok := any(writer).(http.Flusher)
+	require.True(t, ok)
+	require.NoError(t, http.NewResponseController(writer).Flush())
+	require.True(t, recorder.Flushed)
+	require.Equal(t, http.StatusOK, writer.statusCode)
+}
+
+func TestMetrics_CompletionHandler(t *testing.T) {
+	metrics := NewPrometheusMetricsHandler(PrometheusMetricsHandlerOptions{})
+	handler := NewCompletionHTTPHandler(CompletionHandlerOptions{
+		Handler:        &failingCompletionHandler{},
+		MetricsHandler: metrics,
+	})
+	request, err := NewCompletionHTTPRequest(context.Background(), "http://localhost/callback", &OperationCompletionSuccessful{Body: strings.NewReader("success")})
+	require.NoError(t, err)
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	var b strings.Builder
+	_, err = metrics.WriteTo(&b)
+	require.NoError(t, err)
+	require.Contains(t, b.String(), `nexus_requests_total{component="completion_handler",method="complete_operation",outcome="handler_error",status_code="400"} 1`)
+}
+
+func TestPrometheusMetricsHandler_Exposition(t *testing.T) {
+	metrics := NewPrometheusMetricsHandler(PrometheusMetricsHandlerOptions{LatencyBuckets: []float64{1, 0.1}})
+	request := MetricsRequest{Component: MetricsComponentHandler, Method: MetricsMethodGetOperationResult}
+	metrics.RequestStarted(request)
+	metrics.RequestStarted(request)
+	metrics.RequestCompleted(request, MetricsResult{Outcome: MetricsOutcomeTimeout, StatusCode: http.StatusRequestTimeout, Latency: 500 * time.Millisecond})
+
+	server := httptest.NewServer(metrics)
+	defer server.Close()
+	response, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.Equal(t, "text/plain; version=0.0.4; charset=utf-8", response.Header.Get(headerContentType))
+	require.Equal(t, `# HELP nexus_requests_total Total number of Nexus requests.
+# TYPE nexus_requests_total counter
+nexus_requests_total{component="handler",method="get_operation_result",outcome="timeout",status_code="408"} 1
+# HELP nexus_request_latency_seconds Latency of Nexus requests in seconds.
+# TYPE nexus_request_latency_seconds histogram
+nexus_request_latency_seconds_bucket{component="handler",method="get_operation_result",outcome="timeout",le="0.1"} 0
+nexus_request_latency_seconds_bucket{component="handler",method="get_operation_result",outcome="timeout",le="1"} 1
+nexus_request_latency_seconds_bucket{component="handler",method="get_operation_result",outcome="timeout",le="+Inf"} 1
+nexus_request_latency_seconds_sum{component="handler",method="get_operation_result",outcome="timeout"} 0.5
+nexus_request_latency_seconds_count{component="handler",method="get_operation_result",outcome="timeout"} 1
+# HELP nexus_requests_in_flight Number of Nexus requests currently in flight.
+# TYPE nexus_requests_in_flight gauge
+nexus_requests_in_flight{component="handler",method="get_operation_result"} 1
+`, string(body))
+}
+
+func TestFormatLabels_Escaping(t *testing.T) {
+	require.Equal(t, `{a="x\\y\"z\n"}`, formatLabels("a", "x\\y\"z\n"))
+}
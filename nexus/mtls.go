@@ -0,0 +1,305 @@
+package nexus
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// CertificateReloaderOptions are options for [NewCertificateReloader].
+type CertificateReloaderOptions struct {
+	// Path of a PEM encoded certificate chain. Required.
+	CertFile string
+	// Path of a PEM encoded private key. Required.
+	KeyFile string
+	// Minimum interval between checks for changes of the certificate and key files. Files are checked lazily when a
+	// certificate is requested for a TLS handshake.
+	//
+	// Defaults to one minute.
+	ReloadInterval time.Duration
+}
+
+// A CertificateReloader serves a certificate and key loaded from disk to TLS handshakes, reloading them when the files
+// change, e.g. when a short lived certificate is rotated by an external agent.
+//
+// Use [CertificateReloader.GetClientCertificate] as the [tls.Config] GetClientCertificate callback of clients and
+// [CertificateReloader.GetCertificate] as the GetCertificate callback of servers.
+type CertificateReloader struct {
+	options CertificateReloaderOptions
+	now     func() time.Time
+
+	mu          sync.Mutex
+	certificate *tls.Certificate
+	certPEM     []byte
+	keyPEM      []byte
+	checkedAt   time.Time
+}
+
+var errMissingCertificateFiles = errors.New("certificate and key files are required")
+
+// NewCertificateReloader constructs a [CertificateReloader], loading the certificate and key from disk.
+func NewCertificateReloader(options CertificateReloaderOptions) (*CertificateReloader, error) {
+	if options.CertFile == "" || options.KeyFile == "" {
+		return nil, errMissingCertificateFiles
+	}
+	if options.ReloadInterval <= 0 {
+		options.ReloadInterval = time.Minute
+	}
+	r := &CertificateReloader{options: options, now: time.Now}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload loads the certificate and key from disk if they have changed since they were last loaded. The previously
+// loaded certificate is retained if loading fails.
+func (r *CertificateReloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reloadLocked()
+}
+
+func (r *CertificateReloader) reloadLocked() error {
+	r.checkedAt = r.now()
+	certPEM, err := os.ReadFile(r.options.CertFile)
+	if err != nil {
+		return err
+	}
+	keyPEM, err := os.ReadFile(r.options.KeyFile)
+	if err != nil {
+		return err
+	}
+	if r.certificate != nil && bytes.Equal(certPEM, r.certPEM) && bytes.Equal(keyPEM, r.keyPEM) {
+		return nil
+	}
+	certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return err
+	}
+	r.certificate, r.certPEM, r.keyPEM = &certificate, certPEM, keyPEM
+	return nil
+}
+
+// current returns the loaded certificate, reloading it first if the reload interval has elapsed.
+func (r *CertificateReloader) current() *tls.Certificate {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.now().Sub(r.checkedAt) >= r.options.ReloadInterval {
+		// Keep serving the previous certificate if the files are missing or being rewritten.
+		_ = r.reloadLocked()
+	}
+	return r.certificate
+}
+
+// GetClientCertificate returns the current certificate, for use as [tls.Config] GetClientCertificate.
+func (r *CertificateReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.current(), nil
+}
+
+// GetCertificate returns the current certificate, for use as [tls.Config] GetCertificate.
+func (r *CertificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.current(), nil
+}
+
+// ClientTLSOptions configure mutual TLS for a [Client]. See [ClientOptions.TLS].
+type ClientTLSOptions struct {
+	// Path of the PEM encoded client certificate chain, reloaded when it changes on disk.
+	// Optional, no client certificate is presented if empty.
+	CertFile string
+	// Path of the PEM encoded private key of the client certificate, reloaded when it changes on disk.
+	KeyFile string
+	// Minimum interval between checks for changes of the certificate and key files.
+	// Defaults to one minute.
+	ReloadInterval time.Duration
+	// Path of a PEM encoded bundle of certificate authorities to verify server certificates with.
+	// Defaults to the system's roots.
+	RootCAFile string
+	// Server name to verify server certificates against.
+	// Defaults to the host of the service base URL.
+	ServerName string
+}
+
+// newHTTPCaller constructs an HTTP client configured with the TLS options.
+func (o *ClientTLSOptions) newHTTPCaller() (func(*http.Request) (*http.Response, error), error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: o.ServerName,
+	}
+	if o.CertFile != "" || o.KeyFile != "" {
+		reloader, err := NewCertificateReloader(CertificateReloaderOptions{
+			CertFile:       o.CertFile,
+			KeyFile:        o.KeyFile,
+			ReloadInterval: o.ReloadInterval,
+		})
+		if err != nil {
+			return nil, err
+		}
+		config.GetClientCertificate = reloader.GetClientCertificate
+	}
+	if o.RootCAFile != "" {
+		b, err := os.ReadFile(o.RootCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificates found in %s", o.RootCAFile)
+		}
+		config.RootCAs = pool
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+	return (&http.Client{Transport: transport}).Do, nil
+}
+
+// PeerIdentity is the identity of a caller authenticated with a TLS client certificate.
+//
+// Handlers constructed with [NewHTTPHandler] extract the identity from requests made over mutual TLS connections and
+// make it available via [PeerIdentityFromContext]. The server must verify client certificates, e.g. with a
+// [tls.Config] ClientAuth of [tls.RequireAndVerifyClientCert].
+type PeerIdentity struct {
+	// SPIFFE ID of the peer, the first spiffe:// URI SAN of its certificate, if any.
+	SPIFFEID string
+	// DNS SANs of the peer's certificate.
+	DNSNames []string
+	// Subject of the peer's certificate.
+	Subject pkix.Name
+	// The peer's leaf certificate.
+	Certificate *x509.Certificate
+}
+
+// NewPeerIdentity extracts the identity of a peer from its leaf certificate.
+func NewPeerIdentity(certificate *x509.Certificate) *PeerIdentity {
+	identity := &PeerIdentity{
+		DNSNames:    certificate.DNSNames,
+		Subject:     certificate.Subject,
+		Certificate: certificate,
+	}
+	for _, uri := range certificate.URIs {
+		if uri.Scheme == "spiffe" {
+			identity.SPIFFEID = uri.String()
+			break
+		}
+	}
+	return identity
+}
+
+// peerIdentityFromRequest extracts the identity of the peer of a mutual TLS request. Only certificates verified by the
+// server are considered, unverified certificates, e.g. requested with [tls.RequestClientCert], are ignored.
+func peerIdentityFromRequest(request *http.Request) *PeerIdentity {
+	if request.TLS == nil || len(request.TLS.VerifiedChains) == 0 || len(request.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return NewPeerIdentity(request.TLS.VerifiedChains[0][0])
+}
+
+type peerIdentityKeyType struct{}
+
+var peerIdentityKey = peerIdentityKeyType{}
+
+// ContextWithPeerIdentity returns a copy of ctx that carries the given peer identity.
+func ContextWithPeerIdentity(ctx context.Context, identity *PeerIdentity) context.Context {
+	return context.WithValue(ctx, peerIdentityKey, identity)
+}
+
+// PeerIdentityFromContext returns the peer identity stored in ctx, if any.
+func PeerIdentityFromContext(ctx context.Context) (*PeerIdentity, bool) {
+	identity, ok := ctx.Value(peerIdentityKey).(*PeerIdentity)
+	return identity, ok && identity != nil
+}
+
+// A PeerAuthorizationRule allows peers to call operations. A peer matches a rule if any of its SPIFFE ID, DNS names, or
+// subject common name match. See [HandlerOptions.PeerAuthorizationRules].
+type PeerAuthorizationRule struct {
+	// Name of the operation the rule applies to, or * for all operations.
+	Operation string
+	// SPIFFE IDs to allow. Entries ending with /* match any ID under the given path, e.g.
+	// spiffe://example.org/ns/prod/*.
+	SPIFFEIDs []string
+	// DNS names to allow. Entries of the form *.example.com match any subdomain of example.com.
+	DNSNames []string
+	// Subject common names to allow.
+	CommonNames []string
+}
+
+func (r PeerAuthorizationRule) matches(identity *PeerIdentity) bool {
+	for _, pattern := range r.SPIFFEIDs {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasSuffix(prefix, "/") {
+			if identity.SPIFFEID != "" && strings.HasPrefix(identity.SPIFFEID, prefix) {
+				return true
+			}
+		} else if identity.SPIFFEID != "" && identity.SPIFFEID == pattern {
+			return true
+		}
+	}
+	for _, name := range identity.DNSNames {
+		if matchesHost(r.DNSNames, name) {
+			return true
+		}
+	}
+	if identity.Subject.CommonName != "" {
+		for _, commonName := range r.CommonNames {
+			if commonName == identity.Subject.CommonName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// authorizePeer checks the identity of a request's peer against the rules that apply to operation.
+func authorizePeer(rules []PeerAuthorizationRule, operation string, identity *PeerIdentity) error {
+	if identity == nil {
+		return &HandlerError{
+			StatusCode:    http.StatusUnauthorized,
+			Failure:       &Failure{Message: "client certificate required"},
+			RetryBehavior: RetryBehaviorNonRetryable,
+		}
+	}
+	for _, rule := range rules {
+		if (rule.Operation == "*" || rule.Operation == operation) && rule.matches(identity) {
+			return nil
+		}
+	}
+	return &HandlerError{
+		StatusCode:    http.StatusForbidden,
+		Failure:       &Failure{Message: "peer is not authorized"},
+		RetryBehavior: RetryBehaviorNonRetryable,
+	}
+}
+
+// identifyPeer wraps a handler function, making the identity of mutual TLS peers available via the request's context
+// and enforcing the handler's peer authorization rules.
+func (h *httpHandler) identifyPeer(handler http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		identity := peerIdentityFromRequest(request)
+		if identity != nil {
+			request = request.WithContext(ContextWithPeerIdentity(request.Context(), identity))
+		}
+		if len(h.options.PeerAuthorizationRules) > 0 {
+			operation, err := url.PathUnescape(mux.Vars(request)["operation"])
+			if err != nil {
+				h.writeFailure(writer, newBadRequestError("failed to parse URL path"))
+				return
+			}
+			if err := authorizePeer(h.options.PeerAuthorizationRules, operation, identity); err != nil {
+				h.writeFailure(writer, err)
+				return
+			}
+		}
+		handler(writer, request)
+	}
+}
@@ -0,0 +1,262 @@
+package nexus
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	pem         []byte
+	serial      int64
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{
+		certificate: certificate,
+		key:         key,
+		pem:         pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		serial:      1,
+	}
+}
+
+// issue returns PEM encoded certificate and key for a leaf certificate modified by configure.
+func (ca *testCA) issue(t *testing.T, configure func(*x509.Certificate)) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ca.serial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(ca.serial),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	}
+	configure(template)
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.certificate, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func (ca *testCA) issueClient(t *testing.T, spiffeID string, dnsName string, commonName string) (certPEM, keyPEM []byte) {
+	return ca.issue(t, func(template *x509.Certificate) {
+		template.Subject = pkix.Name{CommonName: commonName}
+		if dnsName != "" {
+			template.DNSNames = []string{dnsName}
+		}
+		if spiffeID != "" {
+			u, err := url.Parse(spiffeID)
+			require.NoError(t, err)
+			template.URIs = []*url.URL{u}
+		}
+	})
+}
+
+func writeTestFile(t *testing.T, path string, b []byte) string {
+	require.NoError(t, os.WriteFile(path, b, 0o600))
+	return path
+}
+
+type peerIdentityHandler struct {
+	UnimplementedHandler
+}
+
+func (h *peerIdentityHandler) StartOperation(ctx context.Context, request *StartOperationRequest) (OperationResponse, error) {
+	identity, ok := PeerIdentityFromContext(ctx)
+	if !ok {
+		return &OperationResponseSync{Body: bytes.NewReader([]byte("anonymous"))}, nil
+	}
+	return &OperationResponseSync{Body: bytes.NewReader([]byte(identity.SPIFFEID + "|" + identity.Subject.CommonName))}, nil
+}
+
+// setupMTLS starts a TLS server that verifies client certificates issued by ca and returns the path of the CA bundle.
+func setupMTLS(t *testing.T, ca *testCA, options HandlerOptions, clientAuth tls.ClientAuthType) (serviceBaseURL string, caFile string, teardown func()) {
+	serverCertPEM, serverKeyPEM := ca.issue(t, func(template *x509.Certificate) {
+		template.Subject = pkix.Name{CommonName: "server"}
+		template.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	})
+	serverCert, err := tls.X509KeyPair(serverCertPEM, serverKeyPEM)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.certificate)
+
+	server := httptest.NewUnstartedServer(NewHTTPHandler(options))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   clientAuth,
+		ClientCAs:    pool,
+	}
+	server.StartTLS()
+	caFile = writeTestFile(t, filepath.Join(t.TempDir(), "ca.pem"), ca.pem)
+	return server.URL + "/", caFile, server.Close
+}
+
+func newMTLSClient(t *testing.T, ca *testCA, serviceBaseURL, caFile string, spiffeID, commonName string) *Client {
+	options := ClientOptions{
+		ServiceBaseURL: serviceBaseURL,
+		TLS:            &ClientTLSOptions{RootCAFile: caFile},
+	}
+	if spiffeID != "" || commonName != "" {
+		dir := t.TempDir()
+		certPEM, keyPEM := ca.issueClient(t, spiffeID, "", commonName)
+		options.TLS.CertFile = writeTestFile(t, filepath.Join(dir, "cert.pem"), certPEM)
+		options.TLS.KeyFile = writeTestFile(t, filepath.Join(dir, "key.pem"), keyPEM)
+	}
+	client, err := NewClient(options)
+	require.NoError(t, err)
+	return client
+}
+
+func readSyncResult(t *testing.T, result *StartOperationResult) string {
+	require.NotNil(t, result.Successful)
+	defer result.Successful.Body.Close()
+	b, err := io.ReadAll(result.Successful.Body)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestMTLS_PeerIdentity(t *testing.T) {
+	ca := newTestCA(t)
+	serviceBaseURL, caFile, teardown := setupMTLS(t, ca, HandlerOptions{Handler: &peerIdentityHandler{}}, tls.RequireAndVerifyClientCert)
+	defer teardown()
+
+	client := newMTLSClient(t, ca, serviceBaseURL, caFile, "spiffe://example.org/ns/prod/sa/caller", "caller")
+	result, err := client.StartOperation(context.Background(), StartOperationOptions{Operation: "foo"})
+	require.NoError(t, err)
+	require.Equal(t, "spiffe://example.org/ns/prod/sa/caller|caller", readSyncResult(t, result))
+}
+
+func TestMTLS_PeerAuthorizationRules(t *testing.T) {
+	ca := newTestCA(t)
+	serviceBaseURL, caFile, teardown := setupMTLS(t, ca, HandlerOptions{
+		Handler: &peerIdentityHandler{},
+		PeerAuthorizationRules: []PeerAuthorizationRule{
+			{Operation: "prod-only", SPIFFEIDs: []string{"spiffe://example.org/ns/prod/*"}},
+			{Operation: "*", CommonNames: []string{"admin"}},
+		},
+	}, tls.VerifyClientCertIfGiven)
+	defer teardown()
+
+	prod := newMTLSClient(t, ca, serviceBaseURL, caFile, "spiffe://example.org/ns/prod/sa/caller", "caller")
+	result, err := prod.StartOperation(context.Background(), StartOperationOptions{Operation: "prod-only"})
+	require.NoError(t, err)
+	require.Equal(t, "spiffe://example.org/ns/prod/sa/caller|caller", readSyncResult(t, result))
+
+	var unexpectedResponseError *UnexpectedResponseError
+	_, err = prod.StartOperation(context.Background(), StartOperationOptions{Operation: "other"})
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusForbidden, unexpectedResponseError.Response.StatusCode)
+
+	staging := newMTLSClient(t, ca, serviceBaseURL, caFile, "spiffe://example.org/ns/staging/sa/caller", "caller")
+	_, err = staging.StartOperation(context.Background(), StartOperationOptions{Operation: "prod-only"})
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusForbidden, unexpectedResponseError.Response.StatusCode)
+
+	admin := newMTLSClient(t, ca, serviceBaseURL, caFile, "", "admin")
+	result, err = admin.StartOperation(context.Background(), StartOperationOptions{Operation: "other"})
+	require.NoError(t, err)
+	require.Equal(t, "|admin", readSyncResult(t, result))
+
+	anonymous := newMTLSClient(t, ca, serviceBaseURL, caFile, "", "")
+	_, err = anonymous.StartOperation(context.Background(), StartOperationOptions{Operation: "prod-only"})
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusUnauthorized, unexpectedResponseError.Response.StatusCode)
+}
+
+func TestMTLS_UntrustedClientCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	serviceBaseURL, caFile, teardown := setupMTLS(t, ca, HandlerOptions{Handler: &peerIdentityHandler{}}, tls.RequireAndVerifyClientCert)
+	defer teardown()
+
+	client := newMTLSClient(t, newTestCA(t), serviceBaseURL, caFile, "spiffe://example.org/ns/prod/sa/caller", "caller")
+	_, err := client.StartOperation(context.Background(), StartOperationOptions{Operation: "foo"})
+	require.Error(t, err)
+}
+
+func TestCertificateReloader(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	certPEM, keyPEM := ca.issueClient(t, "", "", "first")
+	writeTestFile(t, certFile, certPEM)
+	writeTestFile(t, keyFile, keyPEM)
+
+	reloader, err := NewCertificateReloader(CertificateReloaderOptions{CertFile: certFile, KeyFile: keyFile})
+	require.NoError(t, err)
+	now := time.Now()
+	reloader.now = func() time.Time { return now }
+
+	commonName := func() string {
+		certificate, err := reloader.GetClientCertificate(nil)
+		require.NoError(t, err)
+		leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+		require.NoError(t, err)
+		return leaf.Subject.CommonName
+	}
+	require.Equal(t, "first", commonName())
+
+	certPEM, keyPEM = ca.issueClient(t, "", "", "second")
+	writeTestFile(t, certFile, certPEM)
+	writeTestFile(t, keyFile, keyPEM)
+	// Not reloaded before the reload interval elapses.
+	require.Equal(t, "first", commonName())
+	now = now.Add(time.Minute)
+	require.Equal(t, "second", commonName())
+
+	// Invalid files are ignored.
+	writeTestFile(t, certFile, []byte("garbage"))
+	now = now.Add(time.Minute)
+	require.Equal(t, "second", commonName())
+	require.Error(t, reloader.Reload())
+	require.Equal(t, "second", commonName())
+
+	_, err = NewCertificateReloader(CertificateReloaderOptions{CertFile: certFile})
+	require.ErrorIs(t, err, errMissingCertificateFiles)
+}
+
+func TestNewClient_TLSAndHTTPCallerConflict(t *testing.T) {
+	_, err := NewClient(ClientOptions{
+		ServiceBaseURL: "https://localhost/",
+		HTTPCaller:     http.DefaultClient.Do,
+		TLS:            &ClientTLSOptions{},
+	})
+	require.ErrorIs(t, err, errConflictingHTTPCaller)
+}
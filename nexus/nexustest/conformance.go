@@ -0,0 +1,518 @@
+package nexustest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nexus-rpc/sdk-go/nexus"
+)
+
+const (
+	// ConformanceOperationSync is the name of an operation handlers under conformance test must complete
+	// synchronously, responding with the start request's body and Content-Type.
+	ConformanceOperationSync = "conformance-sync"
+	// ConformanceOperationAsync is the name of an operation handlers under conformance test must start
+	// asynchronously, completing it as described by its [ConformanceInput].
+	ConformanceOperationAsync = "conformance-async"
+)
+
+// ConformanceInput is the JSON encoded input of [ConformanceOperationAsync] start requests.
+type ConformanceInput struct {
+	// State to complete the operation in: succeeded, failed, or canceled. Operations started in the running state
+	// never complete unless canceled.
+	State nexus.OperationState `json:"state"`
+	// Result of succeeded operations, used as the result body, or failure message of failed and canceled operations.
+	Result string `json:"result,omitempty"`
+	// Duration after starting to complete the operation after.
+	Delay time.Duration `json:"delay,omitempty"`
+}
+
+const (
+	conformanceTimeout     = 5 * time.Second
+	conformanceLongPollMin = 300 * time.Millisecond
+	// Timeout of each request, leaving room for long polls that wait up to conformanceTimeout.
+	conformanceRequestTimeout = 2 * conformanceTimeout
+)
+
+// RunHandlerConformance verifies that [nexus.Handler] implementations follow the Nexus HTTP API, running each check as
+// a subtest against a handler constructed by factory and served with [nexus.NewHTTPHandler]. Violations are reported
+// with the offending request and the expected and actual responses.
+//
+// Handlers under test must implement the [ConformanceOperationSync] and [ConformanceOperationAsync] operations, and:
+//   - Reject start requests for any other operation, and requests for unknown operation IDs, with a 404 status.
+//   - Respond to start requests with the same request ID with the same operation ID.
+//   - Deliver completions to the callback URL of start requests, e.g. with [nexus.NewCompletionHTTPRequest], when
+//     operations complete.
+//   - Return results of succeeded operations, and [nexus.UnsuccessfulOperationError] for failed and canceled ones,
+//     from GetOperationResult. Return [nexus.ErrOperationStillRunning] for running operations, after waiting up to
+//     [nexus.GetOperationResultRequest.Wait] for them to complete.
+//   - Cancel running operations, completing them as canceled, and accept repeated cancelation requests.
+func RunHandlerConformance(t *testing.T, factory func(t *testing.T) nexus.Handler) {
+	for _, check := range conformanceChecks {
+		check := check
+		t.Run(check.name, func(t *testing.T) {
+			t.Parallel()
+			if err := check.run(newConformanceEnv(factory(t))); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+type conformanceCheck struct {
+	name string
+	run  func(*conformanceEnv) error
+}
+
+var conformanceChecks = []conformanceCheck{
+	{"SyncStart", checkSyncStart},
+	{"AsyncStart", checkAsyncStart},
+	{"RequestID", checkRequestID},
+	{"Callback", checkCallback},
+	{"StillRunning", checkStillRunning},
+	{"LongPoll", checkLongPoll},
+	{"LongPollTimeout", checkLongPollTimeout},
+	{"Succeeded", checkCompleted(nexus.OperationStateSucceeded)},
+	{"Failed", checkCompleted(nexus.OperationStateFailed)},
+	{"Canceled", checkCompleted(nexus.OperationStateCanceled)},
+	{"Cancel", checkCancel},
+	{"UnknownOperation", checkUnknownOperation},
+	{"UnknownOperationID", checkUnknownOperationID},
+	{"PathEscaping", checkPathEscaping},
+}
+
+// conformanceEnv sends requests to a handler under test.
+type conformanceEnv struct {
+	caller func(*http.Request) (*http.Response, error)
+}
+
+func newConformanceEnv(handler nexus.Handler) *conformanceEnv {
+	return &conformanceEnv{caller: NewHTTPCaller(nexus.NewHTTPHandler(nexus.HandlerOptions{
+		Handler:          handler,
+		GetResultTimeout: conformanceTimeout,
+	}))}
+}
+
+type conformanceResponse struct {
+	request    string
+	statusCode int
+	header     http.Header
+	body       string
+	elapsed    time.Duration
+}
+
+// errorf formats a protocol violation for the request the response was received for.
+func (r *conformanceResponse) errorf(format string, args ...any) error {
+	return fmt.Errorf("%s: %s", r.request, fmt.Sprintf(format, args...))
+}
+
+// expectStatus fails if the response does not have one of the given status codes.
+func (r *conformanceResponse) expectStatus(statusCodes ...int) error {
+	for _, statusCode := range statusCodes {
+		if r.statusCode == statusCode {
+			return nil
+		}
+	}
+	expected := make([]string, len(statusCodes))
+	for i, statusCode := range statusCodes {
+		expected[i] = fmt.Sprint(statusCode)
+	}
+	return r.errorf("expected status %s, got %d with body %q", strings.Join(expected, " or "), r.statusCode, r.body)
+}
+
+// do sends a request to the handler. The path is relative to the service root and must be escaped.
+func (e *conformanceEnv) do(method, path string, header http.Header, body string) (*conformanceResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), conformanceRequestTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, method, ServiceBaseURL+path, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		request.Header[k] = v
+	}
+	return e.send(request, method+" /"+path)
+}
+
+// send sends a request to the handler, reading the response. The request is described as given in violations.
+func (e *conformanceEnv) send(request *http.Request, description string) (*conformanceResponse, error) {
+	response := &conformanceResponse{request: description}
+	start := time.Now()
+	httpResponse, err := e.caller(request)
+	if err != nil {
+		return nil, response.errorf("request failed: %v", err)
+	}
+	defer httpResponse.Body.Close()
+	b, err := io.ReadAll(httpResponse.Body)
+	if err != nil {
+		return nil, response.errorf("failed to read response body: %v", err)
+	}
+	response.statusCode = httpResponse.StatusCode
+	response.header = httpResponse.Header
+	response.body = string(b)
+	response.elapsed = time.Since(start)
+	return response, nil
+}
+
+// start starts an async conformance operation, returning its ID.
+func (e *conformanceEnv) start(input ConformanceInput, query url.Values, header http.Header) (string, error) {
+	b, err := json.Marshal(input)
+	if err != nil {
+		return "", err
+	}
+	if header == nil {
+		header = make(http.Header)
+	}
+	header.Set("Content-Type", "application/json")
+	path := url.PathEscape(ConformanceOperationAsync)
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	response, err := e.do("POST", path, header, string(b))
+	if err != nil {
+		return "", err
+	}
+	if err := response.expectStatus(http.StatusCreated); err != nil {
+		return "", err
+	}
+	var info nexus.OperationInfo
+	if err := json.Unmarshal([]byte(response.body), &info); err != nil {
+		return "", response.errorf("invalid operation info %q: %v", response.body, err)
+	}
+	if info.ID == "" {
+		return "", response.errorf("empty operation ID in %q", response.body)
+	}
+	if info.State != nexus.OperationStateRunning {
+		return "", response.errorf("expected operation state %q, got %q", nexus.OperationStateRunning, info.State)
+	}
+	return info.ID, nil
+}
+
+func operationPath(operationID string, suffix string) string {
+	return url.PathEscape(ConformanceOperationAsync) + "/" + url.PathEscape(operationID) + suffix
+}
+
+// info gets the state of an operation.
+func (e *conformanceEnv) info(operationID string) (nexus.OperationState, error) {
+	response, err := e.do("GET", operationPath(operationID, ""), nil, "")
+	if err != nil {
+		return "", err
+	}
+	if err := response.expectStatus(http.StatusOK); err != nil {
+		return "", err
+	}
+	var info nexus.OperationInfo
+	if err := json.Unmarshal([]byte(response.body), &info); err != nil {
+		return "", response.errorf("invalid operation info %q: %v", response.body, err)
+	}
+	if info.ID != operationID {
+		return "", response.errorf("expected operation ID %q, got %q", operationID, info.ID)
+	}
+	return info.State, nil
+}
+
+// awaitState polls an operation's info until it reaches the given state.
+func (e *conformanceEnv) awaitState(operationID string, state nexus.OperationState) error {
+	deadline := time.Now().Add(conformanceTimeout)
+	for {
+		actual, err := e.info(operationID)
+		if err != nil {
+			return err
+		}
+		if actual == state {
+			return nil
+		}
+		if actual != nexus.OperationStateRunning || time.Now().After(deadline) {
+			return fmt.Errorf("GET /%s: expected operation state %q, got %q", operationPath(operationID, ""), state, actual)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// expectResult gets the result of a completed operation, expecting it to match its input.
+func (e *conformanceEnv) expectResult(operationID string, input ConformanceInput) error {
+	response, err := e.do("GET", operationPath(operationID, "/result"), nil, "")
+	if err != nil {
+		return err
+	}
+	return expectCompletedResponse(response, input)
+}
+
+func expectCompletedResponse(response *conformanceResponse, input ConformanceInput) error {
+	if input.State == nexus.OperationStateSucceeded {
+		if err := response.expectStatus(http.StatusOK); err != nil {
+			return err
+		}
+		if response.body != input.Result {
+			return response.errorf("expected result %q, got %q", input.Result, response.body)
+		}
+		return nil
+	}
+	if err := response.expectStatus(http.StatusFailedDependency); err != nil {
+		return err
+	}
+	if state := response.header.Get("Nexus-Operation-State"); state != string(input.State) {
+		return response.errorf("expected Nexus-Operation-State header %q, got %q", input.State, state)
+	}
+	var failure nexus.Failure
+	if err := json.Unmarshal([]byte(response.body), &failure); err != nil {
+		return response.errorf("invalid failure %q: %v", response.body, err)
+	}
+	if failure.Message != input.Result {
+		return response.errorf("expected failure message %q, got %q", input.Result, failure.Message)
+	}
+	return nil
+}
+
+func checkSyncStart(e *conformanceEnv) error {
+	response, err := e.do("POST", url.PathEscape(ConformanceOperationSync), http.Header{"Content-Type": []string{"text/plain"}}, "hello")
+	if err != nil {
+		return err
+	}
+	if err := response.expectStatus(http.StatusOK); err != nil {
+		return err
+	}
+	if response.body != "hello" {
+		return response.errorf("expected the input %q as result, got %q", "hello", response.body)
+	}
+	if contentType := response.header.Get("Content-Type"); contentType != "text/plain" {
+		return response.errorf("expected Content-Type %q, got %q", "text/plain", contentType)
+	}
+	return nil
+}
+
+func checkAsyncStart(e *conformanceEnv) error {
+	operationID, err := e.start(ConformanceInput{State: nexus.OperationStateRunning}, nil, nil)
+	if err != nil {
+		return err
+	}
+	return e.awaitState(operationID, nexus.OperationStateRunning)
+}
+
+func checkRequestID(e *conformanceEnv) error {
+	input := ConformanceInput{State: nexus.OperationStateRunning}
+	first, err := e.start(input, nil, http.Header{"Nexus-Request-Id": []string{"conformance-request-1"}})
+	if err != nil {
+		return err
+	}
+	retried, err := e.start(input, nil, http.Header{"Nexus-Request-Id": []string{"conformance-request-1"}})
+	if err != nil {
+		return err
+	}
+	if retried != first {
+		return fmt.Errorf("POST /%s: expected retried start request with the same request ID to return operation ID %q, got %q", ConformanceOperationAsync, first, retried)
+	}
+	other, err := e.start(input, nil, http.Header{"Nexus-Request-Id": []string{"conformance-request-2"}})
+	if err != nil {
+		return err
+	}
+	if other == first {
+		return fmt.Errorf("POST /%s: expected start request with another request ID to start a new operation, got operation ID %q again", ConformanceOperationAsync, first)
+	}
+	return nil
+}
+
+// conformanceCompletionHandler records completions delivered to a callback URL.
+type conformanceCompletionHandler struct {
+	mu          sync.Mutex
+	completions []*conformanceResponse
+	received    chan struct{}
+}
+
+func (h *conformanceCompletionHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	b, _ := io.ReadAll(request.Body)
+	h.mu.Lock()
+	h.completions = append(h.completions, &conformanceResponse{
+		request:    "completion " + request.Method + " " + request.URL.String(),
+		statusCode: http.StatusOK,
+		header:     request.Header,
+		body:       string(b),
+	})
+	if len(h.completions) == 1 {
+		close(h.received)
+	}
+	h.mu.Unlock()
+}
+
+func checkCallback(e *conformanceEnv) error {
+	completions := &conformanceCompletionHandler{received: make(chan struct{})}
+	server := httptest.NewServer(completions)
+	defer server.Close()
+
+	callbackURL := server.URL + "/callback?token=conformance"
+	input := ConformanceInput{State: nexus.OperationStateSucceeded, Result: "done", Delay: 50 * time.Millisecond}
+	if _, err := e.start(input, url.Values{"callback": []string{callbackURL}}, nil); err != nil {
+		return err
+	}
+	select {
+	case <-completions.received:
+	case <-time.After(conformanceTimeout):
+		return fmt.Errorf("expected a completion to be delivered to callback URL %s within %v", callbackURL, conformanceTimeout)
+	}
+	completions.mu.Lock()
+	completion := completions.completions[0]
+	completions.mu.Unlock()
+	if !strings.HasSuffix(completion.request, "/callback?token=conformance") || !strings.HasPrefix(completion.request, "completion POST") {
+		return fmt.Errorf("expected completion to be POSTed to callback URL %s, got %s", callbackURL, completion.request)
+	}
+	if state := completion.header.Get("Nexus-Operation-State"); state != string(nexus.OperationStateSucceeded) {
+		return completion.errorf("expected Nexus-Operation-State header %q, got %q", nexus.OperationStateSucceeded, state)
+	}
+	if completion.body != input.Result {
+		return completion.errorf("expected result %q, got %q", input.Result, completion.body)
+	}
+	return nil
+}
+
+func checkStillRunning(e *conformanceEnv) error {
+	operationID, err := e.start(ConformanceInput{State: nexus.OperationStateRunning}, nil, nil)
+	if err != nil {
+		return err
+	}
+	response, err := e.do("GET", operationPath(operationID, "/result"), nil, "")
+	if err != nil {
+		return err
+	}
+	if err := response.expectStatus(http.StatusPreconditionFailed); err != nil {
+		return err
+	}
+	if response.elapsed >= conformanceLongPollMin {
+		return response.errorf("expected request without wait to return immediately, took %v", response.elapsed)
+	}
+	return e.awaitState(operationID, nexus.OperationStateRunning)
+}
+
+func checkLongPoll(e *conformanceEnv) error {
+	input := ConformanceInput{State: nexus.OperationStateSucceeded, Result: "done", Delay: conformanceLongPollMin}
+	operationID, err := e.start(input, nil, nil)
+	if err != nil {
+		return err
+	}
+	response, err := e.do("GET", operationPath(operationID, "/result?wait=")+conformanceTimeout.String(), nil, "")
+	if err != nil {
+		return err
+	}
+	return expectCompletedResponse(response, input)
+}
+
+func checkLongPollTimeout(e *conformanceEnv) error {
+	operationID, err := e.start(ConformanceInput{State: nexus.OperationStateRunning}, nil, nil)
+	if err != nil {
+		return err
+	}
+	response, err := e.do("GET", operationPath(operationID, "/result?wait=")+conformanceLongPollMin.String(), nil, "")
+	if err != nil {
+		return err
+	}
+	if err := response.expectStatus(http.StatusRequestTimeout, http.StatusPreconditionFailed); err != nil {
+		return err
+	}
+	// Allow for some timer imprecision.
+	if response.elapsed < conformanceLongPollMin*9/10 {
+		return response.errorf("expected to wait %v for the operation to complete, responded after %v", conformanceLongPollMin, response.elapsed)
+	}
+	return nil
+}
+
+func checkCompleted(state nexus.OperationState) func(*conformanceEnv) error {
+	return func(e *conformanceEnv) error {
+		input := ConformanceInput{State: state, Result: "conformance " + string(state)}
+		operationID, err := e.start(input, nil, nil)
+		if err != nil {
+			return err
+		}
+		if err := e.awaitState(operationID, state); err != nil {
+			return err
+		}
+		return e.expectResult(operationID, input)
+	}
+}
+
+func checkCancel(e *conformanceEnv) error {
+	operationID, err := e.start(ConformanceInput{State: nexus.OperationStateRunning}, nil, nil)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < 2; i++ {
+		response, err := e.do("POST", operationPath(operationID, "/cancel"), nil, "")
+		if err != nil {
+			return err
+		}
+		if err := response.expectStatus(http.StatusAccepted); err != nil {
+			if i > 0 {
+				return fmt.Errorf("expected cancelation to be idempotent: %w", err)
+			}
+			return err
+		}
+	}
+	if err := e.awaitState(operationID, nexus.OperationStateCanceled); err != nil {
+		return err
+	}
+	response, err := e.do("GET", operationPath(operationID, "/result"), nil, "")
+	if err != nil {
+		return err
+	}
+	if err := response.expectStatus(http.StatusFailedDependency); err != nil {
+		return err
+	}
+	if state := response.header.Get("Nexus-Operation-State"); state != string(nexus.OperationStateCanceled) {
+		return response.errorf("expected Nexus-Operation-State header %q, got %q", nexus.OperationStateCanceled, state)
+	}
+	return nil
+}
+
+func checkUnknownOperation(e *conformanceEnv) error {
+	response, err := e.do("POST", "conformance-unknown", nil, "")
+	if err != nil {
+		return err
+	}
+	return response.expectStatus(http.StatusNotFound)
+}
+
+func checkUnknownOperationID(e *conformanceEnv) error {
+	var errs []error
+	for _, request := range []struct{ method, suffix string }{{"GET", ""}, {"GET", "/result"}, {"POST", "/cancel"}} {
+		response, err := e.do(request.method, operationPath("conformance-unknown-id", request.suffix), nil, "")
+		if err != nil {
+			return err
+		}
+		errs = append(errs, response.expectStatus(http.StatusNotFound))
+	}
+	return errors.Join(errs...)
+}
+
+func checkPathEscaping(e *conformanceEnv) error {
+	// Operation names are unescaped.
+	escaped := strings.ReplaceAll(ConformanceOperationSync, "-", "%2D")
+	response, err := e.do("POST", escaped, http.Header{"Content-Type": []string{"text/plain"}}, "hello")
+	if err != nil {
+		return err
+	}
+	if err := response.expectStatus(http.StatusOK); err != nil {
+		return err
+	}
+	// Escaped slashes do not separate path segments.
+	response, err = e.do("GET", url.PathEscape(ConformanceOperationAsync)+"/"+url.PathEscape("conformance/unknown-id"), nil, "")
+	if err != nil {
+		return err
+	}
+	if err := response.expectStatus(http.StatusNotFound); err != nil {
+		return err
+	}
+	// Handlers must accept the IDs they generate regardless of the characters they contain, clients escape them.
+	operationID, err := e.start(ConformanceInput{State: nexus.OperationStateRunning}, nil, nil)
+	if err != nil {
+		return err
+	}
+	return e.awaitState(operationID, nexus.OperationStateRunning)
+}
@@ -0,0 +1,202 @@
+package nexustest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nexus-rpc/sdk-go/nexus"
+	"github.com/stretchr/testify/require"
+)
+
+type conformingOperation struct {
+	state       nexus.OperationState
+	result      string
+	done        chan struct{}
+	callbackURL string
+}
+
+// conformingHandler is an in-memory handler that follows the Nexus HTTP API.
+type conformingHandler struct {
+	nexus.UnimplementedHandler
+	mu         sync.Mutex
+	operations map[string]*conformingOperation
+	requestIDs map[string]string
+}
+
+func newConformingHandler(t *testing.T) nexus.Handler {
+	return &conformingHandler{
+		operations: make(map[string]*conformingOperation),
+		requestIDs: make(map[string]string),
+	}
+}
+
+func newNotFoundError(format string, args ...any) error {
+	return &nexus.HandlerError{StatusCode: http.StatusNotFound, Failure: &nexus.Failure{Message: fmt.Sprintf(format, args...)}}
+}
+
+func (h *conformingHandler) StartOperation(ctx context.Context, request *nexus.StartOperationRequest) (nexus.OperationResponse, error) {
+	switch request.Operation {
+	case ConformanceOperationSync:
+		body, err := io.ReadAll(request.HTTPRequest.Body)
+		if err != nil {
+			return nil, err
+		}
+		header := http.Header{"Content-Type": request.HTTPRequest.Header.Values("Content-Type")}
+		return &nexus.OperationResponseSync{Header: header, Body: strings.NewReader(string(body))}, nil
+	case ConformanceOperationAsync:
+		var input ConformanceInput
+		if err := json.NewDecoder(request.HTTPRequest.Body).Decode(&input); err != nil {
+			return nil, &nexus.HandlerError{StatusCode: http.StatusBadRequest, Failure: &nexus.Failure{Message: err.Error()}}
+		}
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if operationID, ok := h.requestIDs[request.RequestID]; ok {
+			return &nexus.OperationResponseAsync{OperationID: operationID}, nil
+		}
+		// IDs contain characters that must be escaped in URL paths.
+		operationID := fmt.Sprintf("op/%d ?#%%", len(h.operations))
+		h.operations[operationID] = &conformingOperation{
+			state:       nexus.OperationStateRunning,
+			done:        make(chan struct{}),
+			callbackURL: request.CallbackURL,
+		}
+		// Requests without an ID are never deduplicated.
+		if request.RequestID != "" {
+			h.requestIDs[request.RequestID] = operationID
+		}
+		if input.State != nexus.OperationStateRunning {
+			time.AfterFunc(input.Delay, func() { h.complete(operationID, input.State, input.Result) })
+		}
+		return &nexus.OperationResponseAsync{OperationID: operationID}, nil
+	}
+	return nil, newNotFoundError("unknown operation: %q", request.Operation)
+}
+
+func (h *conformingHandler) complete(operationID string, state nexus.OperationState, result string) {
+	h.mu.Lock()
+	operation := h.operations[operationID]
+	if operation.state != nexus.OperationStateRunning {
+		h.mu.Unlock()
+		return
+	}
+	operation.state, operation.result = state, result
+	close(operation.done)
+	h.mu.Unlock()
+
+	if operation.callbackURL == "" {
+		return
+	}
+	var completion nexus.OperationCompletion = &nexus.OperationCompletionSuccessful{Body: strings.NewReader(result)}
+	if state != nexus.OperationStateSucceeded {
+		completion = &nexus.OperationCompletionUnsuccessful{State: state, Failure: &nexus.Failure{Message: result}}
+	}
+	request, err := nexus.NewCompletionHTTPRequest(context.Background(), operation.callbackURL, completion)
+	if err != nil {
+		return
+	}
+	if response, err := http.DefaultClient.Do(request); err == nil {
+		response.Body.Close()
+	}
+}
+
+func (h *conformingHandler) lookup(operationID string) (*conformingOperation, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	operation, ok := h.operations[operationID]
+	if !ok {
+		return nil, newNotFoundError("unknown operation ID: %q", operationID)
+	}
+	return operation, nil
+}
+
+func (h *conformingHandler) GetOperationResult(ctx context.Context, request *nexus.GetOperationResultRequest) (*nexus.OperationResponseSync, error) {
+	operation, err := h.lookup(request.OperationID)
+	if err != nil {
+		return nil, err
+	}
+	if request.Wait > 0 {
+		select {
+		case <-operation.done:
+		case <-time.After(request.Wait):
+		case <-ctx.Done():
+		}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	switch operation.state {
+	case nexus.OperationStateRunning:
+		return nil, nexus.ErrOperationStillRunning
+	case nexus.OperationStateSucceeded:
+		return &nexus.OperationResponseSync{Body: strings.NewReader(operation.result)}, nil
+	}
+	return nil, &nexus.UnsuccessfulOperationError{State: operation.state, Failure: nexus.Failure{Message: operation.result}}
+}
+
+func (h *conformingHandler) GetOperationInfo(ctx context.Context, request *nexus.GetOperationInfoRequest) (*nexus.OperationInfo, error) {
+	operation, err := h.lookup(request.OperationID)
+	if err != nil {
+		return nil, err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return &nexus.OperationInfo{ID: request.OperationID, State: operation.state}, nil
+}
+
+func (h *conformingHandler) CancelOperation(ctx context.Context, request *nexus.CancelOperationRequest) error {
+	if _, err := h.lookup(request.OperationID); err != nil {
+		return err
+	}
+	h.complete(request.OperationID, nexus.OperationStateCanceled, "canceled")
+	return nil
+}
+
+func TestRunHandlerConformance(t *testing.T) {
+	RunHandlerConformance(t, newConformingHandler)
+}
+
+// impatientHandler does not wait for operations to complete.
+type impatientHandler struct {
+	*conformingHandler
+}
+
+func (h impatientHandler) GetOperationResult(ctx context.Context, request *nexus.GetOperationResultRequest) (*nexus.OperationResponseSync, error) {
+	request.Wait = 0
+	return h.conformingHandler.GetOperationResult(ctx, request)
+}
+
+// cancelOnceHandler rejects repeated cancelation requests.
+type cancelOnceHandler struct {
+	*conformingHandler
+}
+
+func (h cancelOnceHandler) CancelOperation(ctx context.Context, request *nexus.CancelOperationRequest) error {
+	info, err := h.GetOperationInfo(ctx, &nexus.GetOperationInfoRequest{OperationID: request.OperationID})
+	if err != nil {
+		return err
+	}
+	if info.State != nexus.OperationStateRunning {
+		return &nexus.HandlerError{StatusCode: http.StatusConflict, Failure: &nexus.Failure{Message: "already canceled"}}
+	}
+	return h.conformingHandler.CancelOperation(ctx, request)
+}
+
+func TestConformanceChecks_ReportViolations(t *testing.T) {
+	conforming := func() *conformingHandler { return newConformingHandler(t).(*conformingHandler) }
+
+	err := checkLongPollTimeout(newConformanceEnv(impatientHandler{conforming()}))
+	require.ErrorContains(t, err, "GET /conformance-async/op%2F0%20%3F%23%25/result?wait=300ms: expected to wait 300ms")
+
+	err = checkCancel(newConformanceEnv(cancelOnceHandler{conforming()}))
+	require.ErrorContains(t, err, "expected cancelation to be idempotent")
+	require.ErrorContains(t, err, "expected status 202, got 409")
+
+	err = checkUnknownOperation(newConformanceEnv(&echoHandler{}))
+	require.ErrorContains(t, err, "POST /conformance-unknown: expected status 404, got 200")
+}
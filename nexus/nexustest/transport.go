@@ -0,0 +1,235 @@
+// Package nexustest provides utilities for testing Nexus clients and handlers in-process, without network sockets.
+package nexustest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/nexus-rpc/sdk-go/nexus"
+)
+
+// ServiceBaseURL is the base URL of services served by [NewClient]. The host is never resolved.
+const ServiceBaseURL = "http://nexus.test/"
+
+// A Transport is an [http.RoundTripper] that dispatches requests directly into an [http.Handler] in the same process.
+//
+// Requests are served concurrently with the caller, as they would be over a network: response headers are returned
+// as soon as the handler writes them, and response bodies are streamed as the handler writes them. Canceling a
+// request's context aborts reading the response. Both canceling it and closing the response body before the handler
+// returns cancel the context seen by the handler.
+type Transport struct {
+	// Handler to dispatch requests to, e.g. built with [nexus.NewHTTPHandler] or [nexus.NewCompletionHTTPHandler].
+	Handler http.Handler
+}
+
+var errHandlerPanicked = errors.New("nexustest: handler panicked")
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *Transport) RoundTrip(request *http.Request) (*http.Response, error) {
+	ctx := request.Context()
+	serverRequest, err := newServerRequest(request)
+	if err != nil {
+		if request.Body != nil {
+			request.Body.Close()
+		}
+		// Like net/http servers, reject requests with malformed URIs without invoking the handler.
+		return newBadRequestResponse(request), nil
+	}
+	// Canceled when the handler returns or the caller closes the response body, like a client disconnecting.
+	serverCtx, cancel := context.WithCancel(serverRequest.Context())
+	reader, writer := io.Pipe()
+	w := &responseWriter{
+		header: make(http.Header),
+		body:   writer,
+		ready:  make(chan struct{}),
+		response: &http.Response{
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Body:       &responseBody{PipeReader: reader, cancel: cancel},
+			Request:    request,
+		},
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer cancel()
+		t.serve(w, serverRequest.WithContext(serverCtx))
+	}()
+	go func() {
+		// Abort reading the response if the request is canceled before the handler completes.
+		select {
+		case <-ctx.Done():
+			writer.CloseWithError(ctx.Err())
+		case <-done:
+		}
+	}()
+
+	select {
+	case <-w.ready:
+		return w.response, nil
+	case <-done:
+		select {
+		case <-w.ready:
+			return w.response, nil
+		default:
+			return nil, w.err
+		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// serve invokes the handler, committing the response when it returns.
+func (t *Transport) serve(w *responseWriter, request *http.Request) {
+	defer func() {
+		if request.Body != nil {
+			request.Body.Close()
+		}
+	}()
+	defer func() {
+		if r := recover(); r != nil {
+			w.fail(fmt.Errorf("%w: %v", errHandlerPanicked, r))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.body.Close()
+	}()
+	t.Handler.ServeHTTP(w, request)
+}
+
+// responseBody is the body of a response returned by [Transport.RoundTrip].
+type responseBody struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+}
+
+// Close cancels the context of the handler, which may still be writing the response, and closes the body.
+func (b *responseBody) Close() error {
+	b.cancel()
+	return b.PipeReader.Close()
+}
+
+// newServerRequest converts a client request into a request as seen by a server.
+func newServerRequest(request *http.Request) (*http.Request, error) {
+	serverURL, err := url.ParseRequestURI(request.URL.RequestURI())
+	if err != nil {
+		return nil, err
+	}
+	serverRequest := request.Clone(request.Context())
+	serverRequest.URL = serverURL
+	serverRequest.RequestURI = request.URL.RequestURI()
+	serverRequest.RemoteAddr = "192.0.2.1:1234"
+	serverRequest.Proto, serverRequest.ProtoMajor, serverRequest.ProtoMinor = "HTTP/1.1", 1, 1
+	if serverRequest.Host == "" {
+		serverRequest.Host = request.URL.Host
+	}
+	if serverRequest.Body == nil {
+		serverRequest.Body = http.NoBody
+	}
+	return serverRequest, nil
+}
+
+// newBadRequestResponse returns the response of net/http servers to malformed requests.
+func newBadRequestResponse(request *http.Request) *http.Response {
+	body := "400 Bad Request"
+	return &http.Response{
+		Status:        "400 Bad Request",
+		StatusCode:    http.StatusBadRequest,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"text/plain; charset=utf-8"}, "Connection": []string{"close"}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}
+}
+
+// responseWriter streams a handler's response to the caller of [Transport.RoundTrip].
+type responseWriter struct {
+	header   http.Header
+	body     *io.PipeWriter
+	ready    chan struct{}
+	response *http.Response
+
+	mu          sync.Mutex
+	wroteHeader bool
+	// Error to fail the round trip with if the handler panics before writing the response headers.
+	err error
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *responseWriter) WriteHeader(statusCode int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.response.StatusCode = statusCode
+	w.response.Status = fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode))
+	w.response.Header = w.header.Clone()
+	w.response.ContentLength = -1
+	if contentLength, err := strconv.ParseInt(w.header.Get("Content-Length"), 10, 64); err == nil {
+		w.response.ContentLength = contentLength
+	}
+	close(w.ready)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	if !w.wroteHeader && w.header.Get("Content-Type") == "" && len(b) > 0 {
+		// Like net/http servers, sniff the content type of responses that do not declare one.
+		w.header.Set("Content-Type", http.DetectContentType(b))
+	}
+	w.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(b)
+}
+
+// Flush implements the http.Flusher interface. Writes are unbuffered, flushing only commits the response headers.
+func (w *responseWriter) Flush() {
+	w.WriteHeader(http.StatusOK)
+}
+
+// fail aborts the response, failing the round trip if the response headers have not been written yet and reading the
+// response body otherwise.
+func (w *responseWriter) fail(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.err = err
+	w.body.CloseWithError(err)
+}
+
+// NewHTTPCaller returns a function that dispatches requests directly into handler, for use as
+// [nexus.ClientOptions.HTTPCaller].
+func NewHTTPCaller(handler http.Handler) func(*http.Request) (*http.Response, error) {
+	return (&Transport{Handler: handler}).RoundTrip
+}
+
+// NewHTTPClient returns an HTTP client that dispatches requests directly into handler, e.g. for delivering completions
+// built with [nexus.NewCompletionHTTPRequest] to a handler built with [nexus.NewCompletionHTTPHandler].
+func NewHTTPClient(handler http.Handler) *http.Client {
+	return &http.Client{Transport: &Transport{Handler: handler}}
+}
+
+// NewClient constructs a [nexus.Client] for the service served by handler, dispatching requests directly into it.
+// The service base URL defaults to [ServiceBaseURL].
+func NewClient(handler http.Handler, options nexus.ClientOptions) (*nexus.Client, error) {
+	if options.ServiceBaseURL == "" {
+		options.ServiceBaseURL = ServiceBaseURL
+	}
+	options.HTTPCaller = NewHTTPCaller(handler)
+	return nexus.NewClient(options)
+}
@@ -0,0 +1,194 @@
+package nexustest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nexus-rpc/sdk-go/nexus"
+	"github.com/stretchr/testify/require"
+)
+
+type echoHandler struct {
+	nexus.UnimplementedHandler
+	release chan struct{}
+}
+
+func (h *echoHandler) StartOperation(ctx context.Context, request *nexus.StartOperationRequest) (nexus.OperationResponse, error) {
+	if request.Operation == "async" {
+		return &nexus.OperationResponseAsync{OperationID: "a/b"}, nil
+	}
+	body, err := io.ReadAll(request.HTTPRequest.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &nexus.OperationResponseSync{Body: bytes.NewReader(body)}, nil
+}
+
+func (h *echoHandler) GetOperationResult(ctx context.Context, request *nexus.GetOperationResultRequest) (*nexus.OperationResponseSync, error) {
+	if request.Wait > 0 {
+		select {
+		case <-h.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return &nexus.OperationResponseSync{Body: bytes.NewReader([]byte(request.OperationID))}, nil
+}
+
+func TestNewClient(t *testing.T) {
+	handler := &echoHandler{release: make(chan struct{})}
+	client, err := NewClient(nexus.NewHTTPHandler(nexus.HandlerOptions{Handler: handler}), nexus.ClientOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	result, err := client.StartOperation(ctx, nexus.StartOperationOptions{Operation: "sync", Body: bytes.NewReader([]byte("input"))})
+	require.NoError(t, err)
+	require.NotNil(t, result.Successful)
+	body, err := io.ReadAll(result.Successful.Body)
+	require.NoError(t, err)
+	require.Equal(t, "input", string(body))
+
+	result, err = client.StartOperation(ctx, nexus.StartOperationOptions{Operation: "async"})
+	require.NoError(t, err)
+	require.NotNil(t, result.Pending)
+	require.Equal(t, "a/b", result.Pending.ID)
+
+	// Long poll, waiting until the result is released.
+	time.AfterFunc(time.Millisecond*100, func() { close(handler.release) })
+	start := time.Now()
+	response, err := result.Pending.GetResult(ctx, nexus.GetOperationResultOptions{Wait: time.Second})
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), time.Millisecond*100)
+	body, err = io.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.Equal(t, "a/b", string(body))
+}
+
+func TestTransport_StreamsResponse(t *testing.T) {
+	release := make(chan struct{})
+	client := NewHTTPClient(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/plain")
+		writer.WriteHeader(http.StatusAccepted)
+		_, _ = writer.Write([]byte("first"))
+		writer.(http.Flusher).Flush()
+		<-release
+		_, _ = writer.Write([]byte("second"))
+	}))
+
+	response, err := client.Get(ServiceBaseURL + "stream")
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusAccepted, response.StatusCode)
+	require.Equal(t, "text/plain", response.Header.Get("Content-Type"))
+	first := make([]byte, 5)
+	_, err = io.ReadFull(response.Body, first)
+	require.NoError(t, err)
+	require.Equal(t, "first", string(first))
+
+	close(release)
+	rest, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.Equal(t, "second", string(rest))
+}
+
+func TestTransport_ContextCancelation(t *testing.T) {
+	canceled := make(chan struct{})
+	caller := NewHTTPCaller(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		<-request.Context().Done()
+		close(canceled)
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, "GET", ServiceBaseURL, nil)
+	require.NoError(t, err)
+	_, err = caller(request)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("handler context not canceled")
+	}
+}
+
+func TestTransport_Panic(t *testing.T) {
+	caller := NewHTTPCaller(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		panic("boom")
+	}))
+	request, err := http.NewRequest("GET", ServiceBaseURL, nil)
+	require.NoError(t, err)
+	_, err = caller(request)
+	require.ErrorIs(t, err, errHandlerPanicked)
+}
+
+type recordingCompletionHandler struct {
+	requests []*nexus.CompletionRequest
+}
+
+func (h *recordingCompletionHandler) CompleteOperation(ctx context.Context, request *nexus.CompletionRequest) error {
+	h.requests = append(h.requests, request)
+	return nil
+}
+
+func TestNewHTTPClient_Completion(t *testing.T) {
+	handler := &recordingCompletionHandler{}
+	client := NewHTTPClient(nexus.NewCompletionHTTPHandler(nexus.CompletionHandlerOptions{Handler: handler}))
+
+	completion, err := nexus.NewOperationCompletionSuccessful("done")
+	require.NoError(t, err)
+	request, err := nexus.NewCompletionHTTPRequest(context.Background(), ServiceBaseURL+"callback?a=b", completion)
+	require.NoError(t, err)
+	response, err := client.Do(request)
+	require.NoError(t, err)
+	response.Body.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Len(t, handler.requests, 1)
+	require.Equal(t, nexus.OperationStateSucceeded, handler.requests[0].State)
+	require.Equal(t, "b", handler.requests[0].HTTPRequest.URL.Query().Get("a"))
+}
+
+func TestTransport_ResponseBodyClose(t *testing.T) {
+	canceled := make(chan struct{})
+	client := NewHTTPClient(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusOK)
+		<-request.Context().Done()
+		close(canceled)
+	}))
+
+	response, err := client.Get(ServiceBaseURL + "stream")
+	require.NoError(t, err)
+	require.NoError(t, response.Body.Close())
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("handler context not canceled")
+	}
+}
+
+// closeRecorder records whether a request body was closed.
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestTransport_MalformedRequestURI(t *testing.T) {
+	caller := NewHTTPCaller(http.NotFoundHandler())
+	body := &closeRecorder{Reader: bytes.NewReader(nil)}
+	request, err := http.NewRequest("POST", ServiceBaseURL, body)
+	require.NoError(t, err)
+	request.URL.Opaque = "/%zz"
+	response, err := caller(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusBadRequest, response.StatusCode)
+	require.True(t, body.closed)
+}
@@ -0,0 +1,261 @@
+package nexus
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// OperationResult is the result of a completed operation, delivered to waiters via a [ResultNotifier].
+//
+// Results are kept in memory and may be delivered to any number of waiters, hence bodies are represented as byte
+// slices rather than readers.
+type OperationResult struct {
+	// Header to deliver in the HTTP response of successful results.
+	Header http.Header
+	// Body of successful results.
+	Body []byte
+	// Set if the operation completed as failed or canceled.
+	Unsuccessful *UnsuccessfulOperationError
+}
+
+// NewOperationResult constructs a successful [OperationResult], setting the proper Content-Type header.
+// Marshals the provided value to JSON using [json.Marshal].
+func NewOperationResult(v any) (*OperationResult, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	header := make(http.Header, 1)
+	header.Set(headerContentType, contentTypeJSON)
+	return &OperationResult{Header: header, Body: b}, nil
+}
+
+// Response converts the result to the return values of [Handler.GetOperationResult].
+// Each call returns a response with its own copy of the header and its own body reader.
+func (r *OperationResult) Response() (*OperationResponseSync, error) {
+	if r.Unsuccessful != nil {
+		return nil, r.Unsuccessful
+	}
+	return &OperationResponseSync{
+		Header: r.Header.Clone(),
+		Body:   bytes.NewReader(r.Body),
+	}, nil
+}
+
+// ResultNotifierOptions are options for [NewResultNotifier].
+type ResultNotifierOptions struct {
+	// Maximum number of results to retain for waiters that subscribe after an operation has completed.
+	// When exceeded, the oldest results are evicted first.
+	//
+	// Defaults to 1000.
+	MaxRetainedResults int
+	// Duration to retain results for waiters that subscribe after an operation has completed.
+	//
+	// Defaults to one minute.
+	RetentionPeriod time.Duration
+}
+
+// ResultNotifier is an in-process hub for delivering operation results to long poll get-result requests.
+//
+// Applications call [ResultNotifier.Notify] when an operation completes, and [Handler.GetOperationResult]
+// implementations call [ResultNotifier.Await] or use [ResultNotifier.GetOperationResult] to block until the result is
+// available. A single notification is delivered to all concurrent waiters for the same operation.
+//
+// Results are retained for a bounded time and count so that waiters that subscribe right after an operation has
+// completed still observe its result. The notifier does not replace durable storage: GetOperationResult
+// implementations should check their application's store before waiting and the notifier only closes the gap between
+// that check and the operation's completion.
+//
+// Results are scoped to tenants: results delivered with [ResultNotifier.NotifyTenant] are only observed by waiters
+// whose context carries the same tenant, see [TenantFromContext], so that operation IDs of one tenant can never be
+// used to read results of another.
+type ResultNotifier struct {
+	options ResultNotifierOptions
+
+	mu            sync.Mutex
+	subscriptions map[resultKey]*resultSubscription
+	retained      map[resultKey]*list.Element
+	// Retained results, oldest first.
+	retainedOrder *list.List
+}
+
+// resultKey identifies an operation's result within a tenant.
+type resultKey struct {
+	tenant      string
+	operationID string
+}
+
+func resultKeyFromContext(ctx context.Context, operationID string) resultKey {
+	tenant, _ := TenantFromContext(ctx)
+	return resultKey{tenant: tenant, operationID: operationID}
+}
+
+type resultSubscription struct {
+	done    chan struct{}
+	result  *OperationResult
+	waiters int
+}
+
+type retainedResult struct {
+	key       resultKey
+	result    *OperationResult
+	expiresAt time.Time
+}
+
+// NewResultNotifier constructs a new [ResultNotifier].
+func NewResultNotifier(options ResultNotifierOptions) *ResultNotifier {
+	if options.MaxRetainedResults <= 0 {
+		options.MaxRetainedResults = 1000
+	}
+	if options.RetentionPeriod <= 0 {
+		options.RetentionPeriod = time.Minute
+	}
+	return &ResultNotifier{
+		options:       options,
+		subscriptions: make(map[resultKey]*resultSubscription),
+		retained:      make(map[resultKey]*list.Element),
+		retainedOrder: list.New(),
+	}
+}
+
+// Notify delivers the result of a completed operation to all of its current waiters and retains it for waiters that
+// subscribe later. Only the first notification for a retained operation is kept, subsequent notifications are ignored.
+//
+// Results delivered with Notify are not scoped to a tenant, use [ResultNotifier.NotifyTenant] for operations started
+// by handlers with [HandlerOptions.Tenancy] set.
+func (n *ResultNotifier) Notify(operationID string, result *OperationResult) {
+	n.notify(resultKey{operationID: operationID}, result)
+}
+
+// NotifyTenant is like [ResultNotifier.Notify] for an operation started by the given tenant. The result is only
+// delivered to waiters whose context carries the same tenant.
+func (n *ResultNotifier) NotifyTenant(tenant, operationID string, result *OperationResult) {
+	n.notify(resultKey{tenant: tenant, operationID: operationID}, result)
+}
+
+func (n *ResultNotifier) notify(key resultKey, result *OperationResult) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.evictLocked(time.Now())
+	if _, ok := n.retained[key]; ok {
+		return
+	}
+	if sub, ok := n.subscriptions[key]; ok {
+		sub.result = result
+		close(sub.done)
+		delete(n.subscriptions, key)
+	}
+	n.retained[key] = n.retainedOrder.PushBack(&retainedResult{
+		key:       key,
+		result:    result,
+		expiresAt: time.Now().Add(n.options.RetentionPeriod),
+	})
+	if n.retainedOrder.Len() > n.options.MaxRetainedResults {
+		n.removeLocked(n.retainedOrder.Front())
+	}
+}
+
+// Peek returns the retained result of an operation delivered with [ResultNotifier.Notify], if it is known to have
+// completed.
+func (n *ResultNotifier) Peek(operationID string) (*OperationResult, bool) {
+	return n.peek(resultKey{operationID: operationID})
+}
+
+// PeekTenant is like [ResultNotifier.Peek] for an operation started by the given tenant.
+func (n *ResultNotifier) PeekTenant(tenant, operationID string) (*OperationResult, bool) {
+	return n.peek(resultKey{tenant: tenant, operationID: operationID})
+}
+
+func (n *ResultNotifier) peek(key resultKey) (*OperationResult, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.evictLocked(time.Now())
+	if element, ok := n.retained[key]; ok {
+		return element.Value.(*retainedResult).result, true
+	}
+	return nil, false
+}
+
+// Await blocks until the given operation's result is delivered via [ResultNotifier.Notify] or the context is done, in
+// which case the context's error is returned. Returns immediately if the result is already retained.
+//
+// Only results delivered for the tenant carried by ctx, if any, are observed.
+func (n *ResultNotifier) Await(ctx context.Context, operationID string) (*OperationResult, error) {
+	key := resultKeyFromContext(ctx, operationID)
+	n.mu.Lock()
+	n.evictLocked(time.Now())
+	if element, ok := n.retained[key]; ok {
+		n.mu.Unlock()
+		return element.Value.(*retainedResult).result, nil
+	}
+	sub, ok := n.subscriptions[key]
+	if !ok {
+		sub = &resultSubscription{done: make(chan struct{})}
+		n.subscriptions[key] = sub
+	}
+	sub.waiters++
+	n.mu.Unlock()
+
+	select {
+	case <-sub.done:
+		return sub.result, nil
+	case <-ctx.Done():
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	select {
+	case <-sub.done:
+		// Notified while racing with context cancelation, prefer the result.
+		return sub.result, nil
+	default:
+	}
+	sub.waiters--
+	if sub.waiters == 0 {
+		delete(n.subscriptions, key)
+	}
+	return nil, ctx.Err()
+}
+
+// GetOperationResult is a helper for implementing [Handler.GetOperationResult] on top of the notifier.
+//
+// Requests without a wait duration return the retained result, if any, or [ErrOperationStillRunning]. Long poll
+// requests wait up to [GetOperationResultRequest.Wait] for the result before returning [ErrOperationStillRunning].
+func (n *ResultNotifier) GetOperationResult(ctx context.Context, request *GetOperationResultRequest) (*OperationResponseSync, error) {
+	if request.Wait <= 0 {
+		result, ok := n.peek(resultKeyFromContext(ctx, request.OperationID))
+		if !ok {
+			return nil, ErrOperationStillRunning
+		}
+		return result.Response()
+	}
+	ctx, cancel := context.WithTimeout(ctx, request.Wait)
+	defer cancel()
+	result, err := n.Await(ctx, request.OperationID)
+	if err != nil {
+		return nil, ErrOperationStillRunning
+	}
+	return result.Response()
+}
+
+// evictLocked removes expired results. Must be called with the lock held.
+func (n *ResultNotifier) evictLocked(now time.Time) {
+	for element := n.retainedOrder.Front(); element != nil; element = n.retainedOrder.Front() {
+		if element.Value.(*retainedResult).expiresAt.After(now) {
+			return
+		}
+		n.removeLocked(element)
+	}
+}
+
+// removeLocked removes a retained result. Must be called with the lock held.
+func (n *ResultNotifier) removeLocked(element *list.Element) {
+	n.retainedOrder.Remove(element)
+	delete(n.retained, element.Value.(*retainedResult).key)
+}
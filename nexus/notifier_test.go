@@ -0,0 +1,148 @@
+package nexus
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultNotifier_NotifyBeforeAwait(t *testing.T) {
+	notifier := NewResultNotifier(ResultNotifierOptions{})
+	result, err := NewOperationResult("done")
+	require.NoError(t, err)
+	notifier.Notify("id", result)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	awaited, err := notifier.Await(ctx, "id")
+	require.NoError(t, err)
+	require.Equal(t, result, awaited)
+}
+
+func TestResultNotifier_FanOut(t *testing.T) {
+	notifier := NewResultNotifier(ResultNotifierOptions{})
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	const numWaiters = 10
+	var wg sync.WaitGroup
+	results := make(chan *OperationResult, numWaiters)
+	errs := make(chan error, numWaiters)
+	for i := 0; i < numWaiters; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := notifier.Await(ctx, "id")
+			if err != nil {
+				errs <- err
+				return
+			}
+			results <- result
+		}()
+	}
+	require.Eventually(t, func() bool {
+		notifier.mu.Lock()
+		defer notifier.mu.Unlock()
+		sub, ok := notifier.subscriptions[resultKey{operationID: "id"}]
+		return ok && sub.waiters == numWaiters
+	}, time.Second, time.Millisecond*10)
+
+	result := &OperationResult{Body: []byte("done")}
+	notifier.Notify("id", result)
+	wg.Wait()
+	close(results)
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.Len(t, results, numWaiters)
+	for r := range results {
+		require.Equal(t, result, r)
+	}
+	require.Empty(t, notifier.subscriptions)
+}
+
+func TestResultNotifier_AbandonedWaitersAreRemoved(t *testing.T) {
+	notifier := NewResultNotifier(ResultNotifierOptions{})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	_, err := notifier.Await(ctx, "id")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Empty(t, notifier.subscriptions)
+}
+
+func TestResultNotifier_BoundedRetention(t *testing.T) {
+	notifier := NewResultNotifier(ResultNotifierOptions{MaxRetainedResults: 2, RetentionPeriod: time.Millisecond * 100})
+	notifier.Notify("a", &OperationResult{Body: []byte("a")})
+	notifier.Notify("b", &OperationResult{Body: []byte("b")})
+	notifier.Notify("c", &OperationResult{Body: []byte("c")})
+
+	_, ok := notifier.Peek("a")
+	require.False(t, ok)
+	result, ok := notifier.Peek("b")
+	require.True(t, ok)
+	require.Equal(t, []byte("b"), result.Body)
+
+	// Duplicate notifications are ignored.
+	notifier.Notify("c", &OperationResult{Body: []byte("c2")})
+	result, ok = notifier.Peek("c")
+	require.True(t, ok)
+	require.Equal(t, []byte("c"), result.Body)
+
+	require.Eventually(t, func() bool {
+		_, ok := notifier.Peek("c")
+		return !ok
+	}, time.Second, time.Millisecond*10)
+	require.Equal(t, 0, notifier.retainedOrder.Len())
+}
+
+type notifierHandler struct {
+	UnimplementedHandler
+	notifier *ResultNotifier
+}
+
+func (h *notifierHandler) GetOperationResult(ctx context.Context, request *GetOperationResultRequest) (*OperationResponseSync, error) {
+	return h.notifier.GetOperationResult(ctx, request)
+}
+
+func TestResultNotifier_GetOperationResult(t *testing.T) {
+	notifier := NewResultNotifier(ResultNotifierOptions{})
+	ctx, client, teardown := setup(t, &notifierHandler{notifier: notifier})
+	defer teardown()
+
+	handle, err := client.NewHandle("foo", "id")
+	require.NoError(t, err)
+
+	_, err = handle.GetResult(ctx, GetOperationResultOptions{})
+	require.ErrorIs(t, err, ErrOperationStillRunning)
+
+	_, err = handle.GetResult(ctx, GetOperationResultOptions{Wait: time.Millisecond * 50})
+	require.ErrorIs(t, err, ErrOperationStillRunning)
+
+	result, err := NewOperationResult("done")
+	require.NoError(t, err)
+	go func() {
+		time.Sleep(time.Millisecond * 100)
+		notifier.Notify("id", result)
+	}()
+	// Wait longer than the handler's GetResultTimeout to verify that the client re-polls.
+	response, err := handle.GetResult(ctx, GetOperationResultOptions{Wait: time.Second})
+	require.NoError(t, err)
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.Equal(t, `"done"`, string(body))
+	require.Equal(t, contentTypeJSON, response.Header.Get(headerContentType))
+
+	notifier.Notify("canceled", &OperationResult{Unsuccessful: &UnsuccessfulOperationError{State: OperationStateCanceled}})
+	handle, err = client.NewHandle("foo", "canceled")
+	require.NoError(t, err)
+	_, err = handle.GetResult(ctx, GetOperationResultOptions{})
+	var unsuccessfulError *UnsuccessfulOperationError
+	require.ErrorAs(t, err, &unsuccessfulError)
+	require.Equal(t, OperationStateCanceled, unsuccessfulError.State)
+}
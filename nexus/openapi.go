@@ -0,0 +1,310 @@
+package nexus
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// OpenAPIOptions are options for [GenerateOpenAPI].
+type OpenAPIOptions struct {
+	// Title of the API.
+	// Defaults to the registry's service name, or "Nexus Service" if unset.
+	Title string
+	// Version of the API.
+	// Defaults to the registry's service version, or "0.0.0" if unset.
+	Version string
+	// Description of the API. Optional.
+	Description string
+	// URLs of servers hosting the service, e.g. the ServiceBaseURL clients are configured with. Optional.
+	ServerURLs []string
+}
+
+const openAPIVersion = "3.1.0"
+
+// GenerateOpenAPI generates an [OpenAPI 3] document in JSON format describing the Nexus HTTP API of the operations
+// registered in registry, as served by a handler created with [NewHTTPHandler].
+//
+// The document includes the service description route and the start, get-result, get-info, and cancel routes for each
+// operation. Input and output schemas are taken from
+// each [OperationDefinition]'s InputSchema and OutputSchema, falling back to schemas generated with
+// [JSONSchemaForType] from the definition's InputType and OutputType.
+//
+// [OpenAPI 3]: https://spec.openapis.org/oas/v3.1.0
+func GenerateOpenAPI(registry *OperationRegistry, options OpenAPIOptions) ([]byte, error) {
+	if options.Title == "" {
+		options.Title = registry.options.Name
+	}
+	if options.Title == "" {
+		options.Title = "Nexus Service"
+	}
+	if options.Version == "" {
+		options.Version = registry.options.Version
+	}
+	if options.Version == "" {
+		options.Version = "0.0.0"
+	}
+
+	info := map[string]any{"title": options.Title, "version": options.Version}
+	if options.Description != "" {
+		info["description"] = options.Description
+	}
+	document := map[string]any{
+		"openapi":    openAPIVersion,
+		"info":       info,
+		"paths":      openAPIPaths(registry.Operations()),
+		"components": openAPIComponents(),
+	}
+	if len(options.ServerURLs) > 0 {
+		servers := make([]any, len(options.ServerURLs))
+		for i, u := range options.ServerURLs {
+			servers[i] = map[string]any{"url": u}
+		}
+		document["servers"] = servers
+	}
+	return json.MarshalIndent(document, "", "  ")
+}
+
+func openAPIRef(kind, name string) map[string]any {
+	return map[string]any{"$ref": "#/components/" + kind + "/" + name}
+}
+
+func openAPIJSONContent(schema any) map[string]any {
+	return map[string]any{contentTypeJSON: map[string]any{"schema": schema}}
+}
+
+func openAPIFailureResponse(description string) map[string]any {
+	return map[string]any{
+		"description": description,
+		"content":     openAPIJSONContent(openAPIRef("schemas", "Failure")),
+	}
+}
+
+// openAPIPayload describes an operation's input or output.
+func openAPIPayload(contentType string, schema json.RawMessage, typeSchema func() json.RawMessage) map[string]any {
+	if contentType == "" {
+		contentType = "*/*"
+	}
+	mediaType := map[string]any{}
+	if len(schema) == 0 {
+		schema = typeSchema()
+	}
+	if len(schema) > 0 {
+		mediaType["schema"] = schema
+	}
+	return map[string]any{contentType: mediaType}
+}
+
+func openAPIPaths(definitions []OperationDefinition) map[string]any {
+	paths := map[string]any{
+		"/": map[string]any{
+			"get": map[string]any{
+				"operationId": "describeService",
+				"summary":     "Describe the service and the operations it exposes.",
+				"responses": map[string]any{
+					"200": map[string]any{
+						"description": "Service description.",
+						"content":     openAPIJSONContent(openAPIRef("schemas", "ServiceDescription")),
+					},
+					"default": openAPIFailureResponse("Request failed."),
+				},
+			},
+		},
+	}
+
+	for _, definition := range definitions {
+		definition := definition
+		escapedName := url.PathEscape(definition.Name)
+		input := openAPIPayload(definition.InputContentType, definition.InputSchema, func() json.RawMessage {
+			if definition.InputType == nil {
+				return nil
+			}
+			return JSONSchemaForType(definition.InputType)
+		})
+		output := openAPIPayload(definition.OutputContentType, definition.OutputSchema, func() json.RawMessage {
+			if definition.OutputType == nil {
+				return nil
+			}
+			return JSONSchemaForType(definition.OutputType)
+		})
+		// Definitions that specify neither capability may complete either way.
+		sync := definition.Sync || !definition.Async
+		async := definition.Async || !definition.Sync
+		tags := []string{definition.Name}
+		resultResponse := map[string]any{
+			"description": "Operation completed successfully.",
+			"content":     output,
+		}
+		unsuccessfulResponse := map[string]any{
+			"description": "Operation completed as failed or canceled.",
+			"headers": map[string]any{
+				headerOperationState: openAPIRef("headers", "OperationState"),
+			},
+			"content": openAPIJSONContent(openAPIRef("schemas", "Failure")),
+		}
+
+		startResponses := map[string]any{
+			strconv.Itoa(statusOperationFailed): unsuccessfulResponse,
+			"default":                           openAPIFailureResponse("Request failed."),
+		}
+		if sync {
+			startResponses[strconv.Itoa(http.StatusOK)] = resultResponse
+		}
+		if async {
+			startResponses[strconv.Itoa(http.StatusCreated)] = map[string]any{
+				"description": "Asynchronous operation started.",
+				"content":     openAPIJSONContent(openAPIRef("schemas", "OperationInfo")),
+			}
+		}
+		start := map[string]any{
+			"operationId": "startOperation:" + definition.Name,
+			"summary":     "Start the " + definition.Name + " operation.",
+			"tags":        tags,
+			"parameters": []any{
+				openAPIRef("parameters", "RequestID"),
+				openAPIRef("parameters", "Callback"),
+			},
+			"requestBody": map[string]any{"content": input},
+			"responses":   startResponses,
+		}
+		if definition.Description != "" {
+			start["description"] = definition.Description
+		}
+		paths["/"+escapedName] = map[string]any{"post": start}
+
+		// The remaining routes are served for every operation, regardless of how it's declared to complete.
+		paths["/"+escapedName+"/{operationId}"] = map[string]any{
+			"parameters": []any{openAPIRef("parameters", "OperationID")},
+			"get": map[string]any{
+				"operationId": "getOperationInfo:" + definition.Name,
+				"summary":     "Get information about a " + definition.Name + " operation.",
+				"tags":        tags,
+				"responses": map[string]any{
+					strconv.Itoa(http.StatusOK): map[string]any{
+						"description": "Operation information.",
+						"content":     openAPIJSONContent(openAPIRef("schemas", "OperationInfo")),
+					},
+					"default": openAPIFailureResponse("Request failed."),
+				},
+			},
+		}
+		paths["/"+escapedName+"/{operationId}/result"] = map[string]any{
+			"parameters": []any{openAPIRef("parameters", "OperationID")},
+			"get": map[string]any{
+				"operationId": "getOperationResult:" + definition.Name,
+				"summary":     "Get the result of a " + definition.Name + " operation.",
+				"tags":        tags,
+				"parameters":  []any{openAPIRef("parameters", "Wait")},
+				"responses": map[string]any{
+					strconv.Itoa(http.StatusOK): resultResponse,
+					strconv.Itoa(http.StatusRequestTimeout): map[string]any{
+						"description": "The server's long poll timeout was reached before the operation completed. " +
+							"Clients should poll again.",
+					},
+					strconv.Itoa(statusOperationRunning): map[string]any{
+						"description": "The operation is still running.",
+					},
+					strconv.Itoa(statusOperationFailed): unsuccessfulResponse,
+					"default":                           openAPIFailureResponse("Request failed."),
+				},
+			},
+		}
+		paths["/"+escapedName+"/{operationId}/cancel"] = map[string]any{
+			"parameters": []any{openAPIRef("parameters", "OperationID")},
+			"post": map[string]any{
+				"operationId": "cancelOperation:" + definition.Name,
+				"summary":     "Request cancelation of a " + definition.Name + " operation.",
+				"tags":        tags,
+				"responses": map[string]any{
+					strconv.Itoa(http.StatusAccepted): map[string]any{
+						"description": "Cancelation request delivered.",
+					},
+					"default": openAPIFailureResponse("Request failed."),
+				},
+			},
+		}
+	}
+	return paths
+}
+
+func openAPIComponents() map[string]any {
+	operationStateSchema := map[string]any{
+		"type": "string",
+		"enum": []string{
+			string(OperationStateRunning),
+			string(OperationStateSucceeded),
+			string(OperationStateFailed),
+			string(OperationStateCanceled),
+		},
+	}
+	return map[string]any{
+		"schemas": map[string]any{
+			"Failure": map[string]any{
+				"type":        "object",
+				"description": "Protocol level failure returned in unsuccessful responses.",
+				"properties": map[string]any{
+					"message": map[string]any{"type": "string"},
+					"metadata": map[string]any{
+						"type":                 "object",
+						"additionalProperties": map[string]any{"type": "string"},
+					},
+					"details": map[string]any{},
+					"cause":   openAPIRef("schemas", "Failure"),
+					"causes": map[string]any{
+						"description": "Causes of failures with several causes, e.g. joined errors, in place of cause.",
+						"type":        "array",
+						"items":       openAPIRef("schemas", "Failure"),
+					},
+				},
+				"required": []string{"message"},
+			},
+			"OperationState": operationStateSchema,
+			"OperationInfo": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"id":    map[string]any{"type": "string"},
+					"state": openAPIRef("schemas", "OperationState"),
+				},
+				"required": []string{"id", "state"},
+			},
+			"ServiceDescription": JSONSchemaFor[ServiceDescription](),
+		},
+		"parameters": map[string]any{
+			"RequestID": map[string]any{
+				"name":        headerRequestID,
+				"in":          "header",
+				"description": "Request ID used by handlers to dedupe start requests.",
+				"schema":      map[string]any{"type": "string"},
+			},
+			"Callback": map[string]any{
+				"name":        queryCallbackURL,
+				"in":          "query",
+				"description": "URL to deliver the completion of an asynchronous operation to.",
+				"schema":      map[string]any{"type": "string", "format": "uri"},
+			},
+			"Wait": map[string]any{
+				"name":        queryWait,
+				"in":          "query",
+				"description": "Duration to wait for the operation to complete, turning the request into a long poll, e.g. 10s.",
+				"schema":      map[string]any{"type": "string"},
+			},
+			"OperationID": map[string]any{
+				"name":        "operationId",
+				"in":          "path",
+				"required":    true,
+				"description": "Operation ID as returned by the handler in response to a start request.",
+				"schema":      map[string]any{"type": "string"},
+			},
+		},
+		"headers": map[string]any{
+			"OperationState": map[string]any{
+				"description": "State of an unsuccessfully completed operation.",
+				"schema": map[string]any{
+					"type": "string",
+					"enum": []string{string(OperationStateFailed), string(OperationStateCanceled)},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,131 @@
+package nexus
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateOpenAPI(t *testing.T) {
+	registry := NewOperationRegistry(OperationRegistryOptions{Name: "greeter", Version: "v1"})
+	greet := NewOperationDefinition[describeInput, describeOutput]("greet")
+	greet.Description = "Greets."
+	syncOnly := OperationDefinition{
+		Name:        "sync/only",
+		Sync:        true,
+		InputSchema: json.RawMessage(`{"type":"string"}`),
+	}
+	require.NoError(t, registry.Register(greet, syncOnly))
+
+	b, err := GenerateOpenAPI(registry, OpenAPIOptions{ServerURLs: []string{"https://example.com/greeter"}})
+	require.NoError(t, err)
+
+	var document struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct {
+			Title   string `json:"title"`
+			Version string `json:"version"`
+		} `json:"info"`
+		Servers []struct {
+			URL string `json:"url"`
+		} `json:"servers"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas    map[string]json.RawMessage `json:"schemas"`
+			Parameters map[string]struct {
+				Name string `json:"name"`
+				In   string `json:"in"`
+			} `json:"parameters"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(b, &document))
+	require.Equal(t, "3.1.0", document.OpenAPI)
+	require.Equal(t, "greeter", document.Info.Title)
+	require.Equal(t, "v1", document.Info.Version)
+	require.Equal(t, "https://example.com/greeter", document.Servers[0].URL)
+
+	var paths []string
+	for p := range document.Paths {
+		paths = append(paths, p)
+	}
+	require.ElementsMatch(t, []string{
+		"/",
+		"/greet",
+		"/greet/{operationId}",
+		"/greet/{operationId}/result",
+		"/greet/{operationId}/cancel",
+		"/sync%2Fonly",
+		"/sync%2Fonly/{operationId}",
+		"/sync%2Fonly/{operationId}/result",
+		"/sync%2Fonly/{operationId}/cancel",
+	}, paths)
+
+	require.JSONEq(t, `{
+		"operationId": "startOperation:greet",
+		"summary": "Start the greet operation.",
+		"description": "Greets.",
+		"tags": ["greet"],
+		"parameters": [
+			{"$ref": "#/components/parameters/RequestID"},
+			{"$ref": "#/components/parameters/Callback"}
+		],
+		"requestBody": {
+			"content": {
+				"application/json": {
+					"schema": {"type": "object", "properties": {"name": {"type": "string"}}, "required": ["name"]}
+				}
+			}
+		},
+		"responses": {
+			"200": {
+				"description": "Operation completed successfully.",
+				"content": {
+					"application/json": {
+						"schema": {"type": "object", "properties": {"greeting": {"type": "string"}}, "required": ["greeting"]}
+					}
+				}
+			},
+			"201": {
+				"description": "Asynchronous operation started.",
+				"content": {"application/json": {"schema": {"$ref": "#/components/schemas/OperationInfo"}}}
+			},
+			"424": {
+				"description": "Operation completed as failed or canceled.",
+				"headers": {"Nexus-Operation-State": {"$ref": "#/components/headers/OperationState"}},
+				"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Failure"}}}
+			},
+			"default": {
+				"description": "Request failed.",
+				"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Failure"}}}
+			}
+		}
+	}`, string(document.Paths["/greet"]["post"]))
+
+	var syncStart struct {
+		RequestBody struct {
+			Content map[string]json.RawMessage `json:"content"`
+		} `json:"requestBody"`
+		Responses map[string]json.RawMessage `json:"responses"`
+	}
+	require.NoError(t, json.Unmarshal(document.Paths["/sync%2Fonly"]["post"], &syncStart))
+	require.JSONEq(t, `{"schema": {"type": "string"}}`, string(syncStart.RequestBody.Content["*/*"]))
+	require.NotContains(t, syncStart.Responses, "201")
+
+	var getResult struct {
+		Parameters []map[string]string        `json:"parameters"`
+		Responses  map[string]json.RawMessage `json:"responses"`
+	}
+	require.NoError(t, json.Unmarshal(document.Paths["/greet/{operationId}/result"]["get"], &getResult))
+	require.Equal(t, "#/components/parameters/Wait", getResult.Parameters[0]["$ref"])
+	require.Contains(t, getResult.Responses, "408")
+	require.Contains(t, getResult.Responses, "412")
+	require.Contains(t, getResult.Responses, "424")
+
+	require.Equal(t, "Nexus-Request-Id", document.Components.Parameters["RequestID"].Name)
+	require.Equal(t, "header", document.Components.Parameters["RequestID"].In)
+	require.Equal(t, "callback", document.Components.Parameters["Callback"].Name)
+	require.Equal(t, "wait", document.Components.Parameters["Wait"].Name)
+	require.Contains(t, document.Components.Schemas, "Failure")
+	require.Contains(t, document.Components.Schemas, "ServiceDescription")
+}
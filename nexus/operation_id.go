@@ -0,0 +1,160 @@
+package nexus
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Version of the operation ID format, prepended to encoded IDs.
+const operationIDVersion = byte(1)
+
+// OperationIDPayload is the content of an opaque operation ID encoded with an [OperationIDCodec].
+type OperationIDPayload struct {
+	// Internal identifier of the operation, e.g. a database key.
+	ID string
+	// Tenant that started the operation. Empty if the handler does not resolve tenants.
+	Tenant string
+	// Name of the operation.
+	Operation string
+	// Time the ID was encoded at. Defaults to the current time when encoding.
+	CreatedAt time.Time
+}
+
+// OperationIDCodecOptions are options for [NewOperationIDCodec].
+type OperationIDCodecOptions struct {
+	// AES key of 16, 24, or 32 bytes for encrypting IDs with AES-GCM. Required.
+	Key []byte
+	// Additional keys accepted when decoding IDs, e.g. keys that are being rotated out. Optional.
+	DecryptionKeys [][]byte
+	// Maximum age of IDs. Older IDs are rejected when decoding. Optional, IDs never expire if zero.
+	MaxAge time.Duration
+}
+
+// An OperationIDCodec encrypts and authenticates internal operation identifiers into opaque operation IDs that leak
+// nothing about them and cannot be forged or tampered with.
+//
+// The tenant and operation name are bound to the ID, an ID issued for one operation or tenant fails to decode for any
+// other. Set [HandlerOptions.OperationIDCodec] to encode and decode IDs automatically.
+type OperationIDCodec struct {
+	aeads  []cipher.AEAD
+	maxAge time.Duration
+	now    func() time.Time
+}
+
+var errInvalidOperationID = errors.New("invalid operation ID")
+
+var errExpiredOperationID = errors.New("expired operation ID")
+
+// NewOperationIDCodec constructs an [OperationIDCodec] from the given options.
+func NewOperationIDCodec(options OperationIDCodecOptions) (*OperationIDCodec, error) {
+	codec := &OperationIDCodec{maxAge: options.MaxAge, now: time.Now}
+	for _, key := range append([][]byte{options.Key}, options.DecryptionKeys...) {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid operation ID key: %w", err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		codec.aeads = append(codec.aeads, aead)
+	}
+	return codec, nil
+}
+
+// operationIDAdditionalData binds the tenant and operation name to an ID.
+func operationIDAdditionalData(operation, tenant string) []byte {
+	data := []byte{operationIDVersion}
+	data = binary.AppendUvarint(data, uint64(len(operation)))
+	data = append(data, operation...)
+	return append(data, tenant...)
+}
+
+// Encode encrypts a payload into an opaque operation ID.
+func (c *OperationIDCodec) Encode(payload OperationIDPayload) (string, error) {
+	createdAt := payload.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = c.now()
+	}
+	aead := c.aeads[0]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	plaintext := binary.BigEndian.AppendUint64(nil, uint64(createdAt.UnixMilli()))
+	plaintext = append(plaintext, payload.ID...)
+	b := append([]byte{operationIDVersion}, nonce...)
+	b = aead.Seal(b, nonce, plaintext, operationIDAdditionalData(payload.Operation, payload.Tenant))
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Decode decrypts and verifies an opaque operation ID issued for the given operation and tenant.
+// Fails if the ID was not encoded with any of the codec's keys, was modified, was issued for another operation or
+// tenant, or has expired.
+func (c *OperationIDCodec) Decode(id, operation, tenant string) (*OperationIDPayload, error) {
+	b, err := base64.RawURLEncoding.DecodeString(id)
+	if err != nil || len(b) == 0 || b[0] != operationIDVersion {
+		return nil, errInvalidOperationID
+	}
+	additionalData := operationIDAdditionalData(operation, tenant)
+	for _, aead := range c.aeads {
+		if len(b) < 1+aead.NonceSize()+aead.Overhead() {
+			return nil, errInvalidOperationID
+		}
+		nonce, ciphertext := b[1:1+aead.NonceSize()], b[1+aead.NonceSize():]
+		plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+		if err != nil {
+			continue
+		}
+		if len(plaintext) < 8 {
+			return nil, errInvalidOperationID
+		}
+		createdAt := time.UnixMilli(int64(binary.BigEndian.Uint64(plaintext)))
+		if c.maxAge > 0 && c.now().Sub(createdAt) > c.maxAge {
+			return nil, errExpiredOperationID
+		}
+		return &OperationIDPayload{
+			ID:        string(plaintext[8:]),
+			Tenant:    tenant,
+			Operation: operation,
+			CreatedAt: createdAt,
+		}, nil
+	}
+	return nil, errInvalidOperationID
+}
+
+// encodeOperationID encodes the internal ID of an operation started by a request with the handler's OperationIDCodec,
+// if set.
+func (h *httpHandler) encodeOperationID(ctx context.Context, operation, operationID string) (string, error) {
+	if h.options.OperationIDCodec == nil {
+		return operationID, nil
+	}
+	tenant, _ := TenantFromContext(ctx)
+	return h.options.OperationIDCodec.Encode(OperationIDPayload{ID: operationID, Tenant: tenant, Operation: operation})
+}
+
+// decodeOperationID decodes the operation ID of a request with the handler's OperationIDCodec, if set, failing with a
+// 404 error if the ID is invalid.
+func (h *httpHandler) decodeOperationID(request *http.Request, operation, operationID string) (string, error) {
+	if h.options.OperationIDCodec == nil {
+		return operationID, nil
+	}
+	tenant, _ := TenantFromContext(request.Context())
+	payload, err := h.options.OperationIDCodec.Decode(operationID, operation, tenant)
+	if err != nil {
+		return "", &HandlerError{
+			StatusCode:    http.StatusNotFound,
+			Failure:       &Failure{Message: fmt.Sprintf("operation not found: %q", operationID)},
+			RetryBehavior: RetryBehaviorNonRetryable,
+		}
+	}
+	return payload.ID, nil
+}
@@ -0,0 +1,159 @@
+package nexus
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestOperationIDCodec(t *testing.T, options OperationIDCodecOptions) *OperationIDCodec {
+	if options.Key == nil {
+		options.Key = bytes.Repeat([]byte{1}, 32)
+	}
+	codec, err := NewOperationIDCodec(options)
+	require.NoError(t, err)
+	return codec
+}
+
+func TestOperationIDCodec(t *testing.T) {
+	codec := newTestOperationIDCodec(t, OperationIDCodecOptions{})
+	id, err := codec.Encode(OperationIDPayload{ID: "row-42", Tenant: "acme", Operation: "foo"})
+	require.NoError(t, err)
+	require.NotContains(t, id, "row-42")
+
+	payload, err := codec.Decode(id, "foo", "acme")
+	require.NoError(t, err)
+	require.Equal(t, "row-42", payload.ID)
+	require.Equal(t, "acme", payload.Tenant)
+	require.Equal(t, "foo", payload.Operation)
+	require.WithinDuration(t, time.Now(), payload.CreatedAt, time.Second)
+
+	// Encoding is randomized.
+	other, err := codec.Encode(OperationIDPayload{ID: "row-42", Tenant: "acme", Operation: "foo"})
+	require.NoError(t, err)
+	require.NotEqual(t, id, other)
+
+	_, err = codec.Decode(id, "bar", "acme")
+	require.ErrorIs(t, err, errInvalidOperationID)
+	_, err = codec.Decode(id, "foo", "globex")
+	require.ErrorIs(t, err, errInvalidOperationID)
+	_, err = codec.Decode(id, "foo", "")
+	require.ErrorIs(t, err, errInvalidOperationID)
+	_, err = codec.Decode("not an id", "foo", "acme")
+	require.ErrorIs(t, err, errInvalidOperationID)
+
+	b, err := base64.RawURLEncoding.DecodeString(id)
+	require.NoError(t, err)
+	b[len(b)-1] ^= 1
+	_, err = codec.Decode(base64.RawURLEncoding.EncodeToString(b), "foo", "acme")
+	require.ErrorIs(t, err, errInvalidOperationID)
+
+	_, err = newTestOperationIDCodec(t, OperationIDCodecOptions{Key: bytes.Repeat([]byte{2}, 32)}).Decode(id, "foo", "acme")
+	require.ErrorIs(t, err, errInvalidOperationID)
+}
+
+func TestOperationIDCodec_KeyRotation(t *testing.T) {
+	oldKey, newKey := bytes.Repeat([]byte{1}, 16), bytes.Repeat([]byte{2}, 16)
+	id, err := newTestOperationIDCodec(t, OperationIDCodecOptions{Key: oldKey}).Encode(OperationIDPayload{ID: "id", Operation: "foo"})
+	require.NoError(t, err)
+	payload, err := newTestOperationIDCodec(t, OperationIDCodecOptions{Key: newKey, DecryptionKeys: [][]byte{oldKey}}).Decode(id, "foo", "")
+	require.NoError(t, err)
+	require.Equal(t, "id", payload.ID)
+
+	_, err = NewOperationIDCodec(OperationIDCodecOptions{Key: []byte("short")})
+	require.Error(t, err)
+}
+
+func TestOperationIDCodec_MaxAge(t *testing.T) {
+	codec := newTestOperationIDCodec(t, OperationIDCodecOptions{MaxAge: time.Hour})
+	id, err := codec.Encode(OperationIDPayload{ID: "id", Operation: "foo", CreatedAt: time.Now().Add(-time.Minute)})
+	require.NoError(t, err)
+	_, err = codec.Decode(id, "foo", "")
+	require.NoError(t, err)
+
+	id, err = codec.Encode(OperationIDPayload{ID: "id", Operation: "foo", CreatedAt: time.Now().Add(-2 * time.Hour)})
+	require.NoError(t, err)
+	_, err = codec.Decode(id, "foo", "")
+	require.ErrorIs(t, err, errExpiredOperationID)
+}
+
+type internalIDHandler struct {
+	UnimplementedHandler
+	requests []string
+}
+
+func (h *internalIDHandler) StartOperation(ctx context.Context, request *StartOperationRequest) (OperationResponse, error) {
+	return &OperationResponseAsync{OperationID: "row-42"}, nil
+}
+
+func (h *internalIDHandler) GetOperationResult(ctx context.Context, request *GetOperationResultRequest) (*OperationResponseSync, error) {
+	h.requests = append(h.requests, request.OperationID)
+	return &OperationResponseSync{Body: bytes.NewReader([]byte("result"))}, nil
+}
+
+func (h *internalIDHandler) GetOperationInfo(ctx context.Context, request *GetOperationInfoRequest) (*OperationInfo, error) {
+	h.requests = append(h.requests, request.OperationID)
+	return &OperationInfo{ID: request.OperationID, State: OperationStateRunning}, nil
+}
+
+func (h *internalIDHandler) CancelOperation(ctx context.Context, request *CancelOperationRequest) error {
+	h.requests = append(h.requests, request.OperationID)
+	return nil
+}
+
+func TestOperationIDCodec_Handler(t *testing.T) {
+	handler := &internalIDHandler{}
+	ctx, client, teardown := setupCustom(t, HandlerOptions{
+		Handler:          handler,
+		OperationIDCodec: newTestOperationIDCodec(t, OperationIDCodecOptions{}),
+		Tenancy:          &TenantOptions{Source: TenantSourceHeader},
+	}, ClientOptions{})
+	defer teardown()
+
+	acme := http.Header{"Nexus-Tenant": []string{"acme"}}
+	result, err := client.StartOperation(ctx, StartOperationOptions{Operation: "foo", Header: acme})
+	require.NoError(t, err)
+	handle := result.Pending
+	require.NotNil(t, handle)
+	require.NotEqual(t, "row-42", handle.ID)
+
+	response, err := handle.GetResult(ctx, GetOperationResultOptions{Header: acme})
+	require.NoError(t, err)
+	response.Body.Close()
+	info, err := handle.GetInfo(ctx, GetOperationInfoOptions{Header: acme})
+	require.NoError(t, err)
+	require.Equal(t, handle.ID, info.ID)
+	require.NoError(t, handle.Cancel(ctx, CancelOperationOptions{Header: acme}))
+	require.Equal(t, []string{"row-42", "row-42", "row-42"}, handler.requests)
+
+	requireNotFound := func(err error) {
+		t.Helper()
+		var unexpectedResponseError *UnexpectedResponseError
+		require.ErrorAs(t, err, &unexpectedResponseError)
+		require.Equal(t, http.StatusNotFound, unexpectedResponseError.Response.StatusCode)
+	}
+	// Another tenant.
+	requireNotFound(handle.Cancel(ctx, CancelOperationOptions{Header: http.Header{"Nexus-Tenant": []string{"globex"}}}))
+	// Another operation.
+	other, err := client.NewHandle("bar", handle.ID)
+	require.NoError(t, err)
+	_, err = other.GetInfo(ctx, GetOperationInfoOptions{Header: acme})
+	requireNotFound(err)
+	// A tampered ID.
+	tampered, err := client.NewHandle("foo", strings.ToUpper(handle.ID))
+	require.NoError(t, err)
+	_, err = tampered.GetResult(ctx, GetOperationResultOptions{Header: acme})
+	requireNotFound(err)
+	// An internal ID.
+	internal, err := client.NewHandle("foo", "row-42")
+	require.NoError(t, err)
+	requireNotFound(internal.Cancel(ctx, CancelOperationOptions{Header: acme}))
+
+	require.Len(t, handler.requests, 3)
+}
@@ -0,0 +1,55 @@
+package nexus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// A PanicHandler is invoked with the value recovered from a panic in a [Handler] or [CompletionHandler] method and the
+// stack trace of the panicking goroutine, e.g. to report the panic to an error tracker.
+//
+// The panic has already been logged when the PanicHandler is invoked and the request is failed with a 500 status after
+// it returns.
+type PanicHandler func(ctx context.Context, recovered any, stack []byte)
+
+// recoverPanics wraps a handler function, recovering panics and responding with a 500 [Failure]. If the response has
+// already been partially written, the connection is aborted instead.
+func (h *baseHTTPHandler) recoverPanics(handler http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		recorder := &statusRecordingWriter{ResponseWriter: writer}
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				// Sentinel used to deliberately abort a response, let net/http handle it.
+				panic(recovered)
+			}
+			stack := debug.Stack()
+			h.logger.Error("panic in handler", "panic", fmt.Sprint(recovered), "stack", string(stack))
+			if h.panicHandler != nil {
+				h.invokePanicHandler(request.Context(), recovered, stack)
+			}
+			if recorder.statusCode != 0 {
+				panic(http.ErrAbortHandler)
+			}
+			h.writeFailure(recorder, &HandlerError{
+				StatusCode: http.StatusInternalServerError,
+				Failure:    &Failure{Message: "internal server error"},
+			})
+		}()
+		handler(recorder, request)
+	}
+}
+
+func (h *baseHTTPHandler) invokePanicHandler(ctx context.Context, recovered any, stack []byte) {
+	defer func() {
+		if r := recover(); r != nil {
+			h.logger.Error("panic in panic handler", "panic", fmt.Sprint(r))
+		}
+	}()
+	h.panicHandler(ctx, recovered, stack)
+}
@@ -0,0 +1,105 @@
+package nexus
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type panickingHandler struct {
+	UnimplementedHandler
+}
+
+func (h *panickingHandler) StartOperation(ctx context.Context, request *StartOperationRequest) (OperationResponse, error) {
+	panic("boom")
+}
+
+type panickingCompletionHandler struct{}
+
+func (h *panickingCompletionHandler) CompleteOperation(ctx context.Context, completion *CompletionRequest) error {
+	panic("boom")
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestPanicRecovery(t *testing.T) {
+	var logs syncBuffer
+	var recovered []any
+	var stacks [][]byte
+	ctx, client, teardown := setupCustom(t, HandlerOptions{
+		Handler: &panickingHandler{},
+		Logger:  slog.New(slog.NewTextHandler(&logs, nil)),
+		PanicHandler: func(ctx context.Context, r any, stack []byte) {
+			recovered = append(recovered, r)
+			stacks = append(stacks, stack)
+		},
+	}, ClientOptions{})
+	defer teardown()
+
+	_, err := client.StartOperation(ctx, StartOperationOptions{Operation: "foo"})
+	var unexpectedResponseError *UnexpectedResponseError
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusInternalServerError, unexpectedResponseError.Response.StatusCode)
+	require.Equal(t, "internal server error", unexpectedResponseError.Failure.Message)
+	require.Equal(t, []any{"boom"}, recovered)
+	require.Contains(t, string(stacks[0]), "panickingHandler")
+	require.Contains(t, logs.String(), "panic in handler")
+	require.Contains(t, logs.String(), "panic=boom")
+}
+
+func TestPanicRecovery_PanicHandlerPanics(t *testing.T) {
+	ctx, client, teardown := setupCustom(t, HandlerOptions{
+		Handler: &panickingHandler{},
+		Logger:  slog.New(slog.NewTextHandler(&syncBuffer{}, nil)),
+		PanicHandler: func(ctx context.Context, r any, stack []byte) {
+			panic("boom again")
+		},
+	}, ClientOptions{})
+	defer teardown()
+
+	_, err := client.StartOperation(ctx, StartOperationOptions{Operation: "foo"})
+	var unexpectedResponseError *UnexpectedResponseError
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusInternalServerError, unexpectedResponseError.Response.StatusCode)
+}
+
+func TestPanicRecovery_Completion(t *testing.T) {
+	var recovered any
+	handler := NewCompletionHTTPHandler(CompletionHandlerOptions{
+		Handler: &panickingCompletionHandler{},
+		Logger:  slog.New(slog.NewTextHandler(&syncBuffer{}, nil)),
+		PanicHandler: func(ctx context.Context, r any, stack []byte) {
+			recovered = r
+		},
+	})
+	request, err := NewCompletionHTTPRequest(context.Background(), "http://localhost/callback", &OperationCompletionSuccessful{
+		Body: bytes.NewReader([]byte("success")),
+	})
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
+	require.Equal(t, "boom", recovered)
+}
@@ -0,0 +1,207 @@
+package nexus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// OperationDefinition describes an operation registered in an [OperationRegistry].
+type OperationDefinition struct {
+	// Name of the operation.
+	Name string
+	// Human readable description of the operation. Optional.
+	Description string
+	// Whether the operation may complete synchronously, responding inline to start requests.
+	Sync bool
+	// Whether the operation may complete asynchronously.
+	Async bool
+	// Content type of the operation's input, e.g. application/json. Optional.
+	InputContentType string
+	// Content type of the operation's output, e.g. application/json. Optional.
+	OutputContentType string
+	// Go type of the operation's input. Optional.
+	InputType reflect.Type
+	// Go type of the operation's output. Optional.
+	OutputType reflect.Type
+	// JSON schema of the operation's input. Optional.
+	// When set, handlers with the registry set validate the body of start requests against the schema before calling
+	// [Handler.StartOperation], rejecting non conforming input with a 400 status. See [ValidateJSONSchema] for the
+	// supported keywords and [FailureTypeInputValidation] for the failure format.
+	// Use [JSONSchemaFor] to generate a schema from the operation's Go input type.
+	InputSchema json.RawMessage
+	// JSON schema of the operation's output. Optional.
+	OutputSchema json.RawMessage
+	// Tenants the operation is available to. Optional, available to all tenants if empty.
+	// Handlers with [HandlerOptions.Tenancy] set reject requests for operations not available to the request's tenant
+	// with a 404 status and omit them from the [ServiceDescription] served to the tenant.
+	Tenants []string
+}
+
+// NewOperationDefinition is shorthand for defining an operation that may complete both synchronously and
+// asynchronously, and accepts input of type I and produces output of type O, both encoded as JSON.
+func NewOperationDefinition[I, O any](name string) OperationDefinition {
+	return OperationDefinition{
+		Name:              name,
+		Sync:              true,
+		Async:             true,
+		InputContentType:  contentTypeJSON,
+		OutputContentType: contentTypeJSON,
+		InputType:         reflect.TypeOf((*I)(nil)).Elem(),
+		OutputType:        reflect.TypeOf((*O)(nil)).Elem(),
+	}
+}
+
+// OperationRegistryOptions are options for [NewOperationRegistry].
+type OperationRegistryOptions struct {
+	// Name of the service. Optional.
+	Name string
+	// Version of the service. Optional.
+	Version string
+}
+
+// An OperationRegistry holds the definitions of the operations a service exposes.
+//
+// Set [HandlerOptions.Registry] to expose a [ServiceDescription] on the service root and to reject requests for
+// unregistered operations with a 404 status before they reach the [Handler].
+type OperationRegistry struct {
+	options    OperationRegistryOptions
+	mu         sync.RWMutex
+	operations map[string]OperationDefinition
+}
+
+var errDuplicateOperation = errors.New("duplicate operation")
+
+// NewOperationRegistry constructs a new, empty [OperationRegistry].
+func NewOperationRegistry(options OperationRegistryOptions) *OperationRegistry {
+	return &OperationRegistry{
+		options:    options,
+		operations: make(map[string]OperationDefinition),
+	}
+}
+
+// Register adds operation definitions to the registry.
+// Fails if any definition has an empty name, its name is already registered, or its input schema is not valid JSON, in
+// which case no definitions are added.
+func (r *OperationRegistry) Register(definitions ...OperationDefinition) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make(map[string]bool, len(definitions))
+	for _, definition := range definitions {
+		if definition.Name == "" {
+			return errEmptyOperationName
+		}
+		if _, ok := r.operations[definition.Name]; ok || names[definition.Name] {
+			return fmt.Errorf("%w: %q", errDuplicateOperation, definition.Name)
+		}
+		if len(definition.InputSchema) > 0 && !json.Valid(definition.InputSchema) {
+			return fmt.Errorf("%w: input schema of operation %q is not valid JSON", errInvalidSchema, definition.Name)
+		}
+		names[definition.Name] = true
+	}
+	for _, definition := range definitions {
+		r.operations[definition.Name] = definition
+	}
+	return nil
+}
+
+// Lookup gets an operation definition by name.
+func (r *OperationRegistry) Lookup(name string) (OperationDefinition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	definition, ok := r.operations[name]
+	return definition, ok
+}
+
+// Operations returns all registered operation definitions sorted by name.
+func (r *OperationRegistry) Operations() []OperationDefinition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	definitions := make([]OperationDefinition, 0, len(r.operations))
+	for _, definition := range r.operations {
+		definitions = append(definitions, definition)
+	}
+	sort.Slice(definitions, func(i, j int) bool { return definitions[i].Name < definitions[j].Name })
+	return definitions
+}
+
+// Describe returns a machine readable description of the service.
+func (r *OperationRegistry) Describe() *ServiceDescription {
+	return r.describe(r.Operations())
+}
+
+func (r *OperationRegistry) describe(definitions []OperationDefinition) *ServiceDescription {
+	description := &ServiceDescription{
+		Name:       r.options.Name,
+		Version:    r.options.Version,
+		Operations: make([]OperationDescription, len(definitions)),
+	}
+	for i, definition := range definitions {
+		description.Operations[i] = OperationDescription{
+			Name:              definition.Name,
+			Description:       definition.Description,
+			Sync:              definition.Sync,
+			Async:             definition.Async,
+			InputContentType:  definition.InputContentType,
+			OutputContentType: definition.OutputContentType,
+			InputSchema:       definition.InputSchema,
+			OutputSchema:      definition.OutputSchema,
+		}
+	}
+	return description
+}
+
+// ServiceDescription is a machine readable description of a service and the operations it exposes.
+// It is served on the service root by handlers that have an [OperationRegistry] set and fetched with
+// [Client.Describe].
+type ServiceDescription struct {
+	// Name of the service. May be empty.
+	Name string `json:"name,omitempty"`
+	// Version of the service. May be empty.
+	Version string `json:"version,omitempty"`
+	// Operations exposed by the service, sorted by name.
+	Operations []OperationDescription `json:"operations"`
+}
+
+// Operation gets an operation's description by name.
+func (d *ServiceDescription) Operation(name string) (OperationDescription, bool) {
+	for _, operation := range d.Operations {
+		if operation.Name == name {
+			return operation, true
+		}
+	}
+	return OperationDescription{}, false
+}
+
+// OperationDescription describes a single operation in a [ServiceDescription].
+type OperationDescription struct {
+	// Name of the operation.
+	Name string `json:"name"`
+	// Human readable description of the operation. May be empty.
+	Description string `json:"description,omitempty"`
+	// Whether the operation may complete synchronously.
+	Sync bool `json:"sync"`
+	// Whether the operation may complete asynchronously.
+	Async bool `json:"async"`
+	// Content type of the operation's input. May be empty.
+	InputContentType string `json:"inputContentType,omitempty"`
+	// Content type of the operation's output. May be empty.
+	OutputContentType string `json:"outputContentType,omitempty"`
+	// JSON schema of the operation's input. May be empty.
+	InputSchema json.RawMessage `json:"inputSchema,omitempty"`
+	// JSON schema of the operation's output. May be empty.
+	OutputSchema json.RawMessage `json:"outputSchema,omitempty"`
+}
+
+func newUnknownOperationError(operation string) *HandlerError {
+	return &HandlerError{
+		StatusCode: http.StatusNotFound,
+		Failure: &Failure{
+			Message: fmt.Sprintf("unknown operation: %q", operation),
+		},
+	}
+}
@@ -0,0 +1,94 @@
+package nexus
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type describeInput struct {
+	Name string `json:"name"`
+}
+
+type describeOutput struct {
+	Greeting string `json:"greeting"`
+}
+
+func TestOperationRegistry_Register(t *testing.T) {
+	registry := NewOperationRegistry(OperationRegistryOptions{})
+	require.ErrorIs(t, registry.Register(OperationDefinition{}), errEmptyOperationName)
+	require.NoError(t, registry.Register(OperationDefinition{Name: "a"}))
+	require.ErrorIs(t, registry.Register(OperationDefinition{Name: "b"}, OperationDefinition{Name: "a"}), errDuplicateOperation)
+	require.ErrorIs(t, registry.Register(OperationDefinition{Name: "c"}, OperationDefinition{Name: "c"}), errDuplicateOperation)
+	// Failed registrations are not partially applied.
+	_, ok := registry.Lookup("b")
+	require.False(t, ok)
+
+	definition := NewOperationDefinition[describeInput, *describeOutput]("greet")
+	require.Equal(t, reflect.TypeOf(describeInput{}), definition.InputType)
+	require.Equal(t, reflect.TypeOf(&describeOutput{}), definition.OutputType)
+	require.NoError(t, registry.Register(definition))
+	require.Equal(t, []string{"a", "greet"}, []string{registry.Operations()[0].Name, registry.Operations()[1].Name})
+}
+
+func TestDescribe(t *testing.T) {
+	registry := NewOperationRegistry(OperationRegistryOptions{Name: "greeter", Version: "v1.2.3"})
+	greet := NewOperationDefinition[describeInput, describeOutput]("greet")
+	greet.Async = false
+	greet.InputSchema = json.RawMessage(`{"type":"object"}`)
+	require.NoError(t, registry.Register(greet, OperationDefinition{Name: "escape/me", Async: true}))
+
+	ctx, client, teardown := setupCustom(t, HandlerOptions{
+		Handler:  &asyncWithInfoHandler{},
+		Registry: registry,
+	}, ClientOptions{})
+	defer teardown()
+
+	description, err := client.Describe(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &ServiceDescription{
+		Name:    "greeter",
+		Version: "v1.2.3",
+		Operations: []OperationDescription{
+			{Name: "escape/me", Async: true},
+			{
+				Name:              "greet",
+				Sync:              true,
+				InputContentType:  contentTypeJSON,
+				OutputContentType: contentTypeJSON,
+				InputSchema:       json.RawMessage(`{"type":"object"}`),
+			},
+		},
+	}, description)
+	operation, ok := description.Operation("greet")
+	require.True(t, ok)
+	require.True(t, operation.Sync)
+
+	// Registered operations reach the handler.
+	handle, err := client.NewHandle("escape/me", "needs /URL/ escaping")
+	require.NoError(t, err)
+	_, err = handle.GetInfo(ctx, GetOperationInfoOptions{})
+	require.NoError(t, err)
+
+	// Unregistered operations are rejected.
+	handle, err = client.NewHandle("unknown", "needs /URL/ escaping")
+	require.NoError(t, err)
+	_, err = handle.GetInfo(ctx, GetOperationInfoOptions{})
+	var unexpectedResponseError *UnexpectedResponseError
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusNotFound, unexpectedResponseError.Response.StatusCode)
+	require.Equal(t, `unknown operation: "unknown"`, unexpectedResponseError.Failure.Message)
+}
+
+func TestDescribe_NoRegistry(t *testing.T) {
+	ctx, client, teardown := setup(t, &asyncWithInfoHandler{})
+	defer teardown()
+
+	_, err := client.Describe(ctx)
+	var unexpectedResponseError *UnexpectedResponseError
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusNotFound, unexpectedResponseError.Response.StatusCode)
+}
@@ -0,0 +1,125 @@
+package nexus
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+)
+
+// JSONSchemaForType generates a JSON schema describing values of the given Go type as encoded by [json.Marshal].
+//
+// Struct fields are named and omitted according to their json tags. Non pointer fields without the omitempty option are
+// marked as required. Types implementing [json.Marshaler] and recursive references are described with an empty schema,
+// which accepts any value, except for [time.Time] which is described as a date-time string.
+func JSONSchemaForType(t reflect.Type) json.RawMessage {
+	// Marshaling a tree of maps, slices, and strings never fails.
+	b, _ := json.Marshal(jsonSchemaForType(t, map[reflect.Type]bool{}))
+	return b
+}
+
+// JSONSchemaFor is shorthand for calling [JSONSchemaForType] with the type T.
+func JSONSchemaFor[T any]() json.RawMessage {
+	return JSONSchemaForType(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+func jsonSchemaForType(t reflect.Type, visiting map[reflect.Type]bool) map[string]any {
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	if t == rawMessageType {
+		return map[string]any{}
+	}
+	if t.Kind() == reflect.Pointer {
+		return jsonSchemaForType(t.Elem(), visiting)
+	}
+	if t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) {
+		return map[string]any{}
+	}
+	if t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return map[string]any{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}
+		}
+		schema := map[string]any{"type": "array", "items": jsonSchemaForType(t.Elem(), visiting)}
+		if t.Kind() == reflect.Array {
+			schema["minItems"] = t.Len()
+			schema["maxItems"] = t.Len()
+		}
+		return schema
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": jsonSchemaForType(t.Elem(), visiting)}
+	case reflect.Struct:
+		if visiting[t] {
+			return map[string]any{}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		properties := map[string]any{}
+		required := []string{}
+		addStructFields(t, visiting, properties, &required)
+		schema := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	default:
+		// Interfaces and other types that may hold any value.
+		return map[string]any{}
+	}
+}
+
+func addStructFields(t reflect.Type, visiting map[reflect.Type]bool, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fieldType := field.Type
+		if field.Anonymous && name == "" {
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				// Fields of embedded structs are promoted.
+				addStructFields(fieldType, visiting, properties, required)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = jsonSchemaForType(field.Type, visiting)
+		if !strings.Contains(","+opts+",", ",omitempty,") && field.Type.Kind() != reflect.Pointer {
+			*required = append(*required, name)
+		}
+	}
+}
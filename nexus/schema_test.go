@@ -0,0 +1,67 @@
+package nexus
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type schemaEmbedded struct {
+	Promoted string `json:"promoted"`
+}
+
+type schemaNode struct {
+	Value    int           `json:"value"`
+	Children []*schemaNode `json:"children,omitempty"`
+}
+
+type schemaExample struct {
+	schemaEmbedded
+	Name      string `json:"name"`
+	Optional  string `json:"optional,omitempty"`
+	Pointer   *int   `json:"pointer"`
+	Untagged  float64
+	Ignored   string            `json:"-"`
+	Bytes     []byte            `json:"bytes"`
+	Labels    map[string]string `json:"labels"`
+	Fixed     [2]bool           `json:"fixed"`
+	Time      time.Time         `json:"time"`
+	Raw       json.RawMessage   `json:"raw"`
+	Any       any               `json:"any"`
+	Unsigned  uint8             `json:"unsigned"`
+	Tree      schemaNode        `json:"tree"`
+	unexposed string
+}
+
+func TestJSONSchemaFor(t *testing.T) {
+	require.JSONEq(t, `{
+		"type": "object",
+		"properties": {
+			"promoted": {"type": "string"},
+			"name": {"type": "string"},
+			"optional": {"type": "string"},
+			"pointer": {"type": "integer"},
+			"Untagged": {"type": "number"},
+			"bytes": {"type": "string", "contentEncoding": "base64"},
+			"labels": {"type": "object", "additionalProperties": {"type": "string"}},
+			"fixed": {"type": "array", "items": {"type": "boolean"}, "minItems": 2, "maxItems": 2},
+			"time": {"type": "string", "format": "date-time"},
+			"raw": {},
+			"any": {},
+			"unsigned": {"type": "integer", "minimum": 0},
+			"tree": {
+				"type": "object",
+				"properties": {
+					"value": {"type": "integer"},
+					"children": {"type": "array", "items": {}}
+				},
+				"required": ["value"]
+			}
+		},
+		"required": ["promoted", "name", "Untagged", "bytes", "labels", "fixed", "time", "raw", "any", "unsigned", "tree"]
+	}`, string(JSONSchemaFor[schemaExample]()))
+
+	require.JSONEq(t, `{"type": "string"}`, string(JSONSchemaFor[*string]()))
+}
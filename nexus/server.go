@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -24,9 +25,15 @@ type StartOperationRequest struct {
 	RequestID string
 	// Callback URL to call upon completion if the started operation is async.
 	CallbackURL string
+	// Media types the caller accepts a synchronous result in, parsed from the request's Accept header and ordered by
+	// decreasing preference. Empty if the caller did not specify any, in which case any media type is acceptable.
+	// Use [StartOperationRequest.NegotiateResponse] to encode a result in the best matching media type.
+	Accept []MediaRange
 	// The original HTTP request.
 	// Read the URL, Header, and Body of the request to process the operation input.
 	HTTPRequest *http.Request
+
+	codecs []Codec
 }
 
 // GetOperationResultRequest is input for Handler.GetOperationResult.
@@ -34,13 +41,21 @@ type GetOperationResultRequest struct {
 	// Operation name.
 	Operation string
 	// Operation ID as originally generated by a Handler.
-	// It is the handler's responsibility to validate this ID and authorize access to the underlying resource.
+	// It is the handler's responsibility to validate this ID and authorize access to the underlying resource, unless
+	// [HandlerOptions.OperationIDCodec] is set, in which case this is the verified internal ID decoded from the opaque
+	// ID.
 	OperationID string
 	// If non-zero, reflects the duration the caller has indicated that it wants to wait for operation completion,
 	// turning the request into a long poll.
 	Wait time.Duration
+	// Media types the caller accepts the result in, parsed from the request's Accept header and ordered by decreasing
+	// preference. Empty if the caller did not specify any, in which case any media type is acceptable.
+	// Use [GetOperationResultRequest.NegotiateResponse] to encode a result in the best matching media type.
+	Accept []MediaRange
 	// The original HTTP request.
 	HTTPRequest *http.Request
+
+	codecs []Codec
 }
 
 // GetOperationInfoRequest is input for Handler.GetOperationInfo.
@@ -48,7 +63,9 @@ type GetOperationInfoRequest struct {
 	// Operation name.
 	Operation string
 	// Operation ID as originally generated by a Handler.
-	// It is the handler's responsibility to validate this ID and authorize access to the underlying resource.
+	// It is the handler's responsibility to validate this ID and authorize access to the underlying resource, unless
+	// [HandlerOptions.OperationIDCodec] is set, in which case this is the verified internal ID decoded from the opaque
+	// ID.
 	OperationID string
 	// The original HTTP request.
 	HTTPRequest *http.Request
@@ -59,7 +76,9 @@ type CancelOperationRequest struct {
 	// Operation name.
 	Operation string
 	// Operation ID as originally generated by a Handler.
-	// It is the handler's responsibility to validate this ID and authorize access to the underlying resource.
+	// It is the handler's responsibility to validate this ID and authorize access to the underlying resource, unless
+	// [HandlerOptions.OperationIDCodec] is set, in which case this is the verified internal ID decoded from the opaque
+	// ID.
 	OperationID string
 	// The original HTTP request.
 	HTTPRequest *http.Request
@@ -68,7 +87,7 @@ type CancelOperationRequest struct {
 // An OperationResponse is the return type from the handler StartOperation and GetResult methods. It has two
 // implementations: [OperationResponseSync] and [OperationResponseAsync].
 type OperationResponse interface {
-	applyToHTTPResponse(http.ResponseWriter, *httpHandler)
+	applyToHTTPResponse(http.ResponseWriter, *http.Request, *httpHandler)
 }
 
 // Indicates that an operation completed successfully.
@@ -95,7 +114,7 @@ func NewOperationResponseSync(v any) (*OperationResponseSync, error) {
 	}, nil
 }
 
-func (r *OperationResponseSync) applyToHTTPResponse(writer http.ResponseWriter, handler *httpHandler) {
+func (r *OperationResponseSync) applyToHTTPResponse(writer http.ResponseWriter, request *http.Request, handler *httpHandler) {
 	header := writer.Header()
 	for k, v := range r.Header {
 		header[k] = v
@@ -103,7 +122,7 @@ func (r *OperationResponseSync) applyToHTTPResponse(writer http.ResponseWriter,
 	if closer, ok := r.Body.(io.Closer); ok {
 		defer closer.Close()
 	}
-	if _, err := io.Copy(writer, r.Body); err != nil {
+	if err := handler.writeCompressed(writer, request, handler.options.CompressionThreshold, r.Body); err != nil {
 		handler.logger.Error("failed to write response body", "error", err)
 	}
 }
@@ -113,7 +132,7 @@ type OperationResponseAsync struct {
 	OperationID string
 }
 
-func (r *OperationResponseAsync) applyToHTTPResponse(writer http.ResponseWriter, handler *httpHandler) {
+func (r *OperationResponseAsync) applyToHTTPResponse(writer http.ResponseWriter, request *http.Request, handler *httpHandler) {
 	info := OperationInfo{
 		ID:    r.OperationID,
 		State: OperationStateRunning,
@@ -169,6 +188,19 @@ type Handler interface {
 	mustEmbedUnimplementedHandler()
 }
 
+// RetryBehavior hints whether a failed request may be retried.
+type RetryBehavior int
+
+const (
+	// RetryBehaviorUnspecified leaves it to the caller to decide whether to retry, typically based on the response
+	// status code.
+	RetryBehaviorUnspecified RetryBehavior = iota
+	// RetryBehaviorRetryable indicates that the request may succeed if retried.
+	RetryBehaviorRetryable
+	// RetryBehaviorNonRetryable indicates that the request should not be retried.
+	RetryBehaviorNonRetryable
+)
+
 // HandlerError is a special error that can be returned from [Handler] methods for failing an HTTP request with a custom
 // status code and failure message.
 type HandlerError struct {
@@ -176,6 +208,18 @@ type HandlerError struct {
 	StatusCode int
 	// Failure to report back in the response. Optional.
 	Failure *Failure
+	// Hint for callers whether to retry the request, transmitted in the Nexus-Request-Retryable response header and
+	// exposed as [UnexpectedResponseError.RetryBehavior]. Optional.
+	RetryBehavior RetryBehavior
+	// Underlying error. Optional.
+	// If Failure is nil and Cause, or an error it wraps, is of a type registered in the handler's [ErrorRegistry], the
+	// failure reported in the response is converted from that error, allowing clients to restore it.
+	Cause error
+}
+
+// Unwrap returns the underlying cause of the error.
+func (e *HandlerError) Unwrap() error {
+	return e.Cause
 }
 
 // Error implements the error interface.
@@ -195,19 +239,61 @@ func newBadRequestError(format string, args ...any) *HandlerError {
 	}
 }
 
+func newRequestTooLargeError(limit int64) *HandlerError {
+	return &HandlerError{
+		StatusCode: http.StatusRequestEntityTooLarge,
+		Failure: &Failure{
+			Message: fmt.Sprintf("request body exceeds %d bytes", limit),
+		},
+		RetryBehavior: RetryBehaviorNonRetryable,
+	}
+}
+
+// newReadBodyError converts an error reading a request body into a handler error, reporting decompressed bodies that
+// exceed the handler's limit with a 413 status.
+func newReadBodyError(err error, format string, args ...any) *HandlerError {
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		return newRequestTooLargeError(maxBytesError.Limit)
+	}
+	return newBadRequestError(format, args...)
+}
+
 type baseHTTPHandler struct {
-	logger *slog.Logger
+	logger           *slog.Logger
+	metrics          MetricsHandler
+	metricsComponent MetricsComponent
+	tracer           Tracer
+	compressors      []Compressor
+	// Max size in bytes of decompressed request bodies.
+	maxDecompressedBodySize int64
+	panicHandler            PanicHandler
+	errorRegistry           *ErrorRegistry
+}
+
+// failureFromRegisteredError converts the first registered sentinel or error of a registered type in the chain of err
+// into a failure. Unregistered errors it wraps are omitted from the failure's causes. Returns nil if there is no such
+// error.
+func (h *baseHTTPHandler) failureFromRegisteredError(err error) *Failure {
+	registered, ok := h.errorRegistry.findRegistered(err, 0)
+	if !ok {
+		return nil
+	}
+	failure := newRegisteredFailure(registered, h.errorRegistry, 0)
+	return &failure
 }
 
 type httpHandler struct {
 	baseHTTPHandler
 	options HandlerOptions
+	tracker *requestTracker
 }
 
 func (h *baseHTTPHandler) writeFailure(writer http.ResponseWriter, err error) {
 	var failure *Failure
 	var unsuccessfulError *UnsuccessfulOperationError
 	var handlerError *HandlerError
+	var maxBytesError *http.MaxBytesError
 	var operationState OperationState
 	statusCode := http.StatusInternalServerError
 
@@ -225,7 +311,22 @@ func (h *baseHTTPHandler) writeFailure(writer http.ResponseWriter, err error) {
 		}
 	} else if errors.As(err, &handlerError) {
 		failure = handlerError.Failure
+		if failure == nil {
+			failure = h.failureFromRegisteredError(handlerError.Cause)
+		}
 		statusCode = handlerError.StatusCode
+		switch handlerError.RetryBehavior {
+		case RetryBehaviorRetryable:
+			writer.Header().Set(headerRetryable, "true")
+		case RetryBehaviorNonRetryable:
+			writer.Header().Set(headerRetryable, "false")
+		}
+	} else if errors.As(err, &maxBytesError) {
+		// The Handler failed to read a decompressed body that exceeds the limit.
+		h.writeFailure(writer, newRequestTooLargeError(maxBytesError.Limit))
+		return
+	} else if failure = h.failureFromRegisteredError(err); failure != nil {
+		h.logger.Error("handler failed", "error", err)
 	} else {
 		failure = &Failure{
 			Message: "internal server error",
@@ -251,29 +352,137 @@ func (h *baseHTTPHandler) writeFailure(writer http.ResponseWriter, err error) {
 	}
 }
 
+// instrument wraps an HTTP handler function, extracting trace context from the request, and recording metrics and a
+// span for each request.
+func (h *baseHTTPHandler) instrument(method MetricsMethod, handler http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		metricsRequest := MetricsRequest{Component: h.metricsComponent, Method: method}
+		startTime := time.Now()
+		h.metrics.RequestStarted(metricsRequest)
+
+		ctx := extractSpanContext(request.Context(), request.Header)
+		ctx, span := startSpan(ctx, h.tracer, SpanOptions{
+			Name: "nexus." + string(h.metricsComponent) + "." + string(method),
+			Kind: SpanKindServer,
+		})
+
+		recorder := &statusRecordingWriter{ResponseWriter: writer}
+		defer func() {
+			if recorder.statusCode == 0 {
+				// Handler returned without writing, net/http will respond with 200.
+				recorder.statusCode = http.StatusOK
+				recorder.written = time.Now()
+			}
+			outcome := metricsOutcomeFromResponse(method, recorder.statusCode, recorder.Header())
+			h.metrics.RequestCompleted(metricsRequest, MetricsResult{
+				Outcome:    outcome,
+				StatusCode: recorder.statusCode,
+				Latency:    recorder.written.Sub(startTime),
+			})
+			span.SetAttribute("http.status_code", strconv.Itoa(recorder.statusCode))
+			span.SetAttribute("nexus.outcome", string(outcome))
+			if outcome == MetricsOutcomeHandlerError {
+				span.RecordError(fmt.Errorf("request failed with status: %d", recorder.statusCode))
+			}
+			span.End()
+		}()
+		handler(recorder, request.WithContext(ctx))
+	}
+}
+
+// checkOperationRegistered fails with a 404 error if a registry is set and the operation is not registered in it or
+// not available to the request's tenant.
+func (h *httpHandler) checkOperationRegistered(ctx context.Context, operation string) error {
+	if h.options.Registry == nil {
+		return nil
+	}
+	if definition, ok := h.options.Registry.Lookup(operation); !ok || !definition.availableTo(ctx) {
+		return newUnknownOperationError(operation)
+	}
+	return nil
+}
+
+func (h *httpHandler) describeService(writer http.ResponseWriter, request *http.Request) {
+	if err := h.authorize(request, AuthorizationActionDescribeService, "", ""); err != nil {
+		h.writeFailure(writer, err)
+		return
+	}
+	var definitions []OperationDefinition
+	for _, definition := range h.options.Registry.Operations() {
+		if definition.availableTo(request.Context()) {
+			definitions = append(definitions, definition)
+		}
+	}
+	bytes, err := json.Marshal(h.options.Registry.describe(definitions))
+	if err != nil {
+		h.writeFailure(writer, fmt.Errorf("failed to marshal service description: %w", err))
+		return
+	}
+	writer.Header().Set(headerContentType, contentTypeJSON)
+	if _, err := writer.Write(bytes); err != nil {
+		h.logger.Error("failed to write response body", "error", err)
+	}
+}
+
+// escapedPath returns the escaped request path that operation names and IDs are parsed from.
+// URL.RawPath can't be used directly since it is only set when the path contains escapes that differ from the default
+// encoding, e.g. it is empty for "/foo/bar".
+func escapedPath(request *http.Request) string {
+	return request.URL.EscapedPath()
+}
+
 func (h *httpHandler) startOperation(writer http.ResponseWriter, request *http.Request) {
-	operation, err := url.PathUnescape(path.Base(request.URL.RawPath))
+	operation, err := url.PathUnescape(path.Base(escapedPath(request)))
 	if err != nil {
 		h.writeFailure(writer, newBadRequestError("failed to parse URL path"))
 		return
 	}
+	if err := h.checkOperationRegistered(request.Context(), operation); err != nil {
+		h.writeFailure(writer, err)
+		return
+	}
+	if err := h.authorize(request, AuthorizationActionStartOperation, operation, ""); err != nil {
+		h.writeFailure(writer, err)
+		return
+	}
+	if err := h.validateStartOperationInput(operation, request); err != nil {
+		h.writeFailure(writer, err)
+		return
+	}
+	callbackURL := request.URL.Query().Get(queryCallbackURL)
+	if callbackURL != "" && h.options.CallbackURLPolicy != nil {
+		if err := h.options.CallbackURLPolicy.Validate(callbackURL); err != nil {
+			h.writeFailure(writer, newBadRequestError("invalid callback URL: %v", err))
+			return
+		}
+	}
 	handlerRequest := &StartOperationRequest{
 		Operation:   operation,
 		RequestID:   request.Header.Get(headerRequestID),
-		CallbackURL: request.URL.Query().Get(queryCallbackURL),
+		CallbackURL: callbackURL,
+		Accept:      parseAccept(request.Header),
 		HTTPRequest: request,
+		codecs:      h.options.Codecs,
 	}
 	response, err := h.options.Handler.StartOperation(request.Context(), handlerRequest)
 	if err != nil {
 		h.writeFailure(writer, err)
-	} else {
-		response.applyToHTTPResponse(writer, h)
+		return
 	}
+	if async, ok := response.(*OperationResponseAsync); ok {
+		operationID, err := h.encodeOperationID(request.Context(), operation, async.OperationID)
+		if err != nil {
+			h.writeFailure(writer, fmt.Errorf("failed to encode operation ID: %w", err))
+			return
+		}
+		response = &OperationResponseAsync{OperationID: operationID}
+	}
+	response.applyToHTTPResponse(writer, request, h)
 }
 
 func (h *httpHandler) getOperationResult(writer http.ResponseWriter, request *http.Request) {
 	// strip /result
-	prefix, operationIDEscaped := path.Split(path.Dir(request.URL.RawPath))
+	prefix, operationIDEscaped := path.Split(path.Dir(escapedPath(request)))
 	operationID, err := url.PathUnescape(operationIDEscaped)
 	if err != nil {
 		h.writeFailure(writer, newBadRequestError("failed to parse URL path"))
@@ -284,11 +493,31 @@ func (h *httpHandler) getOperationResult(writer http.ResponseWriter, request *ht
 		h.writeFailure(writer, newBadRequestError("failed to parse URL path"))
 		return
 	}
-	handlerRequest := &GetOperationResultRequest{Operation: operation, OperationID: operationID, HTTPRequest: request}
+	if err := h.checkOperationRegistered(request.Context(), operation); err != nil {
+		h.writeFailure(writer, err)
+		return
+	}
+	internalID, err := h.decodeOperationID(request, operation, operationID)
+	if err != nil {
+		h.writeFailure(writer, err)
+		return
+	}
+	if err := h.authorize(request, AuthorizationActionGetOperationResult, operation, internalID); err != nil {
+		h.writeFailure(writer, err)
+		return
+	}
+	handlerRequest := &GetOperationResultRequest{
+		Operation:   operation,
+		OperationID: internalID,
+		Accept:      parseAccept(request.Header),
+		HTTPRequest: request,
+		codecs:      h.options.Codecs,
+	}
 
 	waitStr := request.URL.Query().Get(queryWait)
+	longPoll := waitStr != ""
 	ctx := request.Context()
-	if waitStr != "" {
+	if longPoll {
 		waitDuration, err := time.ParseDuration(waitStr)
 		if err != nil {
 			h.logger.Warn("invalid wait duration query parameter", "wait", waitStr)
@@ -299,11 +528,19 @@ func (h *httpHandler) getOperationResult(writer http.ResponseWriter, request *ht
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(request.Context(), h.options.GetResultTimeout)
 		defer cancel()
+		var accepted bool
+		ctx, cancel, accepted = h.tracker.startLongPoll(ctx)
+		defer cancel()
+		if !accepted {
+			// The handler is draining, have the client poll again, potentially reaching another replica.
+			writer.WriteHeader(http.StatusRequestTimeout)
+			return
+		}
 	}
 
 	response, err := h.options.Handler.GetOperationResult(ctx, handlerRequest)
 	if err != nil {
-		if handlerRequest.Wait > 0 && ctx.Err() != nil {
+		if longPoll && ctx.Err() != nil {
 			writer.WriteHeader(http.StatusRequestTimeout)
 		} else if errors.Is(err, ErrOperationStillRunning) {
 			writer.WriteHeader(statusOperationRunning)
@@ -312,11 +549,11 @@ func (h *httpHandler) getOperationResult(writer http.ResponseWriter, request *ht
 		}
 		return
 	}
-	response.applyToHTTPResponse(writer, h)
+	response.applyToHTTPResponse(writer, request, h)
 }
 
 func (h *httpHandler) getOperationInfo(writer http.ResponseWriter, request *http.Request) {
-	prefix, operationIDEscaped := path.Split(request.URL.RawPath)
+	prefix, operationIDEscaped := path.Split(escapedPath(request))
 	operationID, err := url.PathUnescape(operationIDEscaped)
 	if err != nil {
 		h.writeFailure(writer, newBadRequestError("failed to parse URL path"))
@@ -327,13 +564,32 @@ func (h *httpHandler) getOperationInfo(writer http.ResponseWriter, request *http
 		h.writeFailure(writer, newBadRequestError("failed to parse URL path"))
 		return
 	}
-	handlerRequest := &GetOperationInfoRequest{Operation: operation, OperationID: operationID, HTTPRequest: request}
+	if err := h.checkOperationRegistered(request.Context(), operation); err != nil {
+		h.writeFailure(writer, err)
+		return
+	}
+	internalID, err := h.decodeOperationID(request, operation, operationID)
+	if err != nil {
+		h.writeFailure(writer, err)
+		return
+	}
+	if err := h.authorize(request, AuthorizationActionGetOperationInfo, operation, internalID); err != nil {
+		h.writeFailure(writer, err)
+		return
+	}
+	handlerRequest := &GetOperationInfoRequest{Operation: operation, OperationID: internalID, HTTPRequest: request}
 
 	info, err := h.options.Handler.GetOperationInfo(request.Context(), handlerRequest)
 	if err != nil {
 		h.writeFailure(writer, err)
 		return
 	}
+	if h.options.OperationIDCodec != nil {
+		// Respond with the opaque ID rather than the internal one.
+		opaque := *info
+		opaque.ID = operationID
+		info = &opaque
+	}
 
 	bytes, err := json.Marshal(info)
 	if err != nil {
@@ -348,7 +604,7 @@ func (h *httpHandler) getOperationInfo(writer http.ResponseWriter, request *http
 
 func (h *httpHandler) cancelOperation(writer http.ResponseWriter, request *http.Request) {
 	// strip /cancel
-	prefix, operationIDEscaped := path.Split(path.Dir(request.URL.RawPath))
+	prefix, operationIDEscaped := path.Split(path.Dir(escapedPath(request)))
 	operationID, err := url.PathUnescape(operationIDEscaped)
 	if err != nil {
 		h.writeFailure(writer, newBadRequestError("failed to parse URL path"))
@@ -359,7 +615,20 @@ func (h *httpHandler) cancelOperation(writer http.ResponseWriter, request *http.
 		h.writeFailure(writer, newBadRequestError("failed to parse URL path"))
 		return
 	}
-	handlerRequest := &CancelOperationRequest{Operation: operation, OperationID: operationID, HTTPRequest: request}
+	if err := h.checkOperationRegistered(request.Context(), operation); err != nil {
+		h.writeFailure(writer, err)
+		return
+	}
+	internalID, err := h.decodeOperationID(request, operation, operationID)
+	if err != nil {
+		h.writeFailure(writer, err)
+		return
+	}
+	if err := h.authorize(request, AuthorizationActionCancelOperation, operation, internalID); err != nil {
+		h.writeFailure(writer, err)
+		return
+	}
+	handlerRequest := &CancelOperationRequest{Operation: operation, OperationID: internalID, HTTPRequest: request}
 
 	if err := h.options.Handler.CancelOperation(request.Context(), handlerRequest); err != nil {
 		h.writeFailure(writer, err)
@@ -381,27 +650,151 @@ type HandlerOptions struct {
 	//
 	// Defaults to one minute.
 	GetResultTimeout time.Duration
+	// Optional handler for recording request metrics.
+	MetricsHandler MetricsHandler
+	// Optional tracer for creating a span for each request. Regardless of this option, W3C trace context is
+	// extracted from incoming requests and made available via [SpanContextFromContext].
+	Tracer Tracer
+	// Optional registry of the operations exposed by the service.
+	// When set, a [ServiceDescription] is served for GET requests on the service root and requests for unregistered
+	// operations are rejected with a 404 status without invoking the Handler.
+	Registry *OperationRegistry
+	// Max size in bytes of start request bodies read for validation against the input schema of a registered
+	// operation. Larger bodies are rejected with a 413 status without invoking the Handler.
+	//
+	// Defaults to 4 MiB.
+	MaxInputSize int64
+	// Codecs available for encoding synchronous results with [StartOperationRequest.NegotiateResponse] and
+	// [GetOperationResultRequest.NegotiateResponse], in order of preference.
+	// Defaults to [JSONCodec].
+	Codecs []Codec
+	// Compressors for decompressing request bodies and compressing synchronous result bodies, in order of preference.
+	// Requests with a Content-Encoding not supported by any of the compressors are rejected with a 415 status.
+	// Defaults to [GzipCompressor] and [ZstdCompressor].
+	Compressors []Compressor
+	// Max size in bytes of decompressed request bodies. Reading beyond the limit fails with an [http.MaxBytesError] and
+	// requests are rejected with a 413 status.
+	//
+	// Defaults to 16 MiB.
+	MaxDecompressedBodySize int64
+	// Minimum size in bytes of synchronous result bodies to compress with the compressor negotiated from the caller's
+	// Accept-Encoding header. Set to a positive value to enable compression of result bodies.
+	CompressionThreshold int
+	// Optional hook invoked with panics recovered from Handler methods. Regardless of this option, panics are logged
+	// and the request is failed with a 500 status.
+	PanicHandler PanicHandler
+	// Optional registry of errors that may be reported to callers. Errors returned from Handler methods that are, or
+	// wrap, registered sentinels or errors of registered types are reported as failures converted with
+	// [NewFailureFromError], with a 500 status unless returned as the Cause of a [HandlerError]. Other errors are
+	// reported as internal server errors.
+	ErrorRegistry *ErrorRegistry
+	// Optional policy for callback URLs. When set, start requests with callback URLs that are denied by the policy
+	// are rejected with a 400 status without invoking the Handler. Deliver completions with
+	// [CallbackURLPolicy.HTTPClient] to enforce the policy again when connecting.
+	CallbackURLPolicy *CallbackURLPolicy
+	// Optional rules authorizing mutual TLS peers to call operations. When set, requests without a client certificate
+	// are rejected with a 401 status and requests from peers that match none of the rules that apply to the requested
+	// operation are rejected with a 403 status, without invoking the Handler. Regardless of this option, the identity
+	// of mutual TLS peers is made available via [PeerIdentityFromContext].
+	PeerAuthorizationRules []PeerAuthorizationRule
+	// Optional authorizer invoked for every request after the operation is resolved and before the Handler is invoked.
+	// See [Authorizer].
+	Authorizer Authorizer
+	// Optional function returning the principal a request was made by, provided to the Authorizer.
+	// Defaults to the SPIFFE ID of the request's mutual TLS peer, falling back to its first DNS name prefixed with dns:
+	// and its subject common name prefixed with cn:, e.g. dns:billing.example.org. See [PeerIdentity].
+	Principal func(*http.Request) string
+	// Optional tenancy options. When set, the tenant of every request is resolved before the operation and made
+	// available via [TenantFromContext] and [AuthorizationRequest.Tenant]. Requests the tenant cannot be resolved for,
+	// or whose principal is not a member of the tenant, are rejected without invoking the Handler. Operation
+	// definitions in the Registry may be restricted to tenants, see [OperationDefinition.Tenants]. Operation IDs are
+	// only bound to tenants if OperationIDCodec is set, otherwise handlers must key their operations on the tenant so
+	// that one tenant cannot address operations of another.
+	Tenancy *TenantOptions
+	// Optional codec for opaque operation IDs. When set, the IDs of [OperationResponseAsync] responses are encoded with
+	// the codec, binding them to the operation and tenant, and the IDs of get-result, get-info, and cancel requests are
+	// decoded and verified before the Authorizer and Handler are invoked with the internal ID. Requests with IDs that
+	// fail to decode, e.g. because they were tampered with or issued for another operation or tenant, are rejected with
+	// a 404 status.
+	//
+	// All processes serving the same operations, including across restarts, must share the codec's keys.
+	OperationIDCodec *OperationIDCodec
 }
 
-// NewHTTPHandler constructs an [http.Handler] from given options for handling Nexus service requests.
-func NewHTTPHandler(options HandlerOptions) http.Handler {
+// NewHTTPHandler constructs an [HTTPHandler] from given options for handling Nexus service requests.
+func NewHTTPHandler(options HandlerOptions) *HTTPHandler {
 	if options.Logger == nil {
 		options.Logger = slog.Default()
 	}
 	if options.GetResultTimeout == 0 {
 		options.GetResultTimeout = time.Minute
 	}
+	if options.MetricsHandler == nil {
+		options.MetricsHandler = noopMetricsHandler{}
+	}
+	if options.Tracer == nil {
+		options.Tracer = noopTracer{}
+	}
+	if options.MaxInputSize == 0 {
+		options.MaxInputSize = 4 << 20
+	}
+	if len(options.Codecs) == 0 {
+		options.Codecs = []Codec{JSONCodec{}}
+	}
+	if len(options.Compressors) == 0 {
+		options.Compressors = []Compressor{GzipCompressor{}, ZstdCompressor{}}
+	}
+	if options.MaxDecompressedBodySize == 0 {
+		options.MaxDecompressedBodySize = defaultMaxDecompressedBodySize
+	}
+	if options.Principal == nil {
+		options.Principal = principalFromPeerIdentity
+	}
+	if options.Tenancy != nil {
+		tenancy := *options.Tenancy
+		if tenancy.Header == "" {
+			tenancy.Header = headerTenant
+		}
+		if tenancy.FromPrincipal == nil {
+			tenancy.FromPrincipal = func(principal string) (string, bool) { return principal, true }
+		}
+		options.Tenancy = &tenancy
+	}
 	handler := &httpHandler{
 		baseHTTPHandler: baseHTTPHandler{
-			logger: slog.Default(),
+			logger:           options.Logger,
+			metrics:          options.MetricsHandler,
+			metricsComponent: MetricsComponentHandler,
+			tracer:           options.Tracer,
+			compressors:      options.Compressors,
+			panicHandler:     options.PanicHandler,
+			errorRegistry:    options.ErrorRegistry,
+
+			maxDecompressedBodySize: options.MaxDecompressedBodySize,
 		},
 		options: options,
+		tracker: newRequestTracker(),
 	}
 
 	router := mux.NewRouter().UseEncodedPath()
-	router.HandleFunc("/{operation}", handler.startOperation).Methods("POST")
-	router.HandleFunc("/{operation}/{operation_id}", handler.getOperationInfo).Methods("GET")
-	router.HandleFunc("/{operation}/{operation_id}/result", handler.getOperationResult).Methods("GET")
-	router.HandleFunc("/{operation}/{operation_id}/cancel", handler.cancelOperation).Methods("POST")
-	return router
+	routes := router
+	if options.Tenancy != nil && options.Tenancy.Source == TenantSourcePathSegment {
+		routes = router.PathPrefix("/{tenant}").Subrouter()
+	}
+	if options.Registry != nil {
+		routes.HandleFunc("/", handler.wrap(MetricsMethodDescribeService, handler.describeService)).Methods("GET")
+	}
+	routes.HandleFunc("/{operation}", handler.wrap(MetricsMethodStartOperation, handler.startOperation)).Methods("POST")
+	routes.HandleFunc("/{operation}/{operation_id}", handler.wrap(MetricsMethodGetOperationInfo, handler.getOperationInfo)).Methods("GET")
+	routes.HandleFunc("/{operation}/{operation_id}/result", handler.wrap(MetricsMethodGetOperationResult, handler.getOperationResult)).Methods("GET")
+	routes.HandleFunc("/{operation}/{operation_id}/cancel", handler.wrap(MetricsMethodCancelOperation, handler.cancelOperation)).Methods("POST")
+	return &HTTPHandler{
+		router:  router,
+		tracker: handler.tracker,
+	}
+}
+
+// wrap wraps a route's handler function with common request processing.
+func (h *httpHandler) wrap(method MetricsMethod, handler http.HandlerFunc) http.HandlerFunc {
+	return h.tracker.track(h.instrument(method, h.recoverPanics(h.identifyPeer(h.resolveTenant(h.decompress(handler))))))
 }
@@ -1,6 +1,8 @@
 package nexus
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -62,3 +64,84 @@ func TestWriteFailure_UnsuccessfulOperationError(t *testing.T) {
 	require.NoError(t, json.Unmarshal(writer.Body.Bytes(), &failure))
 	require.Equal(t, "canceled", failure.Message)
 }
+
+func TestWriteFailure_RetryBehavior(t *testing.T) {
+	h := baseHTTPHandler{
+		logger: slog.Default(),
+	}
+
+	for behavior, expected := range map[RetryBehavior]string{
+		RetryBehaviorUnspecified:  "",
+		RetryBehaviorRetryable:    "true",
+		RetryBehaviorNonRetryable: "false",
+	} {
+		writer := httptest.NewRecorder()
+		h.writeFailure(writer, &HandlerError{StatusCode: http.StatusBadRequest, RetryBehavior: behavior})
+		require.Equal(t, expected, writer.Header().Get(headerRetryable))
+	}
+}
+
+type pathRecordingHandler struct {
+	UnimplementedHandler
+	operation   string
+	operationID string
+}
+
+func (h *pathRecordingHandler) StartOperation(ctx context.Context, request *StartOperationRequest) (OperationResponse, error) {
+	h.operation, h.operationID = request.Operation, ""
+	return &OperationResponseSync{Body: bytes.NewReader(nil)}, nil
+}
+
+func (h *pathRecordingHandler) GetOperationResult(ctx context.Context, request *GetOperationResultRequest) (*OperationResponseSync, error) {
+	h.operation, h.operationID = request.Operation, request.OperationID
+	return &OperationResponseSync{Body: bytes.NewReader(nil)}, nil
+}
+
+func (h *pathRecordingHandler) GetOperationInfo(ctx context.Context, request *GetOperationInfoRequest) (*OperationInfo, error) {
+	h.operation, h.operationID = request.Operation, request.OperationID
+	return &OperationInfo{ID: request.OperationID, State: OperationStateRunning}, nil
+}
+
+func (h *pathRecordingHandler) CancelOperation(ctx context.Context, request *CancelOperationRequest) error {
+	h.operation, h.operationID = request.Operation, request.OperationID
+	return nil
+}
+
+func TestEscapedPath(t *testing.T) {
+	plain := httptest.NewRequest("GET", "/foo/plain-id", nil)
+	require.Equal(t, "", plain.URL.RawPath)
+	require.Equal(t, "/foo/plain-id", escapedPath(plain))
+
+	escaped := httptest.NewRequest("GET", "/escape%2Fme/needs%20%2FURL%2F%20escaping", nil)
+	require.Equal(t, "/escape%2Fme/needs%20%2FURL%2F%20escaping", escaped.URL.RawPath)
+	require.Equal(t, "/escape%2Fme/needs%20%2FURL%2F%20escaping", escapedPath(escaped))
+}
+
+func TestHTTPHandler_ParsesOperationAndIDFromPath(t *testing.T) {
+	handler := &pathRecordingHandler{}
+	httpHandler := NewHTTPHandler(HandlerOptions{Handler: handler})
+	cases := []struct {
+		method      string
+		path        string
+		operation   string
+		operationID string
+	}{
+		// Paths without escaped characters have an empty URL.RawPath.
+		{"POST", "/foo", "foo", ""},
+		{"GET", "/foo/plain-id", "foo", "plain-id"},
+		{"GET", "/foo/plain-id/result", "foo", "plain-id"},
+		{"POST", "/foo/plain-id/cancel", "foo", "plain-id"},
+		{"POST", "/escape%2Fme", "escape/me", ""},
+		{"GET", "/escape%2Fme/needs%20%2FURL%2F%20escaping", "escape/me", "needs /URL/ escaping"},
+		{"GET", "/escape%2Fme/needs%20%2FURL%2F%20escaping/result", "escape/me", "needs /URL/ escaping"},
+		{"POST", "/escape%2Fme/needs%20%2FURL%2F%20escaping/cancel", "escape/me", "needs /URL/ escaping"},
+	}
+	for _, c := range cases {
+		*handler = pathRecordingHandler{}
+		writer := httptest.NewRecorder()
+		httpHandler.ServeHTTP(writer, httptest.NewRequest(c.method, c.path, nil))
+		require.Less(t, writer.Code, 300, "%s %s: %s", c.method, c.path, writer.Body.String())
+		require.Equal(t, c.operation, handler.operation, "%s %s", c.method, c.path)
+		require.Equal(t, c.operationID, handler.operationID, "%s %s", c.method, c.path)
+	}
+}
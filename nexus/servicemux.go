@@ -0,0 +1,138 @@
+package nexus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ServiceMuxOptions are options for [NewServiceMux].
+type ServiceMuxOptions struct {
+	// Path prefix to mount services under, e.g. /services, in which case a service named foo serves requests at
+	// /services/foo/{operation}. Defaults to mounting services at the root.
+	Prefix string
+	// A stuctured logger.
+	// Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// A ServiceMux is an [http.Handler] that hosts multiple named services, each served by its own [HTTPHandler], under
+// a common path prefix.
+//
+// Requests to {prefix}/{service}/... are routed to the service's handler with the {prefix}/{service} portion stripped
+// from the URL path. Requests for unknown services are rejected with a 404 status.
+//
+// Set [ClientOptions.Service] to target a service hosted by a ServiceMux.
+type ServiceMux struct {
+	base     baseHTTPHandler
+	prefix   string
+	mu       sync.RWMutex
+	services map[string]*HTTPHandler
+}
+
+var errEmptyServiceName = errors.New("empty service name")
+
+var errDuplicateService = errors.New("duplicate service")
+
+// NewServiceMux constructs a new, empty [ServiceMux].
+func NewServiceMux(options ServiceMuxOptions) *ServiceMux {
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+	prefix := strings.TrimSuffix(options.Prefix, "/")
+	if prefix != "" && !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	return &ServiceMux{
+		base:     baseHTTPHandler{logger: options.Logger},
+		prefix:   (&url.URL{Path: prefix}).EscapedPath(),
+		services: make(map[string]*HTTPHandler),
+	}
+}
+
+// Handle registers the handler for a named service.
+// Fails if the name is empty or a handler is already registered for it.
+func (m *ServiceMux) Handle(service string, handler *HTTPHandler) error {
+	if service == "" {
+		return errEmptyServiceName
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.services[service]; ok {
+		return fmt.Errorf("%w: %q", errDuplicateService, service)
+	}
+	m.services[service] = handler
+	return nil
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (m *ServiceMux) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	rest, ok := strings.CutPrefix(request.URL.EscapedPath(), m.prefix)
+	if !ok || !strings.HasPrefix(rest, "/") {
+		m.base.writeFailure(writer, &HandlerError{StatusCode: http.StatusNotFound, Failure: &Failure{Message: "not found"}})
+		return
+	}
+	segment, remainder, _ := strings.Cut(rest[1:], "/")
+	service, err := url.PathUnescape(segment)
+	if err != nil {
+		m.base.writeFailure(writer, newBadRequestError("failed to parse URL path"))
+		return
+	}
+	m.mu.RLock()
+	handler, ok := m.services[service]
+	m.mu.RUnlock()
+	if !ok {
+		m.base.writeFailure(writer, &HandlerError{
+			StatusCode: http.StatusNotFound,
+			Failure:    &Failure{Message: fmt.Sprintf("unknown service: %q", service)},
+		})
+		return
+	}
+
+	rawPath := "/" + remainder
+	path, err := url.PathUnescape(rawPath)
+	if err != nil {
+		m.base.writeFailure(writer, newBadRequestError("failed to parse URL path"))
+		return
+	}
+	stripped := new(http.Request)
+	*stripped = *request
+	stripped.URL = new(url.URL)
+	*stripped.URL = *request.URL
+	stripped.URL.Path = path
+	stripped.URL.RawPath = rawPath
+	handler.ServeHTTP(writer, stripped)
+}
+
+// Drain calls [HTTPHandler.Drain] on the handlers of all registered services concurrently and blocks until they all
+// return. Returns the context's error if any handler did not finish draining before ctx was done.
+func (m *ServiceMux) Drain(ctx context.Context) error {
+	m.mu.RLock()
+	handlers := make([]*HTTPHandler, 0, len(m.services))
+	for _, handler := range m.services {
+		handlers = append(handlers, handler)
+	}
+	m.mu.RUnlock()
+
+	errs := make([]error, len(handlers))
+	var wg sync.WaitGroup
+	for i, handler := range handlers {
+		wg.Add(1)
+		go func(i int, handler *HTTPHandler) {
+			defer wg.Done()
+			errs[i] = handler.Drain(ctx)
+		}(i, handler)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
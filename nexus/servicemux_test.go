@@ -0,0 +1,74 @@
+package nexus
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceMux(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	registry := NewOperationRegistry(OperationRegistryOptions{Name: "info"})
+	require.NoError(t, registry.Register(OperationDefinition{Name: "escape/me", Async: true}))
+
+	serviceMux := NewServiceMux(ServiceMuxOptions{Prefix: "/services/"})
+	require.NoError(t, serviceMux.Handle("json", NewHTTPHandler(HandlerOptions{Handler: &jsonHandler{}})))
+	require.NoError(t, serviceMux.Handle("info/service", NewHTTPHandler(HandlerOptions{
+		Handler:  &asyncWithInfoHandler{},
+		Registry: registry,
+	})))
+	require.ErrorIs(t, serviceMux.Handle("json", NewHTTPHandler(HandlerOptions{Handler: &jsonHandler{}})), errDuplicateService)
+	require.ErrorIs(t, serviceMux.Handle("", NewHTTPHandler(HandlerOptions{Handler: &jsonHandler{}})), errEmptyServiceName)
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		// Ignore for test purposes
+		_ = http.Serve(listener, serviceMux)
+	}()
+	baseURL := fmt.Sprintf("http://%s/services", listener.Addr().String())
+
+	jsonClient, err := NewClient(ClientOptions{ServiceBaseURL: baseURL, Service: "json"})
+	require.NoError(t, err)
+	result, err := jsonClient.StartOperation(ctx, StartOperationOptions{Operation: "foo"})
+	require.NoError(t, err)
+	defer result.Successful.Body.Close()
+	body, err := io.ReadAll(result.Successful.Body)
+	require.NoError(t, err)
+	require.Equal(t, `"success"`, string(body))
+
+	infoClient, err := NewClient(ClientOptions{ServiceBaseURL: baseURL, Service: "info/service"})
+	require.NoError(t, err)
+	description, err := infoClient.Describe(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "info", description.Name)
+	handle, err := infoClient.NewHandle("escape/me", "needs /URL/ escaping")
+	require.NoError(t, err)
+	info, err := handle.GetInfo(ctx, GetOperationInfoOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "needs /URL/ escaping", info.ID)
+
+	unknownClient, err := NewClient(ClientOptions{ServiceBaseURL: baseURL, Service: "unknown"})
+	require.NoError(t, err)
+	_, err = unknownClient.StartOperation(ctx, StartOperationOptions{Operation: "foo"})
+	var unexpectedResponseError *UnexpectedResponseError
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusNotFound, unexpectedResponseError.Response.StatusCode)
+	require.Equal(t, `unknown service: "unknown"`, unexpectedResponseError.Failure.Message)
+
+	outsideClient, err := NewClient(ClientOptions{ServiceBaseURL: fmt.Sprintf("http://%s/other", listener.Addr().String()), Service: "json"})
+	require.NoError(t, err)
+	_, err = outsideClient.StartOperation(ctx, StartOperationOptions{Operation: "foo"})
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusNotFound, unexpectedResponseError.Response.StatusCode)
+
+	require.NoError(t, serviceMux.Drain(ctx))
+}
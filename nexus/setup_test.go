@@ -15,18 +15,21 @@ const testTimeout = time.Second * 5
 const getResultMaxTimeout = time.Millisecond * 300
 
 func setup(t *testing.T, handler Handler) (ctx context.Context, client *Client, teardown func()) {
-	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
-
-	httpHandler := NewHTTPHandler(HandlerOptions{
+	return setupCustom(t, HandlerOptions{
 		GetResultTimeout: getResultMaxTimeout,
 		Handler:          handler,
-	})
+	}, ClientOptions{})
+}
+
+func setupCustom(t *testing.T, handlerOptions HandlerOptions, clientOptions ClientOptions) (ctx context.Context, client *Client, teardown func()) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+
+	httpHandler := NewHTTPHandler(handlerOptions)
 
 	listener, err := net.Listen("tcp", "localhost:0")
 	require.NoError(t, err)
-	client, err = NewClient(ClientOptions{
-		ServiceBaseURL: fmt.Sprintf("http://%s/", listener.Addr().String()),
-	})
+	clientOptions.ServiceBaseURL = fmt.Sprintf("http://%s/", listener.Addr().String())
+	client, err = NewClient(clientOptions)
 	require.NoError(t, err)
 
 	go func() {
@@ -0,0 +1,148 @@
+package nexus
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+)
+
+// TenantSource is where a handler resolves the tenant of a request from. See [TenantOptions].
+type TenantSource int
+
+const (
+	// Resolve the tenant from the first segment of the URL path, serving requests at /{tenant}/{operation}. Clients
+	// of a tenant include the segment in [ClientOptions.ServiceBaseURL], e.g. https://example.com/acme/.
+	TenantSourcePathSegment = TenantSource(iota)
+	// Resolve the tenant from a request header, see [TenantOptions.Header].
+	TenantSourceHeader
+	// Resolve the tenant from the principal the request was made by, see [TenantOptions.FromPrincipal].
+	TenantSourcePrincipal
+)
+
+const headerTenant = "Nexus-Tenant"
+
+// TenantOptions configure resolving the tenant of every request. See [HandlerOptions.Tenancy].
+type TenantOptions struct {
+	// Where to resolve the tenant from. Defaults to [TenantSourcePathSegment].
+	Source TenantSource
+	// Name of the header to resolve the tenant from with [TenantSourceHeader].
+	// Defaults to Nexus-Tenant.
+	Header string
+	// Function mapping the principal a request was made by, as returned by [HandlerOptions.Principal], to its tenant
+	// with [TenantSourcePrincipal]. Returns false if the principal does not belong to any tenant.
+	// Defaults to using the principal as the tenant.
+	FromPrincipal func(principal string) (string, bool)
+	// Optional function reporting whether the principal a request was made by, as returned by
+	// [HandlerOptions.Principal], is a member of the request's tenant. Requests of non members are rejected with a 403
+	// status without invoking the Handler. Set it with [TenantSourcePathSegment] and [TenantSourceHeader], whose
+	// tenants are chosen by callers.
+	Authorize func(principal, tenant string) bool
+}
+
+type tenantKeyType struct{}
+
+var tenantKey = tenantKeyType{}
+
+// ContextWithTenant returns a copy of ctx that carries the given tenant.
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenant)
+}
+
+// TenantFromContext returns the tenant stored in ctx, if any.
+//
+// Handlers constructed with [NewHTTPHandler] with [HandlerOptions.Tenancy] set store the tenant of every request in
+// the context passed to [Handler] methods.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantKey).(string)
+	return tenant, ok && tenant != ""
+}
+
+// tenantFromRequest resolves the tenant of a request according to the handler's tenancy options and checks that the
+// request's principal is a member of it.
+func (h *httpHandler) tenantFromRequest(request *http.Request) (string, error) {
+	tenant, err := h.resolveRequestTenant(request)
+	if err != nil {
+		return "", err
+	}
+	if authorize := h.options.Tenancy.Authorize; authorize != nil && !authorize(h.options.Principal(request), tenant) {
+		return "", &HandlerError{
+			StatusCode:    http.StatusForbidden,
+			Failure:       &Failure{Message: "principal is not a member of the tenant"},
+			RetryBehavior: RetryBehaviorNonRetryable,
+		}
+	}
+	return tenant, nil
+}
+
+// resolveRequestTenant resolves the tenant of a request from the source set in the handler's tenancy options.
+func (h *httpHandler) resolveRequestTenant(request *http.Request) (string, error) {
+	options := h.options.Tenancy
+	switch options.Source {
+	case TenantSourceHeader:
+		if tenant := request.Header.Get(options.Header); tenant != "" {
+			return tenant, nil
+		}
+		return "", newBadRequestError("missing %s header", options.Header)
+	case TenantSourcePrincipal:
+		principal := h.options.Principal(request)
+		if principal == "" {
+			return "", &HandlerError{
+				StatusCode:    http.StatusUnauthorized,
+				Failure:       &Failure{Message: "authentication required to resolve tenant"},
+				RetryBehavior: RetryBehaviorNonRetryable,
+			}
+		}
+		if tenant, ok := options.FromPrincipal(principal); ok && tenant != "" {
+			return tenant, nil
+		}
+		return "", &HandlerError{
+			StatusCode:    http.StatusForbidden,
+			Failure:       &Failure{Message: "principal does not belong to a tenant"},
+			RetryBehavior: RetryBehaviorNonRetryable,
+		}
+	default:
+		tenant, err := url.PathUnescape(mux.Vars(request)["tenant"])
+		if err != nil {
+			return "", newBadRequestError("failed to parse URL path")
+		}
+		if tenant == "" {
+			return "", newBadRequestError("missing tenant")
+		}
+		return tenant, nil
+	}
+}
+
+// resolveTenant wraps a handler function, making the tenant of requests available via the request's context.
+func (h *httpHandler) resolveTenant(handler http.HandlerFunc) http.HandlerFunc {
+	if h.options.Tenancy == nil {
+		return handler
+	}
+	return func(writer http.ResponseWriter, request *http.Request) {
+		tenant, err := h.tenantFromRequest(request)
+		if err != nil {
+			h.writeFailure(writer, err)
+			return
+		}
+		handler(writer, request.WithContext(ContextWithTenant(request.Context(), tenant)))
+	}
+}
+
+// availableTo reports whether the operation is available to the tenant stored in ctx. Operations are available to
+// all tenants if no tenant is stored in ctx.
+func (d OperationDefinition) availableTo(ctx context.Context) bool {
+	if len(d.Tenants) == 0 {
+		return true
+	}
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		return true
+	}
+	for _, t := range d.Tenants {
+		if t == tenant {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,239 @@
+package nexus
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type tenantHandler struct {
+	UnimplementedHandler
+}
+
+func (h *tenantHandler) StartOperation(ctx context.Context, request *StartOperationRequest) (OperationResponse, error) {
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		tenant = "none"
+	}
+	return &OperationResponseSync{Body: bytes.NewReader([]byte(tenant))}, nil
+}
+
+func newTenantClient(t *testing.T, serviceBaseURL string) *Client {
+	client, err := NewClient(ClientOptions{ServiceBaseURL: serviceBaseURL})
+	require.NoError(t, err)
+	return client
+}
+
+func TestTenancy_PathSegment(t *testing.T) {
+	registry := NewOperationRegistry(OperationRegistryOptions{})
+	require.NoError(t, registry.Register(
+		OperationDefinition{Name: "foo", Sync: true},
+		OperationDefinition{Name: "acme-only", Sync: true, Tenants: []string{"acme"}},
+	))
+	server := httptest.NewServer(NewHTTPHandler(HandlerOptions{
+		Handler:  &tenantHandler{},
+		Registry: registry,
+		Tenancy:  &TenantOptions{Source: TenantSourcePathSegment},
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	acme := newTenantClient(t, server.URL+"/acme/")
+	result, err := acme.StartOperation(ctx, StartOperationOptions{Operation: "foo"})
+	require.NoError(t, err)
+	require.Equal(t, "acme", readSyncResult(t, result))
+	result, err = acme.StartOperation(ctx, StartOperationOptions{Operation: "acme-only"})
+	require.NoError(t, err)
+	require.Equal(t, "acme", readSyncResult(t, result))
+	description, err := acme.Describe(ctx)
+	require.NoError(t, err)
+	require.Len(t, description.Operations, 2)
+
+	globex := newTenantClient(t, server.URL+"/globex/")
+	result, err = globex.StartOperation(ctx, StartOperationOptions{Operation: "foo"})
+	require.NoError(t, err)
+	require.Equal(t, "globex", readSyncResult(t, result))
+	var unexpectedResponseError *UnexpectedResponseError
+	_, err = globex.StartOperation(ctx, StartOperationOptions{Operation: "acme-only"})
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusNotFound, unexpectedResponseError.Response.StatusCode)
+	description, err = globex.Describe(ctx)
+	require.NoError(t, err)
+	require.Len(t, description.Operations, 1)
+	require.Equal(t, "foo", description.Operations[0].Name)
+
+	// Requests without a tenant segment are not routed.
+	_, err = newTenantClient(t, server.URL+"/").StartOperation(ctx, StartOperationOptions{Operation: "foo"})
+	require.Error(t, err)
+}
+
+func TestTenancy_Header(t *testing.T) {
+	ctx, client, teardown := setupCustom(t, HandlerOptions{
+		Handler: &tenantHandler{},
+		Tenancy: &TenantOptions{Source: TenantSourceHeader},
+	}, ClientOptions{})
+	defer teardown()
+
+	result, err := client.StartOperation(ctx, StartOperationOptions{
+		Operation: "foo",
+		Header:    http.Header{"Nexus-Tenant": []string{"acme"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "acme", readSyncResult(t, result))
+
+	var unexpectedResponseError *UnexpectedResponseError
+	_, err = client.StartOperation(ctx, StartOperationOptions{Operation: "foo"})
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusBadRequest, unexpectedResponseError.Response.StatusCode)
+}
+
+func TestTenancy_Authorize(t *testing.T) {
+	ctx, client, teardown := setupCustom(t, HandlerOptions{
+		Handler:   &tenantHandler{},
+		Principal: principalFromHeader,
+		Tenancy: &TenantOptions{
+			Source: TenantSourceHeader,
+			Authorize: func(principal, tenant string) bool {
+				return principal == "alice" && tenant == "acme"
+			},
+		},
+	}, ClientOptions{})
+	defer teardown()
+
+	result, err := client.StartOperation(ctx, StartOperationOptions{
+		Operation: "foo",
+		Header:    http.Header{"Nexus-Tenant": []string{"acme"}, "X-Principal": []string{"alice"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "acme", readSyncResult(t, result))
+
+	var unexpectedResponseError *UnexpectedResponseError
+	for _, header := range []http.Header{
+		{"Nexus-Tenant": []string{"acme"}, "X-Principal": []string{"mallory"}},
+		{"Nexus-Tenant": []string{"globex"}, "X-Principal": []string{"alice"}},
+		{"Nexus-Tenant": []string{"acme"}},
+	} {
+		_, err = client.StartOperation(ctx, StartOperationOptions{Operation: "foo", Header: header})
+		require.ErrorAs(t, err, &unexpectedResponseError)
+		require.Equal(t, http.StatusForbidden, unexpectedResponseError.Response.StatusCode)
+	}
+}
+
+func TestTenancy_OperationIDsWithoutCodec(t *testing.T) {
+	handler := &internalIDHandler{}
+	ctx, client, teardown := setupCustom(t, HandlerOptions{
+		Handler: handler,
+		Tenancy: &TenantOptions{Source: TenantSourceHeader},
+	}, ClientOptions{})
+	defer teardown()
+
+	// IDs are not encoded with an implicit key that other replicas or restarted processes wouldn't share.
+	acme := http.Header{"Nexus-Tenant": []string{"acme"}}
+	result, err := client.StartOperation(ctx, StartOperationOptions{Operation: "foo", Header: acme})
+	require.NoError(t, err)
+	require.Equal(t, "row-42", result.Pending.ID)
+}
+
+func TestTenancy_BindsOperationIDs(t *testing.T) {
+	handler := &internalIDHandler{}
+	ctx, client, teardown := setupCustom(t, HandlerOptions{
+		Handler:          handler,
+		Tenancy:          &TenantOptions{Source: TenantSourceHeader},
+		OperationIDCodec: newTestOperationIDCodec(t, OperationIDCodecOptions{}),
+	}, ClientOptions{})
+	defer teardown()
+
+	acme := http.Header{"Nexus-Tenant": []string{"acme"}}
+	result, err := client.StartOperation(ctx, StartOperationOptions{Operation: "foo", Header: acme})
+	require.NoError(t, err)
+	handle := result.Pending
+	require.NotNil(t, handle)
+	require.NotEqual(t, "row-42", handle.ID)
+	info, err := handle.GetInfo(ctx, GetOperationInfoOptions{Header: acme})
+	require.NoError(t, err)
+	require.Equal(t, handle.ID, info.ID)
+
+	var unexpectedResponseError *UnexpectedResponseError
+	_, err = handle.GetInfo(ctx, GetOperationInfoOptions{Header: http.Header{"Nexus-Tenant": []string{"globex"}}})
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusNotFound, unexpectedResponseError.Response.StatusCode)
+	require.Equal(t, []string{"row-42"}, handler.requests)
+}
+
+func TestTenancy_Principal(t *testing.T) {
+	authorizer := &recordingAuthorizer{}
+	ctx, client, teardown := setupCustom(t, HandlerOptions{
+		Handler:    &tenantHandler{},
+		Authorizer: authorizer,
+		Principal:  principalFromHeader,
+		Tenancy: &TenantOptions{
+			Source: TenantSourcePrincipal,
+			FromPrincipal: func(principal string) (string, bool) {
+				tenant, ok := map[string]string{"alice": "acme"}[principal]
+				return tenant, ok
+			},
+		},
+	}, ClientOptions{})
+	defer teardown()
+
+	result, err := client.StartOperation(ctx, StartOperationOptions{
+		Operation: "foo",
+		Header:    http.Header{"X-Principal": []string{"alice"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "acme", readSyncResult(t, result))
+	require.Len(t, authorizer.requests, 1)
+	require.Equal(t, "acme", authorizer.requests[0].Tenant)
+
+	var unexpectedResponseError *UnexpectedResponseError
+	_, err = client.StartOperation(ctx, StartOperationOptions{
+		Operation: "foo",
+		Header:    http.Header{"X-Principal": []string{"mallory"}},
+	})
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusForbidden, unexpectedResponseError.Response.StatusCode)
+
+	_, err = client.StartOperation(ctx, StartOperationOptions{Operation: "foo"})
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusUnauthorized, unexpectedResponseError.Response.StatusCode)
+	// Requests the tenant cannot be resolved for are not authorized.
+	require.Len(t, authorizer.requests, 1)
+}
+
+func TestResultNotifier_Tenants(t *testing.T) {
+	notifier := NewResultNotifier(ResultNotifierOptions{})
+	result := &OperationResult{Body: []byte("done")}
+	notifier.NotifyTenant("acme", "id", result)
+
+	_, ok := notifier.Peek("id")
+	require.False(t, ok)
+	_, ok = notifier.PeekTenant("globex", "id")
+	require.False(t, ok)
+	peeked, ok := notifier.PeekTenant("acme", "id")
+	require.True(t, ok)
+	require.Equal(t, result, peeked)
+
+	acme := ContextWithTenant(context.Background(), "acme")
+	awaited, err := notifier.Await(acme, "id")
+	require.NoError(t, err)
+	require.Equal(t, result, awaited)
+
+	globex, cancel := context.WithTimeout(ContextWithTenant(context.Background(), "globex"), time.Millisecond*50)
+	defer cancel()
+	_, err = notifier.Await(globex, "id")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	_, err = notifier.GetOperationResult(globex, &GetOperationResultRequest{OperationID: "id"})
+	require.ErrorIs(t, err, ErrOperationStillRunning)
+
+	response, err := notifier.GetOperationResult(acme, &GetOperationResultRequest{OperationID: "id"})
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(response.Body)
+	require.NoError(t, err)
+	require.Equal(t, "done", buf.String())
+}
@@ -0,0 +1,301 @@
+package nexus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	headerTraceparent = "Traceparent"
+	headerTracestate  = "Tracestate"
+)
+
+// SpanContext identifies a span as defined by the [W3C Trace Context] specification.
+//
+// [W3C Trace Context]: https://www.w3.org/TR/trace-context/
+type SpanContext struct {
+	TraceID    [16]byte
+	SpanID     [8]byte
+	TraceFlags byte
+	// Vendor specific trace information, propagated as is via the tracestate header.
+	TraceState string
+	// Set when this span context was extracted from an incoming request.
+	Remote bool
+}
+
+// IsValid reports whether both the trace and span IDs are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// Sampled reports whether the sampled trace flag is set.
+func (sc SpanContext) Sampled() bool {
+	return sc.TraceFlags&0x01 == 0x01
+}
+
+// Traceparent formats the span context as a traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", hex.EncodeToString(sc.TraceID[:]), hex.EncodeToString(sc.SpanID[:]), sc.TraceFlags)
+}
+
+type spanContextKeyType struct{}
+
+var spanContextKey = spanContextKeyType{}
+
+// ContextWithSpanContext returns a copy of ctx that carries the given span context. The span context is injected into
+// outgoing requests made by the [Client] and by requests constructed with [NewCompletionHTTPRequest].
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey, sc)
+}
+
+// SpanContextFromContext returns the span context stored in ctx, if any.
+//
+// Handlers constructed with [NewHTTPHandler] and [NewCompletionHTTPHandler] extract the span context from incoming
+// requests and make it available via the handler's context.
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanContextKey).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
+
+// parseTraceparent parses a traceparent header value as defined by the W3C Trace Context specification.
+func parseTraceparent(value string) (sc SpanContext, ok bool) {
+	value = strings.TrimSpace(value)
+	// version (2) - trace-id (32) - parent-id (16) - trace-flags (2)
+	if len(value) < 55 || value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return sc, false
+	}
+	version, err := hex.DecodeString(value[:2])
+	if err != nil || version[0] == 0xff {
+		return sc, false
+	}
+	// Version 00 has a fixed length, future versions may append fields.
+	if (version[0] == 0 && len(value) != 55) || (len(value) > 55 && value[55] != '-') {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(value[3:35])); err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(value[36:52])); err != nil {
+		return sc, false
+	}
+	flags, err := hex.DecodeString(value[53:55])
+	if err != nil {
+		return sc, false
+	}
+	// Upper case hex is not allowed.
+	if strings.ToLower(value[:55]) != value[:55] {
+		return sc, false
+	}
+	sc.TraceFlags = flags[0]
+	return sc, sc.IsValid()
+}
+
+// extractSpanContext extracts a remote span context from the given header into ctx.
+// Returns ctx unmodified if the header does not contain a valid traceparent.
+func extractSpanContext(ctx context.Context, header http.Header) context.Context {
+	sc, ok := parseTraceparent(header.Get(headerTraceparent))
+	if !ok {
+		return ctx
+	}
+	sc.TraceState = strings.Join(header.Values(headerTracestate), ",")
+	sc.Remote = true
+	return ContextWithSpanContext(ctx, sc)
+}
+
+// injectSpanContext sets the traceparent and tracestate headers from the span context in ctx, if any.
+func injectSpanContext(ctx context.Context, header http.Header) {
+	sc, ok := SpanContextFromContext(ctx)
+	if !ok {
+		return
+	}
+	header.Set(headerTraceparent, sc.Traceparent())
+	if sc.TraceState != "" {
+		header.Set(headerTracestate, sc.TraceState)
+	} else {
+		header.Del(headerTracestate)
+	}
+}
+
+// SpanKind describes the relationship of a span to the request it represents.
+type SpanKind string
+
+const (
+	SpanKindInternal SpanKind = "internal"
+	SpanKindClient   SpanKind = "client"
+	SpanKindServer   SpanKind = "server"
+)
+
+// SpanOptions are options for [Tracer.Start].
+type SpanOptions struct {
+	// Name of the span, e.g. nexus.client.start_operation.
+	Name string
+	Kind SpanKind
+	// Initial attributes of the span.
+	Attributes map[string]string
+}
+
+// A Span represents a single traced call.
+type Span interface {
+	// SpanContext returns the identity of this span.
+	SpanContext() SpanContext
+	// SetAttribute sets a single attribute on the span.
+	SetAttribute(key, value string)
+	// RecordError marks the span as failed.
+	RecordError(err error)
+	// End completes the span.
+	End()
+}
+
+// A Tracer creates spans around client calls, long poll iterations, and handled requests.
+// Use it to adapt the SDK to a tracing library of your choice. Implementations must be safe for concurrent use.
+type Tracer interface {
+	// Start starts a new span. The span should be a child of the span context found in ctx via
+	// [SpanContextFromContext], if any.
+	//
+	// The SDK stores the returned span's context in the context it passes on to handlers and uses to inject trace
+	// headers into outgoing requests.
+	Start(ctx context.Context, options SpanOptions) Span
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SpanContext() SpanContext    { return SpanContext{} }
+func (noopSpan) SetAttribute(string, string) {}
+func (noopSpan) RecordError(error)           {}
+func (noopSpan) End()                        {}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(context.Context, SpanOptions) Span { return noopSpan{} }
+
+// startSpan starts a span with the given tracer, returning a context that carries the new span's context.
+func startSpan(ctx context.Context, tracer Tracer, options SpanOptions) (context.Context, Span) {
+	span := tracer.Start(ctx, options)
+	if sc := span.SpanContext(); sc.IsValid() {
+		ctx = ContextWithSpanContext(ctx, sc)
+	}
+	return ctx, span
+}
+
+// endSpan records err, if set, on span and ends it.
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// RecordedSpan is a snapshot of a span recorded by a [SpanRecorder].
+type RecordedSpan struct {
+	Name        string
+	Kind        SpanKind
+	SpanContext SpanContext
+	// Context of the parent span, invalid for root spans.
+	Parent     SpanContext
+	Attributes map[string]string
+	Errors     []error
+	StartTime  time.Time
+	EndTime    time.Time
+}
+
+// SpanRecorder is an in-memory [Tracer] that records ended spans, meant to be used in tests.
+type SpanRecorder struct {
+	mu    sync.Mutex
+	ended []RecordedSpan
+}
+
+// NewSpanRecorder constructs a new [SpanRecorder].
+func NewSpanRecorder() *SpanRecorder {
+	return &SpanRecorder{}
+}
+
+// Start implements the Tracer interface.
+func (r *SpanRecorder) Start(ctx context.Context, options SpanOptions) Span {
+	span := &recorderSpan{
+		recorder: r,
+		span: RecordedSpan{
+			Name:       options.Name,
+			Kind:       options.Kind,
+			Attributes: make(map[string]string, len(options.Attributes)),
+			StartTime:  time.Now(),
+		},
+	}
+	for k, v := range options.Attributes {
+		span.span.Attributes[k] = v
+	}
+	if parent, ok := SpanContextFromContext(ctx); ok {
+		span.span.Parent = parent
+		span.span.SpanContext.TraceID = parent.TraceID
+		span.span.SpanContext.TraceFlags = parent.TraceFlags
+		span.span.SpanContext.TraceState = parent.TraceState
+	} else {
+		_, _ = rand.Read(span.span.SpanContext.TraceID[:])
+		span.span.SpanContext.TraceFlags = 0x01
+	}
+	_, _ = rand.Read(span.span.SpanContext.SpanID[:])
+	return span
+}
+
+// Spans returns the spans ended so far, in the order they were ended.
+func (r *SpanRecorder) Spans() []RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedSpan(nil), r.ended...)
+}
+
+// Reset clears all recorded spans.
+func (r *SpanRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ended = nil
+}
+
+type recorderSpan struct {
+	recorder *SpanRecorder
+	mu       sync.Mutex
+	span     RecordedSpan
+	ended    bool
+}
+
+func (s *recorderSpan) SpanContext() SpanContext {
+	return s.span.SpanContext
+}
+
+func (s *recorderSpan) SetAttribute(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.span.Attributes[key] = value
+}
+
+func (s *recorderSpan) RecordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.span.Errors = append(s.span.Errors, err)
+}
+
+func (s *recorderSpan) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.span.EndTime = time.Now()
+	snapshot := s.span
+	snapshot.Attributes = make(map[string]string, len(s.span.Attributes))
+	for k, v := range s.span.Attributes {
+		snapshot.Attributes[k] = v
+	}
+	snapshot.Errors = append([]error(nil), s.span.Errors...)
+	s.mu.Unlock()
+
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.recorder.ended = append(s.recorder.ended, snapshot)
+}
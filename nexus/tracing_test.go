@@ -0,0 +1,163 @@
+package nexus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	type testcase struct {
+		name  string
+		value string
+		valid bool
+	}
+	cases := []testcase{
+		{name: "valid", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", valid: true},
+		{name: "future version with extra fields", value: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", valid: true},
+		{name: "invalid version", value: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{name: "version 00 with extra fields", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+		{name: "zero trace ID", value: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{name: "zero span ID", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"},
+		{name: "upper case", value: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		{name: "bad separator", value: "00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{name: "empty"},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			sc, ok := parseTraceparent(c.value)
+			require.Equal(t, c.valid, ok)
+			if c.valid {
+				// Always propagated as version 00.
+				require.Equal(t, "00"+c.value[2:55], sc.Traceparent())
+				require.True(t, sc.Sampled())
+			}
+		})
+	}
+}
+
+type spanContextEchoHandler struct {
+	UnimplementedHandler
+}
+
+func (h *spanContextEchoHandler) StartOperation(ctx context.Context, request *StartOperationRequest) (OperationResponse, error) {
+	sc, ok := SpanContextFromContext(ctx)
+	if !ok {
+		return nil, newBadRequestError("no span context in handler context")
+	}
+	return &OperationResponseSync{Body: strings.NewReader(sc.Traceparent() + " " + sc.TraceState)}, nil
+}
+
+func TestTracing_PropagationWithoutTracer(t *testing.T) {
+	ctx, client, teardown := setup(t, &spanContextEchoHandler{})
+	defer teardown()
+
+	sc, ok := parseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.True(t, ok)
+	sc.TraceState = "vendor=value"
+	ctx = ContextWithSpanContext(ctx, sc)
+
+	response, err := client.ExecuteOperation(ctx, ExecuteOperationOptions{Operation: "foo"})
+	require.NoError(t, err)
+	defer response.Body.Close()
+	body, err := readAndReplaceBody(response)
+	require.NoError(t, err)
+	require.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01 vendor=value", string(body))
+}
+
+func TestTracing_Spans(t *testing.T) {
+	clientTracer := NewSpanRecorder()
+	handlerTracer := NewSpanRecorder()
+	ctx, client, teardown := setupCustom(t, HandlerOptions{
+		Handler:          &asyncWithResultHandler{timesToBlock: 1},
+		GetResultTimeout: getResultMaxTimeout,
+		Tracer:           handlerTracer,
+	}, ClientOptions{Tracer: clientTracer})
+	defer teardown()
+
+	response, err := client.ExecuteOperation(ctx, ExecuteOperationOptions{Operation: "foo"})
+	require.NoError(t, err)
+	response.Body.Close()
+
+	clientSpans := clientTracer.Spans()
+	names := make([]string, len(clientSpans))
+	for i, span := range clientSpans {
+		names[i] = span.Name
+	}
+	require.Equal(t, []string{
+		"nexus.client.start_operation",
+		// The first poll times out and is retried.
+		"nexus.client.get_operation_result.poll",
+		"nexus.client.get_operation_result.poll",
+		"nexus.client.get_operation_result",
+		"nexus.client.execute_operation",
+	}, names)
+	root := clientSpans[4]
+	require.False(t, root.Parent.IsValid())
+	require.Equal(t, "foo", root.Attributes["nexus.operation"])
+	for _, span := range clientSpans[:4] {
+		require.Equal(t, root.SpanContext.TraceID, span.SpanContext.TraceID)
+	}
+	require.Equal(t, root.SpanContext, clientSpans[0].Parent)
+	require.Equal(t, clientSpans[3].SpanContext, clientSpans[1].Parent)
+	require.Equal(t, "a/sync", clientSpans[1].Attributes["nexus.operation_id"])
+
+	// Handler spans end after responses are written, wait for all of them to be recorded.
+	require.Eventually(t, func() bool { return len(handlerTracer.Spans()) == 3 }, time.Second, time.Millisecond*10)
+	handlerSpans := handlerTracer.Spans()
+	sort.Slice(handlerSpans, func(i, j int) bool { return handlerSpans[i].StartTime.Before(handlerSpans[j].StartTime) })
+	for i, span := range handlerSpans {
+		require.Equal(t, SpanKindServer, span.Kind)
+		require.True(t, span.Parent.Remote)
+		// Handler spans should be children of the client spans that sent the requests.
+		require.Equal(t, clientSpans[i].SpanContext.SpanID, span.Parent.SpanID)
+	}
+	require.Equal(t, "nexus.handler.start_operation", handlerSpans[0].Name)
+	require.Equal(t, "async_started", handlerSpans[0].Attributes["nexus.outcome"])
+	require.Equal(t, "timeout", handlerSpans[1].Attributes["nexus.outcome"])
+	require.Equal(t, "408", handlerSpans[1].Attributes["http.status_code"])
+	require.Equal(t, "sync_success", handlerSpans[2].Attributes["nexus.outcome"])
+}
+
+func TestTracing_Completion(t *testing.T) {
+	tracer := NewSpanRecorder()
+	var handlerSpanContext SpanContext
+	handler := NewCompletionHTTPHandler(CompletionHandlerOptions{
+		Handler: completionHandlerFunc(func(ctx context.Context, request *CompletionRequest) error {
+			handlerSpanContext, _ = SpanContextFromContext(ctx)
+			return nil
+		}),
+		Tracer: tracer,
+	})
+
+	sc, ok := parseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.True(t, ok)
+	ctx, cancel := context.WithTimeout(ContextWithSpanContext(context.Background(), sc), time.Second)
+	defer cancel()
+	request, err := NewCompletionHTTPRequest(ctx, "http://localhost/callback", &OperationCompletionSuccessful{
+		Header: http.Header{"foo": []string{"bar"}},
+		Body:   strings.NewReader("success"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, sc.Traceparent(), request.Header.Get(headerTraceparent))
+
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+	spans := tracer.Spans()
+	require.Equal(t, 1, len(spans))
+	require.Equal(t, "nexus.completion_handler.complete_operation", spans[0].Name)
+	require.Equal(t, sc.SpanID, spans[0].Parent.SpanID)
+	require.Equal(t, spans[0].SpanContext, handlerSpanContext)
+}
+
+type completionHandlerFunc func(context.Context, *CompletionRequest) error
+
+func (f completionHandlerFunc) CompleteOperation(ctx context.Context, request *CompletionRequest) error {
+	return f(ctx, request)
+}
@@ -0,0 +1,120 @@
+package nexus
+
+import (
+	"context"
+	"io"
+)
+
+// TypedCompletion is an operation completion with a decoded result, delivered to handlers constructed with
+// [NewTypedCompletionHandler]. It has three implementations: [*CompletionSucceeded], [*CompletionFailed], and
+// [*CompletionCanceled].
+//
+//	switch c := completion.(type) {
+//	case *nexus.CompletionSucceeded[MyResult]:
+//		// use c.Result
+//	case *nexus.CompletionFailed:
+//		// use c.Failure
+//	case *nexus.CompletionCanceled:
+//		// use c.Failure
+//	}
+type TypedCompletion[T any] interface {
+	completionRequest() *CompletionRequest
+}
+
+// CompletionSucceeded is a [TypedCompletion] of an operation that completed successfully.
+type CompletionSucceeded[T any] struct {
+	// Result decoded from the request body.
+	Result T
+	// The original completion request. Its HTTPRequest body has already been consumed.
+	Request *CompletionRequest
+}
+
+func (c *CompletionSucceeded[T]) completionRequest() *CompletionRequest {
+	return c.Request
+}
+
+// CompletionFailed is a [TypedCompletion] of an operation that failed.
+type CompletionFailed struct {
+	// Failure reported by the handler.
+	Failure Failure
+	// The original completion request. Its HTTPRequest body has already been consumed.
+	Request *CompletionRequest
+}
+
+func (c *CompletionFailed) completionRequest() *CompletionRequest {
+	return c.Request
+}
+
+// CompletionCanceled is a [TypedCompletion] of an operation that was canceled.
+type CompletionCanceled struct {
+	// Failure reported by the handler.
+	Failure Failure
+	// The original completion request. Its HTTPRequest body has already been consumed.
+	Request *CompletionRequest
+}
+
+func (c *CompletionCanceled) completionRequest() *CompletionRequest {
+	return c.Request
+}
+
+type typedCompletionHandler[T any] struct {
+	handle func(context.Context, TypedCompletion[T]) error
+}
+
+// NewTypedCompletionHandler constructs a [CompletionHandler] that decodes successful results into values of type T
+// using the [CompletionHandlerOptions.Codecs] codec matching the request's Content-Type, and invokes handle with a
+// [TypedCompletion].
+//
+// Successful completions with an unsupported Content-Type or a body that fails to decode are rejected with a 400
+// status. Successful completions with an empty body and no Content-Type are delivered with a zero Result.
+func NewTypedCompletionHandler[T any](handle func(context.Context, TypedCompletion[T]) error) CompletionHandler {
+	return &typedCompletionHandler[T]{handle: handle}
+}
+
+func (h *typedCompletionHandler[T]) CompleteOperation(ctx context.Context, request *CompletionRequest) error {
+	var failure Failure
+	if request.Failure != nil {
+		failure = *request.Failure
+	}
+	switch request.State {
+	case OperationStateFailed:
+		return h.handle(ctx, &CompletionFailed{Failure: failure, Request: request})
+	case OperationStateCanceled:
+		return h.handle(ctx, &CompletionCanceled{Failure: failure, Request: request})
+	case OperationStateSucceeded:
+		result, err := decodeCompletionResult[T](request)
+		if err != nil {
+			return err
+		}
+		return h.handle(ctx, &CompletionSucceeded[T]{Result: result, Request: request})
+	default:
+		return newBadRequestError("invalid request operation state: %q", request.State)
+	}
+}
+
+// decodeCompletionResult decodes the body of a successful completion request with the codec matching its
+// Content-Type.
+func decodeCompletionResult[T any](request *CompletionRequest) (T, error) {
+	var result T
+	b, err := io.ReadAll(request.HTTPRequest.Body)
+	if err != nil {
+		return result, newReadBodyError(err, "failed to read result from request body")
+	}
+	contentType := request.HTTPRequest.Header.Get(headerContentType)
+	if contentType == "" && len(b) == 0 {
+		return result, nil
+	}
+	codecs := request.codecs
+	if len(codecs) == 0 {
+		codecs = []Codec{JSONCodec{}}
+	}
+	for _, codec := range codecs {
+		if (MediaRange{MediaType: codec.MediaType()}).Matches(contentType) {
+			if err := codec.Unmarshal(b, &result); err != nil {
+				return result, newBadRequestError("failed to decode result from request body: %v", err)
+			}
+			return result, nil
+		}
+	}
+	return result, newBadRequestError("invalid request content type: %q", contentType)
+}
@@ -0,0 +1,108 @@
+package nexus
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type typedResult struct {
+	Value string `json:"value"`
+}
+
+func serveTypedCompletion[T any](t *testing.T, options CompletionHandlerOptions, completion OperationCompletion) (TypedCompletion[T], int) {
+	var received TypedCompletion[T]
+	options.Handler = NewTypedCompletionHandler(func(ctx context.Context, completion TypedCompletion[T]) error {
+		received = completion
+		return nil
+	})
+	handler := NewCompletionHTTPHandler(options)
+	request, err := NewCompletionHTTPRequest(context.Background(), "http://localhost/callback?a=b", completion)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return received, recorder.Code
+}
+
+func TestTypedCompletion_Succeeded(t *testing.T) {
+	completion, err := NewOperationCompletionSuccessful(typedResult{Value: "hello"})
+	require.NoError(t, err)
+	received, status := serveTypedCompletion[typedResult](t, CompletionHandlerOptions{}, completion)
+	require.Equal(t, http.StatusOK, status)
+	succeeded, ok := received.(*CompletionSucceeded[typedResult])
+	require.True(t, ok)
+	require.Equal(t, typedResult{Value: "hello"}, succeeded.Result)
+	require.Equal(t, OperationStateSucceeded, succeeded.Request.State)
+	require.Equal(t, "b", succeeded.Request.HTTPRequest.URL.Query().Get("a"))
+}
+
+func TestTypedCompletion_SucceededEmpty(t *testing.T) {
+	received, status := serveTypedCompletion[*typedResult](t, CompletionHandlerOptions{}, &OperationCompletionSuccessful{
+		Body: bytes.NewReader(nil),
+	})
+	require.Equal(t, http.StatusOK, status)
+	succeeded, ok := received.(*CompletionSucceeded[*typedResult])
+	require.True(t, ok)
+	require.Nil(t, succeeded.Result)
+}
+
+func TestTypedCompletion_CustomCodec(t *testing.T) {
+	options := CompletionHandlerOptions{Codecs: []Codec{JSONCodec{}, textCodec{}}}
+	received, status := serveTypedCompletion[string](t, options, &OperationCompletionSuccessful{
+		Header: http.Header{headerContentType: []string{"text/plain; charset=utf-8"}},
+		Body:   bytes.NewReader([]byte("hello")),
+	})
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "hello", received.(*CompletionSucceeded[string]).Result)
+}
+
+func TestTypedCompletion_UnexpectedContentType(t *testing.T) {
+	received, status := serveTypedCompletion[typedResult](t, CompletionHandlerOptions{}, &OperationCompletionSuccessful{
+		Header: http.Header{headerContentType: []string{"text/plain"}},
+		Body:   bytes.NewReader([]byte("hello")),
+	})
+	require.Equal(t, http.StatusBadRequest, status)
+	require.Nil(t, received)
+
+	received, status = serveTypedCompletion[typedResult](t, CompletionHandlerOptions{}, &OperationCompletionSuccessful{
+		Body: bytes.NewReader([]byte(`{"value":"hello"}`)),
+	})
+	require.Equal(t, http.StatusBadRequest, status)
+	require.Nil(t, received)
+}
+
+func TestTypedCompletion_InvalidBody(t *testing.T) {
+	received, status := serveTypedCompletion[typedResult](t, CompletionHandlerOptions{}, &OperationCompletionSuccessful{
+		Header: http.Header{headerContentType: []string{contentTypeJSON}},
+		Body:   bytes.NewReader([]byte("not json")),
+	})
+	require.Equal(t, http.StatusBadRequest, status)
+	require.Nil(t, received)
+}
+
+func TestTypedCompletion_Failed(t *testing.T) {
+	received, status := serveTypedCompletion[typedResult](t, CompletionHandlerOptions{}, &OperationCompletionUnsuccessful{
+		State:   OperationStateFailed,
+		Failure: &Failure{Message: "failed"},
+	})
+	require.Equal(t, http.StatusOK, status)
+	failed, ok := received.(*CompletionFailed)
+	require.True(t, ok)
+	require.Equal(t, "failed", failed.Failure.Message)
+	require.Equal(t, OperationStateFailed, failed.Request.State)
+}
+
+func TestTypedCompletion_Canceled(t *testing.T) {
+	received, status := serveTypedCompletion[typedResult](t, CompletionHandlerOptions{}, &OperationCompletionUnsuccessful{
+		State:   OperationStateCanceled,
+		Failure: &Failure{Message: "canceled"},
+	})
+	require.Equal(t, http.StatusOK, status)
+	canceled, ok := received.(*CompletionCanceled)
+	require.True(t, ok)
+	require.Equal(t, "canceled", canceled.Failure.Message)
+}
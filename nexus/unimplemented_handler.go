@@ -14,20 +14,28 @@ func (h *UnimplementedHandler) mustEmbedUnimplementedHandler() {}
 
 // StartOperation implements the Handler interface.
 func (h *UnimplementedHandler) StartOperation(ctx context.Context, request *StartOperationRequest) (OperationResponse, error) {
-	return nil, &HandlerError{http.StatusNotImplemented, &Failure{Message: "not implemented"}}
+	return nil, newNotImplementedError()
 }
 
 // GetOperationResult implements the Handler interface.
 func (h *UnimplementedHandler) GetOperationResult(ctx context.Context, request *GetOperationResultRequest) (*OperationResponseSync, error) {
-	return nil, &HandlerError{http.StatusNotImplemented, &Failure{Message: "not implemented"}}
+	return nil, newNotImplementedError()
 }
 
 // GetOperationInfo implements the Handler interface.
 func (h *UnimplementedHandler) GetOperationInfo(ctx context.Context, request *GetOperationInfoRequest) (*OperationInfo, error) {
-	return nil, &HandlerError{http.StatusNotImplemented, &Failure{Message: "not implemented"}}
+	return nil, newNotImplementedError()
 }
 
 // CancelOperation implements the Handler interface.
 func (h *UnimplementedHandler) CancelOperation(ctx context.Context, request *CancelOperationRequest) error {
-	return &HandlerError{http.StatusNotImplemented, &Failure{Message: "not implemented"}}
+	return newNotImplementedError()
+}
+
+func newNotImplementedError() *HandlerError {
+	return &HandlerError{
+		StatusCode:    http.StatusNotImplemented,
+		Failure:       &Failure{Message: "not implemented"},
+		RetryBehavior: RetryBehaviorNonRetryable,
+	}
 }
@@ -0,0 +1,575 @@
+package nexus
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FailureTypeInputValidation is the value of the "type" [Failure] metadata key set on failures returned by handlers
+// that reject start requests with input that does not conform to the operation's input schema. The failure's details
+// are encoded as [InputValidationFailureDetails].
+const FailureTypeInputValidation = "nexus.InputValidationFailure"
+
+// A SchemaViolation describes a single value that does not conform to a JSON schema.
+type SchemaViolation struct {
+	// JSON pointer to the violating value, e.g. /items/0/name. Empty for the root value.
+	Path string `json:"path"`
+	// Schema keyword of the violated constraint, e.g. required, type, or maxLength.
+	Constraint string `json:"constraint"`
+	// Human readable description of the violation.
+	Message string `json:"message"`
+}
+
+// InputValidationFailureDetails are the details of failures with the [FailureTypeInputValidation] type.
+type InputValidationFailureDetails struct {
+	// Violations found in the input.
+	Violations []SchemaViolation `json:"violations"`
+}
+
+// maxSchemaDepth bounds the nesting of subschema evaluation to protect against recursive $ref cycles that do not
+// descend into the value.
+const maxSchemaDepth = 256
+
+var errInvalidSchema = errors.New("invalid JSON schema")
+
+// ValidateJSONSchema validates a JSON encoded value against a JSON schema and returns the violations found, if any.
+//
+// A subset of JSON schema is supported: type, enum, const, properties, required, additionalProperties, items,
+// minItems, maxItems, minLength, maxLength, pattern, minimum, maximum, exclusiveMinimum, exclusiveMaximum, allOf,
+// anyOf, oneOf, not, and $ref to a JSON pointer within the same schema. Other keywords are ignored.
+// Returns an error if the value is not valid JSON or the schema cannot be evaluated.
+func ValidateJSONSchema(schema json.RawMessage, value []byte) ([]SchemaViolation, error) {
+	var root any
+	if err := decodeJSONNumbers(schema, &root); err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidSchema, err)
+	}
+	var v any
+	if err := decodeJSONNumbers(value, &v); err != nil {
+		return nil, err
+	}
+	validator := &schemaValidator{root: root}
+	validator.validate(root, v, "", 0)
+	if validator.err != nil {
+		return nil, validator.err
+	}
+	return validator.violations, nil
+}
+
+func decodeJSONNumbers(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("unexpected data after top-level value")
+	}
+	return nil
+}
+
+type schemaValidator struct {
+	root       any
+	violations []SchemaViolation
+	err        error
+	regexps    map[string]*regexp.Regexp
+}
+
+func (v *schemaValidator) fail(path, constraint, format string, args ...any) {
+	v.violations = append(v.violations, SchemaViolation{
+		Path:       path,
+		Constraint: constraint,
+		Message:    fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *schemaValidator) schemaError(format string, args ...any) {
+	if v.err == nil {
+		v.err = fmt.Errorf("%w: %s", errInvalidSchema, fmt.Sprintf(format, args...))
+	}
+}
+
+// matches reports whether value conforms to schema without recording violations.
+func (v *schemaValidator) matches(schema, value any, path string, depth int) bool {
+	saved := v.violations
+	v.violations = nil
+	v.validate(schema, value, path, depth)
+	ok := len(v.violations) == 0
+	v.violations = saved
+	return ok
+}
+
+func (v *schemaValidator) validate(schema, value any, path string, depth int) {
+	if v.err != nil {
+		return
+	}
+	if depth > maxSchemaDepth {
+		v.schemaError("maximum depth exceeded")
+		return
+	}
+	var s map[string]any
+	switch schema := schema.(type) {
+	case bool:
+		if !schema {
+			v.fail(path, "false", "no value is allowed")
+		}
+		return
+	case map[string]any:
+		s = schema
+	default:
+		v.schemaError("schema at %q is not an object or boolean", path)
+		return
+	}
+
+	if ref, ok := s["$ref"].(string); ok {
+		target, err := resolveJSONPointer(v.root, ref)
+		if err != nil {
+			v.schemaError("%v", err)
+			return
+		}
+		v.validate(target, value, path, depth+1)
+	}
+
+	if t, ok := s["type"]; ok {
+		v.validateType(t, value, path)
+	}
+	if enum, ok := s["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			if jsonValuesEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.fail(path, "enum", "value must be one of the enumerated values")
+		}
+	}
+	if c, ok := s["const"]; ok && !jsonValuesEqual(c, value) {
+		v.fail(path, "const", "value must be equal to the constant value")
+	}
+
+	switch value := value.(type) {
+	case map[string]any:
+		v.validateObject(s, value, path, depth)
+	case []any:
+		v.validateArray(s, value, path, depth)
+	case string:
+		v.validateString(s, value, path)
+	case json.Number:
+		v.validateNumber(s, value, path)
+	}
+
+	if allOf, ok := s["allOf"].([]any); ok {
+		for _, sub := range allOf {
+			v.validate(sub, value, path, depth+1)
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]any); ok {
+		found := false
+		for _, sub := range anyOf {
+			if v.matches(sub, value, path, depth+1) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.fail(path, "anyOf", "value must match at least one schema")
+		}
+	}
+	if oneOf, ok := s["oneOf"].([]any); ok {
+		count := 0
+		for _, sub := range oneOf {
+			if v.matches(sub, value, path, depth+1) {
+				count++
+			}
+		}
+		if count != 1 {
+			v.fail(path, "oneOf", "value must match exactly one schema, matched %d", count)
+		}
+	}
+	if not, ok := s["not"]; ok && v.matches(not, value, path, depth+1) {
+		v.fail(path, "not", "value must not match schema")
+	}
+}
+
+func (v *schemaValidator) validateType(t any, value any, path string) {
+	var types []string
+	switch t := t.(type) {
+	case string:
+		types = []string{t}
+	case []any:
+		for _, e := range t {
+			if s, ok := e.(string); ok {
+				types = append(types, s)
+			}
+		}
+	default:
+		v.schemaError("type at %q must be a string or an array", path)
+		return
+	}
+	for _, t := range types {
+		if jsonValueHasType(value, t) {
+			return
+		}
+	}
+	v.fail(path, "type", "value must be of type %s", strings.Join(types, " or "))
+}
+
+func jsonValueHasType(value any, t string) bool {
+	switch value := value.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case string:
+		return t == "string"
+	case []any:
+		return t == "array"
+	case map[string]any:
+		return t == "object"
+	case json.Number:
+		if t == "number" {
+			return true
+		}
+		if t == "integer" {
+			n, ok := parseJSONNumber(value)
+			return ok && n.isInt()
+		}
+	}
+	return false
+}
+
+func (v *schemaValidator) validateObject(s map[string]any, value map[string]any, path string, depth int) {
+	if required, ok := s["required"].([]any); ok {
+		for _, r := range required {
+			name, ok := r.(string)
+			if !ok {
+				continue
+			}
+			if _, ok := value[name]; !ok {
+				v.fail(path, "required", "missing required property %q", name)
+			}
+		}
+	}
+	properties, _ := s["properties"].(map[string]any)
+	additional, hasAdditional := s["additionalProperties"]
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		propertyPath := path + "/" + escapeJSONPointerToken(key)
+		if propertySchema, ok := properties[key]; ok {
+			v.validate(propertySchema, value[key], propertyPath, depth+1)
+			continue
+		}
+		if !hasAdditional {
+			continue
+		}
+		if allowed, ok := additional.(bool); ok {
+			if !allowed {
+				v.fail(propertyPath, "additionalProperties", "property %q is not allowed", key)
+			}
+			continue
+		}
+		v.validate(additional, value[key], propertyPath, depth+1)
+	}
+}
+
+func (v *schemaValidator) validateArray(s map[string]any, value []any, path string, depth int) {
+	if n, ok := schemaInt(s, "minItems"); ok && len(value) < n {
+		v.fail(path, "minItems", "array must have at least %d items", n)
+	}
+	if n, ok := schemaInt(s, "maxItems"); ok && len(value) > n {
+		v.fail(path, "maxItems", "array must have at most %d items", n)
+	}
+	if items, ok := s["items"]; ok {
+		for i, item := range value {
+			v.validate(items, item, path+"/"+strconv.Itoa(i), depth+1)
+		}
+	}
+}
+
+func (v *schemaValidator) validateString(s map[string]any, value string, path string) {
+	length := utf8.RuneCountInString(value)
+	if n, ok := schemaInt(s, "minLength"); ok && length < n {
+		v.fail(path, "minLength", "string must be at least %d characters long", n)
+	}
+	if n, ok := schemaInt(s, "maxLength"); ok && length > n {
+		v.fail(path, "maxLength", "string must be at most %d characters long", n)
+	}
+	if pattern, ok := s["pattern"].(string); ok {
+		re, ok := v.regexps[pattern]
+		if !ok {
+			var err error
+			re, err = regexp.Compile(pattern)
+			if err != nil {
+				v.schemaError("invalid pattern %q: %v", pattern, err)
+				return
+			}
+			if v.regexps == nil {
+				v.regexps = make(map[string]*regexp.Regexp)
+			}
+			v.regexps[pattern] = re
+		}
+		if !re.MatchString(value) {
+			v.fail(path, "pattern", "string must match pattern %q", pattern)
+		}
+	}
+}
+
+func (v *schemaValidator) validateNumber(s map[string]any, value json.Number, path string) {
+	n, ok := parseJSONNumber(value)
+	if !ok {
+		return
+	}
+	bound := func(keyword string) (jsonNumber, bool) {
+		b, ok := s[keyword].(json.Number)
+		if !ok {
+			return jsonNumber{}, false
+		}
+		return parseJSONNumber(b)
+	}
+	if b, ok := bound("minimum"); ok && n.cmp(b) < 0 {
+		v.fail(path, "minimum", "value must be greater than or equal to %s", b)
+	}
+	if b, ok := bound("maximum"); ok && n.cmp(b) > 0 {
+		v.fail(path, "maximum", "value must be less than or equal to %s", b)
+	}
+	if b, ok := bound("exclusiveMinimum"); ok && n.cmp(b) <= 0 {
+		v.fail(path, "exclusiveMinimum", "value must be greater than %s", b)
+	}
+	if b, ok := bound("exclusiveMaximum"); ok && n.cmp(b) >= 0 {
+		v.fail(path, "exclusiveMaximum", "value must be less than %s", b)
+	}
+}
+
+// Bounds of the number literals compared exactly with big.Rat, whose cost grows with the magnitude of the exponent,
+// e.g. 1e1000000. Other literals are compared as float64, saturating at the infinities.
+const (
+	maxExactJSONNumberLength   = 64
+	maxExactJSONNumberExponent = 400
+)
+
+// jsonNumber is a JSON number literal, exact if within the bounds above and approximated by a float64 otherwise.
+type jsonNumber struct {
+	exact   *big.Rat
+	approx  float64
+	literal string
+}
+
+func parseJSONNumber(literal json.Number) (jsonNumber, bool) {
+	s := string(literal)
+	if exponent := jsonNumberExponent(s); len(s) <= maxExactJSONNumberLength &&
+		exponent <= maxExactJSONNumberExponent && exponent >= -maxExactJSONNumberExponent {
+		r, ok := new(big.Rat).SetString(s)
+		return jsonNumber{exact: r}, ok
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return jsonNumber{}, false
+	}
+	return jsonNumber{approx: f, literal: s}, true
+}
+
+// jsonNumberExponent returns the exponent of a number literal, saturating at the bounds of int.
+func jsonNumberExponent(literal string) int {
+	i := strings.IndexAny(literal, "eE")
+	if i < 0 {
+		return 0
+	}
+	exponent, err := strconv.Atoi(literal[i+1:])
+	if err != nil {
+		if strings.HasPrefix(literal[i+1:], "-") {
+			return math.MinInt
+		}
+		return math.MaxInt
+	}
+	return exponent
+}
+
+func (n jsonNumber) float() float64 {
+	if n.exact != nil {
+		f, _ := n.exact.Float64()
+		return f
+	}
+	return n.approx
+}
+
+func (n jsonNumber) cmp(other jsonNumber) int {
+	if n.exact != nil && other.exact != nil {
+		return n.exact.Cmp(other.exact)
+	}
+	a, b := n.float(), other.float()
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+func (n jsonNumber) isInt() bool {
+	if n.exact != nil {
+		return n.exact.IsInt()
+	}
+	// Decide from the literal since the float64 may have overflowed or underflowed.
+	mantissa := n.literal
+	if i := strings.IndexAny(mantissa, "eE"); i >= 0 {
+		mantissa = mantissa[:i]
+	}
+	whole, fraction, _ := strings.Cut(mantissa, ".")
+	fraction = strings.TrimRight(fraction, "0")
+	if strings.Trim(whole, "-0") == "" && fraction == "" {
+		return true
+	}
+	return jsonNumberExponent(n.literal) >= len(fraction)
+}
+
+func (n jsonNumber) String() string {
+	if n.exact != nil {
+		return n.exact.RatString()
+	}
+	return strconv.FormatFloat(n.approx, 'g', -1, 64)
+}
+
+func schemaInt(s map[string]any, keyword string) (int, bool) {
+	n, ok := s[keyword].(json.Number)
+	if !ok {
+		return 0, false
+	}
+	i, err := n.Int64()
+	return int(i), err == nil
+}
+
+func jsonValuesEqual(a, b any) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		na, okA := parseJSONNumber(a)
+		nb, okB := parseJSONNumber(b)
+		return okA && okB && na.cmp(nb) == 0
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !jsonValuesEqual(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, va := range a {
+			vb, ok := b[k]
+			if !ok || !jsonValuesEqual(va, vb) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+func escapeJSONPointerToken(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+func resolveJSONPointer(root any, ref string) (any, error) {
+	if ref != "#" && !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("unsupported $ref %q", ref)
+	}
+	current := root
+	if ref == "#" {
+		return current, nil
+	}
+	for _, token := range strings.Split(ref[2:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch c := current.(type) {
+		case map[string]any:
+			next, ok := c[token]
+			if !ok {
+				return nil, fmt.Errorf("unresolvable $ref %q", ref)
+			}
+			current = next
+		case []any:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(c) {
+				return nil, fmt.Errorf("unresolvable $ref %q", ref)
+			}
+			current = c[i]
+		default:
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+	}
+	return current, nil
+}
+
+// validateStartOperationInput validates the body of a start request against the operation's input schema, if the
+// operation is registered with one, and restores the body for the handler to read.
+func (h *httpHandler) validateStartOperationInput(operation string, request *http.Request) error {
+	if h.options.Registry == nil {
+		return nil
+	}
+	definition, ok := h.options.Registry.Lookup(operation)
+	if !ok || len(definition.InputSchema) == 0 {
+		return nil
+	}
+	if request.Header.Get(headerContentType) != "" && !isContentTypeJSON(request.Header) {
+		return newBadRequestError("invalid input content type: %q, expected %q",
+			request.Header.Get(headerContentType), contentTypeJSON)
+	}
+	body, err := io.ReadAll(io.LimitReader(request.Body, h.options.MaxInputSize+1))
+	if err != nil {
+		return newReadBodyError(err, "failed to read request body")
+	}
+	if int64(len(body)) > h.options.MaxInputSize {
+		return newRequestTooLargeError(h.options.MaxInputSize)
+	}
+	request.Body = io.NopCloser(bytes.NewReader(body))
+
+	violations, err := ValidateJSONSchema(definition.InputSchema, body)
+	if err != nil {
+		if errors.Is(err, errInvalidSchema) {
+			return fmt.Errorf("failed to validate input of operation %q: %w", operation, err)
+		}
+		return newBadRequestError("invalid JSON input: %v", err)
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	details, err := json.Marshal(InputValidationFailureDetails{Violations: violations})
+	if err != nil {
+		return err
+	}
+	return &HandlerError{
+		StatusCode: http.StatusBadRequest,
+		Failure: &Failure{
+			Message:  "input failed schema validation",
+			Metadata: map[string]string{"type": FailureTypeInputValidation},
+			Details:  details,
+		},
+	}
+}
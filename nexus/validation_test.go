@@ -0,0 +1,234 @@
+package nexus
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateJSONSchema(t *testing.T) {
+	schema := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string", "minLength": 1, "maxLength": 5, "pattern": "^[a-z]+$"},
+			"count": {"type": "integer", "minimum": 1, "exclusiveMaximum": 10},
+			"tags": {"type": "array", "items": {"$ref": "#/$defs/tag"}, "maxItems": 2},
+			"kind": {"enum": ["a", "b"]},
+			"a/b": {"const": 1.0}
+		},
+		"required": ["name", "count"],
+		"additionalProperties": false,
+		"$defs": {"tag": {"type": ["string", "null"]}}
+	}`)
+
+	cases := []struct {
+		name       string
+		value      string
+		violations []SchemaViolation
+	}{
+		{
+			name:  "valid",
+			value: `{"name": "abc", "count": 9, "tags": ["x", null], "kind": "a", "a/b": 1}`,
+		},
+		{
+			name:  "root type",
+			value: `[]`,
+			violations: []SchemaViolation{
+				{Path: "", Constraint: "type", Message: "value must be of type object"},
+			},
+		},
+		{
+			name:  "nested",
+			value: `{"name": "ABCDEF", "count": 1.5, "tags": [1, "x", "y"], "kind": "c", "a/b": 2, "extra": true}`,
+			violations: []SchemaViolation{
+				{Path: "/a~1b", Constraint: "const", Message: "value must be equal to the constant value"},
+				{Path: "/count", Constraint: "type", Message: "value must be of type integer"},
+				{Path: "/extra", Constraint: "additionalProperties", Message: `property "extra" is not allowed`},
+				{Path: "/kind", Constraint: "enum", Message: "value must be one of the enumerated values"},
+				{Path: "/name", Constraint: "maxLength", Message: "string must be at most 5 characters long"},
+				{Path: "/name", Constraint: "pattern", Message: `string must match pattern "^[a-z]+$"`},
+				{Path: "/tags", Constraint: "maxItems", Message: "array must have at most 2 items"},
+				{Path: "/tags/0", Constraint: "type", Message: "value must be of type string or null"},
+			},
+		},
+		{
+			name:  "missing and out of range",
+			value: `{"count": 10}`,
+			violations: []SchemaViolation{
+				{Path: "", Constraint: "required", Message: `missing required property "name"`},
+				{Path: "/count", Constraint: "exclusiveMaximum", Message: "value must be less than 10"},
+			},
+		},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			violations, err := ValidateJSONSchema(schema, []byte(c.value))
+			require.NoError(t, err)
+			require.Equal(t, c.violations, violations)
+		})
+	}
+
+	_, err := ValidateJSONSchema(schema, []byte(`{`))
+	require.Error(t, err)
+	require.NotErrorIs(t, err, errInvalidSchema)
+	_, err = ValidateJSONSchema(json.RawMessage(`{"$ref": "#"}`), []byte(`1`))
+	require.ErrorIs(t, err, errInvalidSchema)
+	_, err = ValidateJSONSchema(json.RawMessage(`{"$ref": "http://example.com/schema"}`), []byte(`1`))
+	require.ErrorIs(t, err, errInvalidSchema)
+}
+
+func TestValidateJSONSchema_Combinators(t *testing.T) {
+	schema := json.RawMessage(`{
+		"allOf": [{"type": "number"}, {"maximum": 100}],
+		"anyOf": [{"minimum": 50}, {"const": 0}],
+		"oneOf": [{"multipleOf": 1}, {"maximum": 75}],
+		"not": {"const": 60}
+	}`)
+	violations, err := ValidateJSONSchema(schema, []byte(`0`))
+	require.NoError(t, err)
+	require.Equal(t, []SchemaViolation{
+		{Path: "", Constraint: "oneOf", Message: "value must match exactly one schema, matched 2"},
+	}, violations)
+
+	violations, err = ValidateJSONSchema(schema, []byte(`101`))
+	require.NoError(t, err)
+	require.Equal(t, []SchemaViolation{
+		{Path: "", Constraint: "maximum", Message: "value must be less than or equal to 100"},
+	}, violations)
+
+	violations, err = ValidateJSONSchema(schema, []byte(`60`))
+	require.NoError(t, err)
+	require.Equal(t, []SchemaViolation{
+		{Path: "", Constraint: "oneOf", Message: "value must match exactly one schema, matched 2"},
+		{Path: "", Constraint: "not", Message: "value must not match schema"},
+	}, violations)
+
+	violations, err = ValidateJSONSchema(json.RawMessage(`false`), []byte(`null`))
+	require.NoError(t, err)
+	require.Equal(t, []SchemaViolation{{Path: "", Constraint: "false", Message: "no value is allowed"}}, violations)
+}
+
+type validatedInputHandler struct {
+	UnimplementedHandler
+}
+
+func (h *validatedInputHandler) StartOperation(ctx context.Context, request *StartOperationRequest) (OperationResponse, error) {
+	var input describeInput
+	if err := json.NewDecoder(request.HTTPRequest.Body).Decode(&input); err != nil {
+		return nil, err
+	}
+	return NewOperationResponseSync(describeOutput{Greeting: "hello " + input.Name})
+}
+
+func TestValidateJSONSchema_LargeNumbers(t *testing.T) {
+	schema := json.RawMessage(`{"type": "integer", "minimum": -1e1000000, "maximum": 1e1000000}`)
+
+	start := time.Now()
+	for _, value := range []string{`1e1000000`, `5`, `12345678901234567890123456789012345678901234567890123456789012345.5e1`} {
+		violations, err := ValidateJSONSchema(schema, []byte(value))
+		require.NoError(t, err)
+		require.Empty(t, violations, value)
+	}
+	violations, err := ValidateJSONSchema(schema, []byte(`1e-1000000`))
+	require.NoError(t, err)
+	require.Equal(t, []SchemaViolation{
+		{Path: "", Constraint: "type", Message: "value must be of type integer"},
+	}, violations)
+	violations, err = ValidateJSONSchema(json.RawMessage(`{"minimum": 0}`), []byte(`-1e999999999999999999999`))
+	require.NoError(t, err)
+	require.Equal(t, []SchemaViolation{
+		{Path: "", Constraint: "minimum", Message: "value must be greater than or equal to 0"},
+	}, violations)
+	require.Less(t, time.Since(start), time.Second)
+}
+
+func TestStartOperation_InputTooLarge(t *testing.T) {
+	registry := NewOperationRegistry(OperationRegistryOptions{})
+	greet := NewOperationDefinition[describeInput, describeOutput]("greet")
+	greet.InputSchema = JSONSchemaFor[describeInput]()
+	require.NoError(t, registry.Register(greet))
+
+	ctx, client, teardown := setupCustom(t, HandlerOptions{
+		Handler:      &validatedInputHandler{},
+		Registry:     registry,
+		MaxInputSize: 16,
+	}, ClientOptions{})
+	defer teardown()
+
+	options, err := NewStartOperationOptions("greet", describeInput{Name: "world"})
+	require.NoError(t, err)
+	result, err := client.StartOperation(ctx, options)
+	require.NoError(t, err)
+	result.Successful.Body.Close()
+
+	options, err = NewStartOperationOptions("greet", describeInput{Name: strings.Repeat("x", 16)})
+	require.NoError(t, err)
+	_, err = client.StartOperation(ctx, options)
+	var unexpectedResponseError *UnexpectedResponseError
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusRequestEntityTooLarge, unexpectedResponseError.Response.StatusCode)
+}
+
+func TestStartOperation_InputValidation(t *testing.T) {
+	registry := NewOperationRegistry(OperationRegistryOptions{})
+	greet := NewOperationDefinition[describeInput, describeOutput]("greet")
+	greet.InputSchema = JSONSchemaFor[describeInput]()
+	require.NoError(t, registry.Register(greet, OperationDefinition{Name: "unvalidated"}))
+	require.ErrorIs(t, registry.Register(OperationDefinition{Name: "invalid", InputSchema: json.RawMessage("{")}), errInvalidSchema)
+
+	ctx, client, teardown := setupCustom(t, HandlerOptions{
+		Handler:  &validatedInputHandler{},
+		Registry: registry,
+	}, ClientOptions{})
+	defer teardown()
+
+	options, err := NewStartOperationOptions("greet", describeInput{Name: "world"})
+	require.NoError(t, err)
+	result, err := client.StartOperation(ctx, options)
+	require.NoError(t, err)
+	defer result.Successful.Body.Close()
+	body, err := io.ReadAll(result.Successful.Body)
+	require.NoError(t, err)
+	require.Equal(t, `{"greeting":"hello world"}`, string(body))
+
+	options, err = NewStartOperationOptions("greet", map[string]any{"name": 1})
+	require.NoError(t, err)
+	_, err = client.StartOperation(ctx, options)
+	var unexpectedResponseError *UnexpectedResponseError
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusBadRequest, unexpectedResponseError.Response.StatusCode)
+	require.Equal(t, "input failed schema validation", unexpectedResponseError.Failure.Message)
+	require.Equal(t, FailureTypeInputValidation, unexpectedResponseError.Failure.Metadata["type"])
+	var details InputValidationFailureDetails
+	require.NoError(t, json.Unmarshal(unexpectedResponseError.Failure.Details, &details))
+	require.Equal(t, []SchemaViolation{
+		{Path: "/name", Constraint: "type", Message: "value must be of type string"},
+	}, details.Violations)
+
+	_, err = client.StartOperation(ctx, StartOperationOptions{Operation: "greet", Body: strings.NewReader("not json")})
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusBadRequest, unexpectedResponseError.Response.StatusCode)
+	require.Contains(t, unexpectedResponseError.Failure.Message, "invalid JSON input")
+
+	_, err = client.StartOperation(ctx, StartOperationOptions{
+		Operation: "greet",
+		Header:    http.Header{headerContentType: []string{"text/plain"}},
+		Body:      strings.NewReader(`{"name": "world"}`),
+	})
+	require.ErrorAs(t, err, &unexpectedResponseError)
+	require.Equal(t, http.StatusBadRequest, unexpectedResponseError.Response.StatusCode)
+
+	// Operations without an input schema are not validated.
+	options, err = NewStartOperationOptions("unvalidated", describeInput{Name: "world"})
+	require.NoError(t, err)
+	result, err = client.StartOperation(ctx, options)
+	require.NoError(t, err)
+	result.Successful.Body.Close()
+}